@@ -56,8 +56,11 @@ func main() {
 			op.UnavailableOfferingsCache,
 			cloudProvider,
 			op.InstanceProvider, op.InstanceTypeProvider,
-			op.PricingProvider, op.VSwitchProvider,
+			op.PricingProvider, op.SpotRiskProvider, op.VSwitchProvider,
 			op.SecurityGroupProvider, op.ImageProvider,
+			op.RAMRoleProvider, op.InstanceEventProvider,
+			op.CapacityReservationProvider, op.DeploymentSetProvider,
+			op.DedicatedHostProvider,
 		)...).
 		Start(ctx)
 }
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	ackclient "github.com/alibabacloud-go/cs-20151215/v5/client"
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	vpcclient "github.com/alibabacloud-go/vpc-20160428/v6/client"
+)
+
+// ECSAPI contains the ECS calls used by the providers, it is implemented by *ecsclient.Client
+type ECSAPI interface {
+	AddTagsWithOptions(*ecsclient.AddTagsRequest, *util.RuntimeOptions) (*ecsclient.AddTagsResponse, error)
+	CreateAutoProvisioningGroupWithOptions(*ecsclient.CreateAutoProvisioningGroupRequest, *util.RuntimeOptions) (*ecsclient.CreateAutoProvisioningGroupResponse, error)
+	CreateDeploymentSetWithOptions(*ecsclient.CreateDeploymentSetRequest, *util.RuntimeOptions) (*ecsclient.CreateDeploymentSetResponse, error)
+	DeleteDeploymentSetWithOptions(*ecsclient.DeleteDeploymentSetRequest, *util.RuntimeOptions) (*ecsclient.DeleteDeploymentSetResponse, error)
+	DeleteInstancesWithOptions(*ecsclient.DeleteInstancesRequest, *util.RuntimeOptions) (*ecsclient.DeleteInstancesResponse, error)
+	DescribeAvailableResourceWithOptions(*ecsclient.DescribeAvailableResourceRequest, *util.RuntimeOptions) (*ecsclient.DescribeAvailableResourceResponse, error)
+	DescribeCapacityReservationsWithOptions(*ecsclient.DescribeCapacityReservationsRequest, *util.RuntimeOptions) (*ecsclient.DescribeCapacityReservationsResponse, error)
+	DescribeDedicatedHostClustersWithOptions(*ecsclient.DescribeDedicatedHostClustersRequest, *util.RuntimeOptions) (*ecsclient.DescribeDedicatedHostClustersResponse, error)
+	DescribeDedicatedHostsWithOptions(*ecsclient.DescribeDedicatedHostsRequest, *util.RuntimeOptions) (*ecsclient.DescribeDedicatedHostsResponse, error)
+	DescribeDeploymentSetsWithOptions(*ecsclient.DescribeDeploymentSetsRequest, *util.RuntimeOptions) (*ecsclient.DescribeDeploymentSetsResponse, error)
+	DescribeElasticityAssurancesWithOptions(*ecsclient.DescribeElasticityAssurancesRequest, *util.RuntimeOptions) (*ecsclient.DescribeElasticityAssurancesResponse, error)
+	DescribeImages(*ecsclient.DescribeImagesRequest) (*ecsclient.DescribeImagesResponse, error)
+	DescribeInstanceHistoryEventsWithOptions(*ecsclient.DescribeInstanceHistoryEventsRequest, *util.RuntimeOptions) (*ecsclient.DescribeInstanceHistoryEventsResponse, error)
+	DescribeInstanceTypesWithOptions(*ecsclient.DescribeInstanceTypesRequest, *util.RuntimeOptions) (*ecsclient.DescribeInstanceTypesResponse, error)
+	DescribeInstancesWithOptions(*ecsclient.DescribeInstancesRequest, *util.RuntimeOptions) (*ecsclient.DescribeInstancesResponse, error)
+	DescribeSecurityGroupsWithOptions(*ecsclient.DescribeSecurityGroupsRequest, *util.RuntimeOptions) (*ecsclient.DescribeSecurityGroupsResponse, error)
+	DescribeSpotPriceHistoryWithOptions(*ecsclient.DescribeSpotPriceHistoryRequest, *util.RuntimeOptions) (*ecsclient.DescribeSpotPriceHistoryResponse, error)
+	ListTagResourcesWithOptions(*ecsclient.ListTagResourcesRequest, *util.RuntimeOptions) (*ecsclient.ListTagResourcesResponse, error)
+	RunInstancesWithOptions(*ecsclient.RunInstancesRequest, *util.RuntimeOptions) (*ecsclient.RunInstancesResponse, error)
+}
+
+// VPCAPI contains the VPC calls used by the providers, it is implemented by *vpcclient.Client
+type VPCAPI interface {
+	DescribeVSwitchesWithOptions(*vpcclient.DescribeVSwitchesRequest, *util.RuntimeOptions) (*vpcclient.DescribeVSwitchesResponse, error)
+}
+
+// ACKAPI contains the ACK calls used by the providers, it is implemented by *ackclient.Client
+type ACKAPI interface {
+	DescribeClusterAttachScripts(*string, *ackclient.DescribeClusterAttachScriptsRequest) (*ackclient.DescribeClusterAttachScriptsResponse, error)
+	DescribeClusterDetail(*string) (*ackclient.DescribeClusterDetailResponse, error)
+	DescribeClusterNodePools(*string, *ackclient.DescribeClusterNodePoolsRequest) (*ackclient.DescribeClusterNodePoolsResponse, error)
+	DescribeKubernetesVersionMetadata(*ackclient.DescribeKubernetesVersionMetadataRequest) (*ackclient.DescribeKubernetesVersionMetadataResponse, error)
+}
+
+// RAMAPI contains the RAM calls used by the providers, it is implemented by *openapi.Client since RAM is called
+// through the generic OpenAPI client
+type RAMAPI interface {
+	CallApi(*openapi.Params, *openapi.OpenApiRequest, *util.RuntimeOptions) (map[string]interface{}, error)
+}
+
+var (
+	_ ECSAPI = (*ecsclient.Client)(nil)
+	_ VPCAPI = (*vpcclient.Client)(nil)
+	_ ACKAPI = (*ackclient.Client)(nil)
+	_ RAMAPI = (*openapi.Client)(nil)
+)
@@ -29,11 +29,40 @@ import (
 
 const (
 	VSwitchSelectionPolicyBalanced = "balanced"
+
+	SpotAllocationStrategyLowestPrice            = "lowest-price"
+	SpotAllocationStrategyDiversified            = "diversified"
+	SpotAllocationStrategyPriceCapacityOptimized = "price-capacity-optimized"
+
+	PayAsYouGoAllocationStrategyLowestPrice = "lowest-price"
+	PayAsYouGoAllocationStrategyPrioritized = "prioritized"
+
+	SpotRiskActionExclude      = "exclude"
+	SpotRiskActionDeprioritize = "deprioritize"
+
+	SpotInterruptionBehaviorTerminate = "terminate"
+	SpotInterruptionBehaviorStop      = "stop"
+
+	InstanceStorePolicyRAID0 = "RAID0"
+
+	DeploymentSetStrategyAvailability = "availability"
+	DeploymentSetStrategyLowLatency   = "low-latency"
+
+	TenancyDefault = "default"
+	TenancyHost    = "host"
+
+	AffinityDefault = "default"
+	AffinityHost    = "host"
+
+	InternetChargeTypePayByTraffic   = "PayByTraffic"
+	InternetChargeTypePayByBandwidth = "PayByBandwidth"
 )
 
 // ECSNodeClassSpec is the top level specification for the AlibabaCloud Karpenter Provider.
 // This will contain the configuration necessary to launch instances in AlibabaCloud.
 // +kubebuilder:validation:XValidation:rule="!(has(self.passwordInherit) ? (self.passwordInherit ? has(self.password) : false) : false)",message="password cannot be set when passwordInherit is true"
+// +kubebuilder:validation:XValidation:rule="(has(self.associatePublicIPAddress) && self.associatePublicIPAddress) || !(has(self.internetChargeType) || has(self.internetMaxBandwidthOut) || has(self.internetMaxBandwidthIn))",message="internetChargeType, internetMaxBandwidthOut and internetMaxBandwidthIn require associatePublicIPAddress"
+// +kubebuilder:validation:XValidation:rule="!has(self.internetMaxBandwidthIn) || self.internetMaxBandwidthIn <= 10 || (has(self.internetMaxBandwidthOut) && self.internetMaxBandwidthIn <= self.internetMaxBandwidthOut)",message="internetMaxBandwidthIn cannot exceed both 10 and internetMaxBandwidthOut"
 type ECSNodeClassSpec struct {
 	// VSwitchSelectorTerms is a list of or vSwitch selector terms. The terms are ORed.
 	// +kubebuilder:validation:XValidation:message="vSwitchSelectorTerms cannot be empty",rule="self.size() != 0"
@@ -46,6 +75,72 @@ type ECSNodeClassSpec struct {
 	// +kubebuilder:validation:Enum:=balanced;cheapest
 	// +kubebuilder:default:=cheapest
 	VSwitchSelectionPolicy string `json:"vSwitchSelectionPolicy,omitempty"`
+	// SpotAllocationStrategy is the strategy to pick the instance type and zone of spot instances.
+	// lowest-price launches the cheapest offering, diversified spreads the instances across the zones and
+	// price-capacity-optimized launches the offering with the most capacity among the cheapest instance types.
+	// Defaults to lowest-price.
+	// +kubebuilder:validation:Enum:=lowest-price;diversified;price-capacity-optimized
+	// +optional
+	SpotAllocationStrategy string `json:"spotAllocationStrategy,omitempty" hash:"ignore"`
+	// PayAsYouGoAllocationStrategy is the strategy to pick the instance type and zone of pay-as-you-go instances.
+	// lowest-price launches the cheapest offering, prioritized tries the instance types in the price order of Karpenter.
+	// Defaults to lowest-price.
+	// +kubebuilder:validation:Enum:=lowest-price;prioritized
+	// +optional
+	PayAsYouGoAllocationStrategy string `json:"payAsYouGoAllocationStrategy,omitempty" hash:"ignore"`
+	// SpotRiskPolicy controls the spot offerings with a high interruption risk. The observed interruptions are only
+	// kept in memory by the controller, they are forgotten when it restarts.
+	// +optional
+	SpotRiskPolicy *SpotRiskPolicy `json:"spotRiskPolicy,omitempty" hash:"ignore"`
+	// SpotOptions configures the spot instances launched from the ECSNodeClass.
+	// +optional
+	SpotOptions *SpotOptions `json:"spotOptions,omitempty" hash:"ignore"`
+	// CapacityReservationSelectorTerms is a list of capacity reservation selector terms, it selects both the capacity
+	// reservations and the elasticity assurances. The terms are ORed. The instances launched into them get the reserved
+	// capacity type, the NodePools which restrict the capacity types need to include it.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id']",rule="self.all(x, has(x.tags) || has(x.id))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in capacityReservationSelectorTerms",rule="!self.exists(x, has(x.id) && has(x.tags))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +optional
+	CapacityReservationSelectorTerms []CapacityReservationSelectorTerm `json:"capacityReservationSelectorTerms,omitempty" hash:"ignore"`
+	// DeploymentSetSelector places the instances into a deployment set, either an existing one selected by id or tags,
+	// or one created for the ECSNodeClass with the strategy. The offerings of the zones where the deployment set is
+	// full are not launched. Changing it drifts the instances, an instance can't be moved into another deployment set.
+	// +optional
+	DeploymentSetSelector *DeploymentSetSelector `json:"deploymentSetSelector,omitempty"`
+	// Placement launches the instances onto dedicated hosts or into an HPC cluster. Auto provisioning groups don't
+	// support them, so the instances are launched with RunInstances, trying the instance types in the price order.
+	// +optional
+	Placement *Placement `json:"placement,omitempty"`
+	// AssociatePublicIPAddress assigns a public IP address to the instances, so the nodes can reach the internet
+	// without a NAT gateway. The address is released with the instance. The vSwitches shared by other accounts can't
+	// assign public IP addresses, they are not used.
+	// +optional
+	AssociatePublicIPAddress *bool `json:"associatePublicIPAddress,omitempty"`
+	// InternetChargeType is the billing method of the public IP addresses, PayByTraffic bills the outbound traffic
+	// and PayByBandwidth bills the outbound bandwidth limit. Defaults to PayByTraffic.
+	// +kubebuilder:validation:Enum:={PayByTraffic,PayByBandwidth}
+	// +optional
+	InternetChargeType *string `json:"internetChargeType,omitempty"`
+	// InternetMaxBandwidthOut is the outbound bandwidth limit of the public IP addresses in Mbit/s. Defaults to 5.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=100
+	// +optional
+	InternetMaxBandwidthOut *int32 `json:"internetMaxBandwidthOut,omitempty"`
+	// InternetMaxBandwidthIn is the inbound bandwidth limit of the public IP addresses in Mbit/s, it goes up to 10 or
+	// to InternetMaxBandwidthOut when it is greater. Defaults to the largest value allowed.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=100
+	// +optional
+	InternetMaxBandwidthIn *int32 `json:"internetMaxBandwidthIn,omitempty"`
+	// IPv6AddressCount assigns IPv6 addresses to the primary network interface of the instances, the nodes register
+	// both their IPv4 and IPv6 address. Only the vSwitches with an IPv6 CIDR block and the instance types supporting
+	// as many IPv6 addresses per network interface are used. Auto provisioning groups don't support IPv6 addresses, so
+	// the instances are launched with RunInstances, trying the instance types in the price order.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=10
+	// +optional
+	IPv6AddressCount *int32 `json:"ipv6AddressCount,omitempty"`
 	// SecurityGroupSelectorTerms is a list of or security group selector terms. The terms are ORed.
 	// +kubebuilder:validation:XValidation:message="securityGroupSelectorTerms cannot be empty",rule="self.size() != 0"
 	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name))"
@@ -55,9 +150,9 @@ type ECSNodeClassSpec struct {
 	// +required
 	SecurityGroupSelectorTerms []SecurityGroupSelectorTerm `json:"securityGroupSelectorTerms" hash:"ignore"`
 	// ImageSelectorTerms is a list of or image selector terms. The terms are ORed.
-	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['id', 'alias']",rule="self.all(x, has(x.id) || has(x.alias))"
-	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in imageSelectorTerms",rule="!self.exists(x, has(x.id) && (has(x.alias)))"
-	// +kubebuilder:validation:XValidation:message="'alias' is mutually exclusive, cannot be set with a combination of other fields in imageSelectorTerms",rule="!self.exists(x, has(x.alias) && (has(x.id)))"
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name', 'alias']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name) || has(x.alias))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in imageSelectorTerms",rule="!self.exists(x, has(x.id) && (has(x.alias) || has(x.tags) || has(x.name) || has(x.owner)))"
+	// +kubebuilder:validation:XValidation:message="'alias' is mutually exclusive, cannot be set with a combination of other fields in imageSelectorTerms",rule="!self.exists(x, has(x.alias) && (has(x.id) || has(x.tags) || has(x.name) || has(x.owner)))"
 	// +kubebuilder:validation:XValidation:message="'alias' is mutually exclusive, cannot be set with a combination of other imageSelectorTerms",rule="!(self.exists(x, has(x.alias)) && self.size() != 1)"
 	// +kubebuilder:validation:MinItems:=1
 	// +kubebuilder:validation:MaxItems:=30
@@ -79,6 +174,7 @@ type ECSNodeClassSpec struct {
 	DataDisks []DataDisk `json:"dataDisks,omitempty"`
 	// The category of the data disk (for example, cloud and cloud_ssd).
 	// Different ECS is compatible with different disk category, using array to maximize ECS creation success.
+	// It is the default of the data disks which don't set their own categories.
 	// Valid values:"cloud", "cloud_efficiency", "cloud_ssd", "cloud_essd", "cloud_auto", and "cloud_essd_entry"
 	// +kubebuilder:validation:Items=Enum=cloud;cloud_efficiency;cloud_ssd;cloud_essd;cloud_auto;cloud_essd_entry
 	// +optional
@@ -87,6 +183,12 @@ type ECSNodeClassSpec struct {
 	// +kubebuilder:default:=false
 	// +optional
 	FormatDataDisk bool `json:"formatDataDisk,omitempty"`
+	// InstanceStorePolicy specifies how to use the local disks of the instance types which have them, e.g. the i and d
+	// families. RAID0 formats the local disks, as a RAID0 array when there are several of them, mounts them for
+	// containerd and kubelet, and reports their capacity as the ephemeral storage of the node.
+	// +kubebuilder:validation:Enum:={RAID0}
+	// +optional
+	InstanceStorePolicy *string `json:"instanceStorePolicy,omitempty"`
 	// Tags to be applied on ecs resources like instances and launch templates.
 	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching ecs:ecs-cluster-name",rule="self.all(k, k !='ecs:ecs-cluster-name')"
@@ -103,6 +205,10 @@ type ECSNodeClassSpec struct {
 	// UserData to be applied to the provisioned nodes and executed before/after the node is registered.
 	// +optional
 	UserData *string `json:"userData,omitempty"`
+	// RAMRole is the name of the RAM role attached to the provisioned instances.
+	// +kubebuilder:validation:Pattern:=`^[a-zA-Z0-9.-]{1,64}$`
+	// +optional
+	RAMRole string `json:"ramRole,omitempty"`
 	// Password is the password for ecs for root.
 	// +kubebuilder:validation:Pattern=`^[A-Za-z\d~!@#$%^&*()_+\-=\[\]{}|\\:;"'<>,.?/]{8,30}$`
 	//+optional
@@ -117,6 +223,53 @@ type ECSNodeClassSpec struct {
 	PasswordInherit bool `json:"passwordInherit,omitempty"`
 }
 
+// SpotRiskPolicy controls the spot offerings with a high interruption risk. The risk score of an offering goes from
+// 0 to 100, it grows with the volatility of the spot price and the interruptions observed in the last 24 hours.
+type SpotRiskPolicy struct {
+	// MaxRiskScore is the risk score above which the action is taken on the spot offering.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=100
+	// +kubebuilder:default:=50
+	// +optional
+	MaxRiskScore *int32 `json:"maxRiskScore,omitempty"`
+	// Action is taken on the spot offerings whose risk score is above MaxRiskScore. exclude stops launching them,
+	// deprioritize raises their price by their risk score in percent, so the offerings with a lower risk are preferred.
+	// +kubebuilder:validation:Enum:=exclude;deprioritize
+	// +kubebuilder:default:=deprioritize
+	// +optional
+	Action string `json:"action,omitempty"`
+}
+
+// SpotOptions configures the price limit and the interruption behavior of the spot instances. The spot offerings whose
+// current price is above the price limit are not launched.
+// +kubebuilder:validation:XValidation:message="'maxPrice' and 'maxPricePercentageOfOnDemand' are mutually exclusive",rule="!(has(self.maxPrice) && has(self.maxPricePercentageOfOnDemand))"
+type SpotOptions struct {
+	// MaxPrice is the highest hourly price paid for a spot instance.
+	// +kubebuilder:validation:Pattern:="^[0-9]+(\\.[0-9]+)?$"
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type:=string
+	// +optional
+	MaxPrice *resource.Quantity `json:"maxPrice,omitempty"`
+	// MaxPricePercentageOfOnDemand is the highest hourly price paid for a spot instance, in percent of the
+	// on-demand price of its instance type.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=100
+	// +optional
+	MaxPricePercentageOfOnDemand *int32 `json:"maxPricePercentageOfOnDemand,omitempty"`
+	// InterruptionBehavior is what happens to a spot instance when it is reclaimed, it is either terminated or stopped.
+	// +kubebuilder:validation:Enum:=terminate;stop
+	// +kubebuilder:default:=terminate
+	// +optional
+	InterruptionBehavior string `json:"interruptionBehavior,omitempty"`
+	// Duration is the protection period of a spot instance in hours, it isn't reclaimed within it. 0 launches the spot
+	// instances without a protection period. The auto provisioning groups can't set it, so the spot instances are
+	// launched one instance type and zone at a time with RunInstances when it is set.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=1
+	// +optional
+	Duration *int32 `json:"duration,omitempty"`
+}
+
 // VSwitchSelectorTerm defines selection logic for a vSwitch used by Karpenter to launch nodes.
 type VSwitchSelectorTerm struct {
 	// Tags is a map of key/value tags used to select vSwitches
@@ -131,6 +284,90 @@ type VSwitchSelectorTerm struct {
 	ID string `json:"id,omitempty"`
 }
 
+// CapacityReservationSelectorTerm defines selection logic for a capacity reservation or an elasticity assurance
+// used by Karpenter to launch nodes.
+type CapacityReservationSelectorTerm struct {
+	// Tags is a map of key/value tags used to select capacity reservations and elasticity assurances
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// ID is the private pool id of the capacity reservation or the elasticity assurance, e.g. crp-xxx or eap-xxx
+	// +kubebuilder:validation:Pattern:="^(crp|eap)-[0-9a-z]+$"
+	// +optional
+	ID string `json:"id,omitempty"`
+}
+
+// DeploymentSetSelector defines the deployment set used by Karpenter to launch nodes, exactly one of the fields is set.
+// +kubebuilder:validation:XValidation:message="expected exactly one, got none or more, ['tags', 'id', 'strategy']",rule="(has(self.tags) ? 1 : 0) + (has(self.id) ? 1 : 0) + (has(self.strategy) ? 1 : 0) == 1"
+type DeploymentSetSelector struct {
+	// Tags is a map of key/value tags used to select a deployment set, the one with the smallest id is used when
+	// several deployment sets match.
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// ID is the deployment set id in ECS
+	// +kubebuilder:validation:Pattern:="^ds-[0-9a-z]+$"
+	// +optional
+	ID string `json:"id,omitempty"`
+	// Strategy creates a deployment set for the ECSNodeClass. availability spreads the instances across physical
+	// servers, low-latency places them close to each other in the network.
+	// +kubebuilder:validation:Enum:=availability;low-latency
+	// +optional
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// Placement configures the dedicated hosts and the HPC cluster the instances are launched onto.
+// +kubebuilder:validation:XValidation:message="'dedicatedHostID' and 'dedicatedHostClusterSelectorTerms' are mutually exclusive",rule="!(has(self.dedicatedHostID) && has(self.dedicatedHostClusterSelectorTerms))"
+// +kubebuilder:validation:XValidation:message="'tenancy' must be host when a dedicated host is selected",rule="!(has(self.tenancy) && self.tenancy == 'default' && (has(self.dedicatedHostID) || has(self.dedicatedHostClusterSelectorTerms)))"
+// +kubebuilder:validation:XValidation:message="'affinity' requires dedicated hosts",rule="!(has(self.affinity) && self.affinity == 'host' && !has(self.dedicatedHostID) && !has(self.dedicatedHostClusterSelectorTerms) && !(has(self.tenancy) && self.tenancy == 'host'))"
+type Placement struct {
+	// DedicatedHostID is the id of the dedicated host the instances are launched onto.
+	// +kubebuilder:validation:Pattern:="^dh-[0-9a-z]+$"
+	// +optional
+	DedicatedHostID string `json:"dedicatedHostID,omitempty"`
+	// DedicatedHostClusterSelectorTerms is a list of dedicated host cluster selector terms. The terms are ORed.
+	// The instances are launched onto the dedicated hosts of the selected clusters.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id']",rule="self.all(x, has(x.tags) || has(x.id))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in dedicatedHostClusterSelectorTerms",rule="!self.exists(x, has(x.id) && has(x.tags))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +optional
+	DedicatedHostClusterSelectorTerms []DedicatedHostClusterSelectorTerm `json:"dedicatedHostClusterSelectorTerms,omitempty" hash:"ignore"`
+	// Tenancy is host to launch the instances onto dedicated hosts. When neither a dedicated host nor a dedicated
+	// host cluster is selected, ECS picks one of the dedicated hosts of the account which accept automatic deployment.
+	// Defaults to host when a dedicated host or a dedicated host cluster is selected.
+	// +kubebuilder:validation:Enum:=default;host
+	// +optional
+	Tenancy string `json:"tenancy,omitempty"`
+	// Affinity is host to keep an instance on its dedicated host when it is stopped and started again.
+	// +kubebuilder:validation:Enum:=default;host
+	// +optional
+	Affinity string `json:"affinity,omitempty"`
+	// HPCClusterID is the id of the HPC cluster the instances are launched into, e.g. for the eRDMA or the
+	// RDMA networking of MPI jobs.
+	// +kubebuilder:validation:Pattern:="^hpc-[0-9a-z]+$"
+	// +optional
+	HPCClusterID string `json:"hpcClusterID,omitempty"`
+}
+
+// DedicatedHostClusterSelectorTerm defines selection logic for a dedicated host cluster used by Karpenter to
+// launch nodes.
+type DedicatedHostClusterSelectorTerm struct {
+	// Tags is a map of key/value tags used to select dedicated host clusters
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// ID is the dedicated host cluster id in ECS
+	// +kubebuilder:validation:Pattern:="^dc-[0-9a-z]+$"
+	// +optional
+	ID string `json:"id,omitempty"`
+}
+
 // SecurityGroupSelectorTerm defines selection logic for a security group used by Karpenter to launch nodes.
 // If multiple fields are used for selection, the requirements are ANDed.
 type SecurityGroupSelectorTerm struct {
@@ -155,7 +392,8 @@ type ImageSelectorTerm struct {
 	// Alias specifies which ACK image to select.
 	// Each alias consists of a family and an image version, specified as "family@version".
 	// Valid families include: AlibabaCloudLinux3,ContainerOS
-	// Currently only supports version pinning to the latest image release, with that images version format (ex: "aliyun3@latest").
+	// The version is either "latest", which selects the newest image release of each architecture, or the release
+	// date embedded in the ACK image ID, which pins that image release (ex: "AlibabaCloudLinux3@20240819").
 	// Setting the version to latest will result in drift when a new Image is released. This is **not** recommended for production environments.
 	// +kubebuilder:validation:XValidation:message="'alias' is improperly formatted, must match the format 'family'",rule="self.matches('^[a-zA-Z0-9]+@.+$')"
 	// +kubebuilder:validation:XValidation:message="family is not supported, must be one of the following: 'AlibabaCloudLinux3,ContainerOS'",rule="self.find('^[^@]+') in ['AlibabaCloudLinux3', 'ContainerOS']"
@@ -165,6 +403,20 @@ type ImageSelectorTerm struct {
 	// ID is the image id in ECS
 	// +optional
 	ID string `json:"id,omitempty"`
+	// Tags is a map of key/value tags used to select images
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// Name is the image name in ECS, wildcards ('*') are supported.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Owner is the owner alias of the image in ECS.
+	// When Name is set without Owner, only the images owned by the current account are selected.
+	// +kubebuilder:validation:Enum:={self,system,others,marketplace}
+	// +optional
+	Owner string `json:"owner,omitempty"`
 }
 
 // KubeletConfiguration defines args to be used when configuring kubelet on provisioned nodes.
@@ -236,6 +488,9 @@ type KubeletConfiguration struct {
 	CPUCFSQuota *bool `json:"cpuCFSQuota,omitempty"`
 }
 
+// +kubebuilder:validation:XValidation:message="provisionedIOPS and burstingEnabled are only offered by the cloud_auto category",rule="!(has(self.provisionedIOPS) || (has(self.burstingEnabled) && self.burstingEnabled)) || !has(self.categories) || 'cloud_auto' in self.categories"
+// +kubebuilder:validation:XValidation:message="performanceLevel PL2 and PL3 are only offered by the cloud_essd category",rule="!(has(self.performanceLevel) && self.performanceLevel in ['PL2', 'PL3']) || !has(self.categories) || 'cloud_essd' in self.categories"
+// +kubebuilder:validation:XValidation:message="performanceLevel PL2 and PL3 can't be combined with provisionedIOPS or burstingEnabled",rule="!(has(self.performanceLevel) && self.performanceLevel in ['PL2', 'PL3']) || !(has(self.provisionedIOPS) || (has(self.burstingEnabled) && self.burstingEnabled))"
 type SystemDisk struct {
 	// The category of the system disk (for example, cloud and cloud_ssd).
 	// Different ECS is compatible with different disk category, using array to maximize ECS creation success.
@@ -261,16 +516,32 @@ type SystemDisk struct {
 	// +kubebuilder:validation:XValidation:message="size invalid",rule="self >= 20"
 	// +optional
 	Size *int32 `json:"size,omitempty"`
-	// The performance level of the ESSD to use as the system disk. Default value: PL0.
+	// The performance level of the ESSD to use as the system disk, ECS picks it when it isn't set.
 	// Valid values:
 	//   * PL0: A single ESSD can deliver up to 10,000 random read/write IOPS.
 	//   * PL1: A single ESSD can deliver up to 50,000 random read/write IOPS.
-	//   * PL2: A single ESSD can deliver up to 100,000 random read/write IOPS.
-	//   * PL3: A single ESSD can deliver up to 1,000,000 random read/write IOPS.
-	// This will be supported soon
+	//   * PL2: A single ESSD can deliver up to 100,000 random read/write IOPS, from 461 GiB on.
+	//   * PL3: A single ESSD can deliver up to 1,000,000 random read/write IOPS, from 1,261 GiB on.
+	// It is only applied when the system disk is an ESSD: PL2 and PL3 are only offered by ESSDs, so the other
+	// categories are not used, PL0 and PL1 are applied when cloud_essd is the only category.
 	// +kubebuilder:validation:Enum:={PL0,PL1,PL2,PL3}
-	// +kubebuilder:default:=PL0
+	// +optional
 	PerformanceLevel *string `json:"performanceLevel,omitempty"`
+	// The provisioned read/write IOPS of the system disk, only ESSD AutoPL disks (cloud_auto) are used when it is set.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=50000
+	// +optional
+	ProvisionedIOPS *int64 `json:"provisionedIOPS,omitempty"`
+	// BurstingEnabled enables the performance burst of the system disk, only ESSD AutoPL disks (cloud_auto) are used
+	// when it is enabled.
+	// +optional
+	BurstingEnabled *bool `json:"burstingEnabled,omitempty"`
+	// Encrypted specifies whether to encrypt the system disk.
+	// +optional
+	Encrypted *bool `json:"encrypted,omitempty"`
+	// KMSKeyID is the ID of the KMS key used to encrypt the system disk, the system disk is encrypted when it is set.
+	// +optional
+	KMSKeyID *string `json:"kmsKeyId,omitempty"`
 }
 
 type DataDisk struct {
@@ -287,6 +558,39 @@ type DataDisk struct {
 	// Mount point of the data disk.
 	// +optional
 	Device *string `json:"device,omitempty"`
+	// The categories of the data disk, in order of preference. Defaults to DataDisksCategories of the ECSNodeClass.
+	// The data disk only falls back to the next categories when all the data disks have the same categories,
+	// otherwise it is created with its first category.
+	// Valid values:"cloud", "cloud_efficiency", "cloud_ssd", "cloud_essd", "cloud_auto", and "cloud_essd_entry"
+	// +kubebuilder:validation:Items=Enum=cloud;cloud_efficiency;cloud_ssd;cloud_essd;cloud_auto;cloud_essd_entry
+	// +optional
+	Categories []string `json:"categories,omitempty"`
+	// The performance level of the data disk when it is an ESSD.
+	// +kubebuilder:validation:Enum:={PL0,PL1,PL2,PL3}
+	// +optional
+	PerformanceLevel *string `json:"performanceLevel,omitempty"`
+	// The provisioned read/write IOPS of the data disk when it is an ESSD AutoPL disk (cloud_auto).
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=50000
+	// +optional
+	ProvisionedIOPS *int64 `json:"provisionedIOPS,omitempty"`
+	// BurstingEnabled enables the performance burst of the data disk when it is an ESSD AutoPL disk (cloud_auto).
+	// +optional
+	BurstingEnabled *bool `json:"burstingEnabled,omitempty"`
+	// Encrypted specifies whether to encrypt the data disk.
+	// +optional
+	Encrypted *bool `json:"encrypted,omitempty"`
+	// KMSKeyID is the ID of the KMS key used to encrypt the data disk, the data disk is encrypted when it is set.
+	// +optional
+	KMSKeyID *string `json:"kmsKeyId,omitempty"`
+	// SnapshotID is the ID of the snapshot the data disk is created from, e.g. to pre-seed the container images.
+	// The volume size must not be smaller than the snapshot.
+	// +kubebuilder:validation:Pattern:="^s-[0-9a-z]+$"
+	// +optional
+	SnapshotID *string `json:"snapshotId,omitempty"`
+	// DeleteWithInstance specifies whether to release the data disk when the instance is released. Defaults to true.
+	// +optional
+	DeleteWithInstance *bool `json:"deleteWithInstance,omitempty"`
 }
 
 // ECSNodeClass is the Schema for the ECSNodeClass API
@@ -394,6 +698,51 @@ func (sd *SystemDisk) GetGiBSize() int32 {
 	return 0
 }
 
+// OnDedicatedHosts returns true when the instances are launched onto dedicated hosts
+func (p *Placement) OnDedicatedHosts() bool {
+	if p == nil {
+		return false
+	}
+	return p.DedicatedHostID != "" || len(p.DedicatedHostClusterSelectorTerms) != 0 || p.Tenancy == TenancyHost
+}
+
+// GetMaxPrice returns the highest hourly price paid for a spot instance whose on-demand price is onDemandPrice,
+// false is returned when the price isn't limited
+func (so *SpotOptions) GetMaxPrice(onDemandPrice float64) (float64, bool) {
+	if so == nil {
+		return 0, false
+	}
+	if so.MaxPrice != nil {
+		return so.MaxPrice.AsApproximateFloat64(), true
+	}
+	if so.MaxPricePercentageOfOnDemand != nil && onDemandPrice > 0 {
+		return onDemandPrice * float64(*so.MaxPricePercentageOfOnDemand) / 100, true
+	}
+	return 0, false
+}
+
+// GetCategories returns the categories of the system disk which offer its performance settings
+func (sd *SystemDisk) GetCategories() []string {
+	categories := sd.Categories
+	if sd.ProvisionedIOPS != nil || lo.FromPtr(sd.BurstingEnabled) {
+		categories = lo.Filter(categories, func(category string, _ int) bool { return category == "cloud_auto" })
+	}
+	if pl := lo.FromPtr(sd.PerformanceLevel); pl == "PL2" || pl == "PL3" {
+		categories = lo.Filter(categories, func(category string, _ int) bool { return category == "cloud_essd" })
+	}
+	return categories
+}
+
+// GetPerformanceLevel returns the performance level the system disk is launched with, nil is returned when the
+// system disk may be created with another category than cloud_essd
+func (sd *SystemDisk) GetPerformanceLevel() *string {
+	categories := lo.Uniq(sd.GetCategories())
+	if len(categories) != 1 || categories[0] != "cloud_essd" {
+		return nil
+	}
+	return sd.PerformanceLevel
+}
+
 func (dd *DataDisk) GetGiBSize() int32 {
 	if dd.VolumeSize != nil {
 		return int32(dd.VolumeSize.Value() / (1024 * 1024 * 1024)) // #nosec G115
@@ -19,12 +19,22 @@ package v1alpha1
 import (
 	"github.com/awslabs/operatorpkg/status"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 const (
 	ConditionTypeVSwitchesReady      = "VSwitchesReady"
 	ConditionTypeSecurityGroupsReady = "SecurityGroupsReady"
 	ConditionTypeImagesReady         = "ImagesReady"
+	ConditionTypeRAMRoleReady        = "RAMRoleReady"
+	ConditionTypeDeploymentSetReady  = "DeploymentSetReady"
+	ConditionTypeDedicatedHostsReady = "DedicatedHostsReady"
+	// ConditionTypeVSwitchIPsExhausted is a warning which doesn't affect the readiness of the ECSNodeClass, it is
+	// set when all the vSwitches of a zone run out of IP addresses
+	ConditionTypeVSwitchIPsExhausted = "VSwitchIPsExhausted"
+
+	CapacityReservationTypeCapacityReservation = "capacity-reservation"
+	CapacityReservationTypeElasticityAssurance = "elasticity-assurance"
 )
 
 // VSwitch contains resolved VSwitch selector values utilized for node launch
@@ -35,6 +45,12 @@ type VSwitch struct {
 	// The associated availability zone ID
 	// +required
 	ZoneID string `json:"zoneID,omitempty"`
+	// The amount of available IP addresses in the vSwitch
+	// +optional
+	AvailableIPAddressCount int64 `json:"availableIPAddressCount"`
+	// The IPv6 CIDR block of the vSwitch, it is empty when IPv6 isn't enabled
+	// +optional
+	IPv6CIDRBlock string `json:"ipv6CIDRBlock,omitempty"`
 }
 
 // SecurityGroup contains resolved SecurityGroup selector values utilized for node launch
@@ -47,6 +63,76 @@ type SecurityGroup struct {
 	Name string `json:"name,omitempty"`
 }
 
+// CapacityReservation contains the remaining capacity of a resolved capacity reservation or elasticity assurance
+// for an instance type in a zone
+type CapacityReservation struct {
+	// ID of the private pool of the capacity reservation or the elasticity assurance
+	// +required
+	ID string `json:"id"`
+	// Type is either capacity-reservation or elasticity-assurance
+	// +kubebuilder:validation:Enum:={capacity-reservation,elasticity-assurance}
+	// +required
+	Type string `json:"type"`
+	// InstanceType reserved by the private pool
+	// +required
+	InstanceType string `json:"instanceType"`
+	// The reserved availability zone ID
+	// +required
+	ZoneID string `json:"zoneID"`
+	// The amount of instances which can still be launched into the private pool
+	// +optional
+	AvailableInstanceCount int32 `json:"availableInstanceCount"`
+}
+
+// DeploymentSet contains the resolved deployment set utilized for node launch
+type DeploymentSet struct {
+	// ID of the deployment set
+	// +required
+	ID string `json:"id"`
+	// Name of the deployment set
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Strategy of the deployment set as reported by ECS, e.g. Availability or LowLatency
+	// +optional
+	Strategy string `json:"strategy,omitempty"`
+	// Capacities contains the amount of instances which can still be placed into the deployment set per zone, the
+	// zones which aren't listed have no limit reported
+	// +optional
+	Capacities []DeploymentSetCapacity `json:"capacities,omitempty"`
+}
+
+// DeploymentSetCapacity is the remaining capacity of a deployment set in a zone
+type DeploymentSetCapacity struct {
+	// The availability zone ID
+	// +required
+	ZoneID string `json:"zoneID"`
+	// The amount of instances which can still be placed into the deployment set in the zone
+	// +optional
+	AvailableInstanceCount int32 `json:"availableInstanceCount"`
+}
+
+// DedicatedHost contains a resolved dedicated host utilized for node launch
+type DedicatedHost struct {
+	// ID of the dedicated host
+	// +required
+	ID string `json:"id"`
+	// ClusterID is the id of the dedicated host cluster the host belongs to
+	// +optional
+	ClusterID string `json:"clusterID,omitempty"`
+	// The associated availability zone ID
+	// +required
+	ZoneID string `json:"zoneID"`
+	// InstanceTypes which can be launched onto the dedicated host
+	// +optional
+	InstanceTypes []string `json:"instanceTypes,omitempty"`
+	// The amount of vCPUs which are still available on the dedicated host
+	// +optional
+	AvailableVCPUs int32 `json:"availableVCPUs"`
+	// The amount of memory which is still available on the dedicated host
+	// +optional
+	AvailableMemory resource.Quantity `json:"availableMemory"`
+}
+
 // Image contains resolved image selector values utilized for node launch
 type Image struct {
 	// ID of the Image
@@ -74,6 +160,16 @@ type ECSNodeClassStatus struct {
 	// cluster under the Image selectors.
 	// +optional
 	Images []Image `json:"images,omitempty"`
+	// CapacityReservations contains the remaining capacity of the capacity reservations and the elasticity
+	// assurances selected by the capacity reservation selectors.
+	// +optional
+	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty"`
+	// DeploymentSet contains the deployment set selected or created for the deployment set selector.
+	// +optional
+	DeploymentSet *DeploymentSet `json:"deploymentSet,omitempty"`
+	// DedicatedHosts contains the dedicated hosts the instances can be launched onto.
+	// +optional
+	DedicatedHosts []DedicatedHost `json:"dedicatedHosts,omitempty"`
 	// Conditions contains signals for health and readiness
 	// +optional
 	Conditions []status.Condition `json:"conditions,omitempty"`
@@ -84,6 +180,9 @@ func (in *ECSNodeClass) StatusConditions() status.ConditionSet {
 		ConditionTypeVSwitchesReady,
 		ConditionTypeSecurityGroupsReady,
 		ConditionTypeImagesReady,
+		ConditionTypeRAMRoleReady,
+		ConditionTypeDeploymentSetReady,
+		ConditionTypeDedicatedHostsReady,
 	).For(in)
 }
 
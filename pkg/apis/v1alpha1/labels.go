@@ -42,6 +42,10 @@ func init() {
 		LabelInstanceGPUManufacturer,
 		LabelInstanceGPUCount,
 		LabelInstanceGPUMemory,
+		LabelInstanceLocalStorageSize,
+		LabelInstanceLocalStorageCategory,
+		LabelInstanceERDMA,
+		LabelInstanceRDMA,
 		LabelTopologyZoneID,
 		corev1.LabelWindowsBuild,
 	)
@@ -78,6 +82,9 @@ var (
 	ResourceAliyunENI             corev1.ResourceName = "aliyun/eni"
 	ResourcePrivateIPv4Address    corev1.ResourceName = "vpc.alibabacloud.com/PrivateIPv4Address"
 	ECSClusterIDTagKey                                = "ecs:ecs-cluster-id"
+	// CapacityTypeReserved is the capacity type of the instances launched into the capacity reservations and the
+	// elasticity assurances, they are billed as pay-as-you-go but the capacity is already paid for
+	CapacityTypeReserved = "reserved"
 
 	LabelNodeClass                           = apis.Group + "/ecsnodeclass"
 	LabelTopologyZoneID                      = "topology.k8s.alibabacloud/zone-id"
@@ -92,6 +99,10 @@ var (
 	LabelInstanceGPUManufacturer             = apis.Group + "/instance-gpu-manufacturer"
 	LabelInstanceGPUCount                    = apis.Group + "/instance-gpu-count"
 	LabelInstanceGPUMemory                   = apis.Group + "/instance-gpu-memory"
+	LabelInstanceLocalStorageSize            = apis.Group + "/instance-local-storage-size"
+	LabelInstanceLocalStorageCategory        = apis.Group + "/instance-local-storage-category"
+	LabelInstanceERDMA                       = apis.Group + "/instance-erdma"
+	LabelInstanceRDMA                        = apis.Group + "/instance-rdma"
 	AnnotationECSNodeClassHash               = apis.Group + "/ecsnodeclass-hash"
 	AnnotationClusterNameTaggedCompatability = apis.CompatibilityGroup + "/cluster-name-tagged"
 	AnnotationECSNodeClassHashVersion        = apis.Group + "/ecsnodeclass-hash-version"
@@ -42,6 +42,43 @@ func (in *Alias) DeepCopy() *Alias {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityReservation) DeepCopyInto(out *CapacityReservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservation.
+func (in *CapacityReservation) DeepCopy() *CapacityReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityReservationSelectorTerm) DeepCopyInto(out *CapacityReservationSelectorTerm) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservationSelectorTerm.
+func (in *CapacityReservationSelectorTerm) DeepCopy() *CapacityReservationSelectorTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityReservationSelectorTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataDisk) DeepCopyInto(out *DataDisk) {
 	*out = *in
@@ -55,6 +92,46 @@ func (in *DataDisk) DeepCopyInto(out *DataDisk) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Categories != nil {
+		in, out := &in.Categories, &out.Categories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PerformanceLevel != nil {
+		in, out := &in.PerformanceLevel, &out.PerformanceLevel
+		*out = new(string)
+		**out = **in
+	}
+	if in.ProvisionedIOPS != nil {
+		in, out := &in.ProvisionedIOPS, &out.ProvisionedIOPS
+		*out = new(int64)
+		**out = **in
+	}
+	if in.BurstingEnabled != nil {
+		in, out := &in.BurstingEnabled, &out.BurstingEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Encrypted != nil {
+		in, out := &in.Encrypted, &out.Encrypted
+		*out = new(bool)
+		**out = **in
+	}
+	if in.KMSKeyID != nil {
+		in, out := &in.KMSKeyID, &out.KMSKeyID
+		*out = new(string)
+		**out = **in
+	}
+	if in.SnapshotID != nil {
+		in, out := &in.SnapshotID, &out.SnapshotID
+		*out = new(string)
+		**out = **in
+	}
+	if in.DeleteWithInstance != nil {
+		in, out := &in.DeleteWithInstance, &out.DeleteWithInstance
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataDisk.
@@ -67,6 +144,106 @@ func (in *DataDisk) DeepCopy() *DataDisk {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DedicatedHost) DeepCopyInto(out *DedicatedHost) {
+	*out = *in
+	if in.InstanceTypes != nil {
+		in, out := &in.InstanceTypes, &out.InstanceTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.AvailableMemory = in.AvailableMemory.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DedicatedHost.
+func (in *DedicatedHost) DeepCopy() *DedicatedHost {
+	if in == nil {
+		return nil
+	}
+	out := new(DedicatedHost)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DedicatedHostClusterSelectorTerm) DeepCopyInto(out *DedicatedHostClusterSelectorTerm) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DedicatedHostClusterSelectorTerm.
+func (in *DedicatedHostClusterSelectorTerm) DeepCopy() *DedicatedHostClusterSelectorTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(DedicatedHostClusterSelectorTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentSet) DeepCopyInto(out *DeploymentSet) {
+	*out = *in
+	if in.Capacities != nil {
+		in, out := &in.Capacities, &out.Capacities
+		*out = make([]DeploymentSetCapacity, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentSet.
+func (in *DeploymentSet) DeepCopy() *DeploymentSet {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentSetCapacity) DeepCopyInto(out *DeploymentSetCapacity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentSetCapacity.
+func (in *DeploymentSetCapacity) DeepCopy() *DeploymentSetCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentSetCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentSetSelector) DeepCopyInto(out *DeploymentSetSelector) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentSetSelector.
+func (in *DeploymentSetSelector) DeepCopy() *DeploymentSetSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentSetSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ECSNodeClass) DeepCopyInto(out *ECSNodeClass) {
 	*out = *in
@@ -136,6 +313,58 @@ func (in *ECSNodeClassSpec) DeepCopyInto(out *ECSNodeClassSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SpotRiskPolicy != nil {
+		in, out := &in.SpotRiskPolicy, &out.SpotRiskPolicy
+		*out = new(SpotRiskPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SpotOptions != nil {
+		in, out := &in.SpotOptions, &out.SpotOptions
+		*out = new(SpotOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CapacityReservationSelectorTerms != nil {
+		in, out := &in.CapacityReservationSelectorTerms, &out.CapacityReservationSelectorTerms
+		*out = make([]CapacityReservationSelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DeploymentSetSelector != nil {
+		in, out := &in.DeploymentSetSelector, &out.DeploymentSetSelector
+		*out = new(DeploymentSetSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(Placement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AssociatePublicIPAddress != nil {
+		in, out := &in.AssociatePublicIPAddress, &out.AssociatePublicIPAddress
+		*out = new(bool)
+		**out = **in
+	}
+	if in.InternetChargeType != nil {
+		in, out := &in.InternetChargeType, &out.InternetChargeType
+		*out = new(string)
+		**out = **in
+	}
+	if in.InternetMaxBandwidthOut != nil {
+		in, out := &in.InternetMaxBandwidthOut, &out.InternetMaxBandwidthOut
+		*out = new(int32)
+		**out = **in
+	}
+	if in.InternetMaxBandwidthIn != nil {
+		in, out := &in.InternetMaxBandwidthIn, &out.InternetMaxBandwidthIn
+		*out = new(int32)
+		**out = **in
+	}
+	if in.IPv6AddressCount != nil {
+		in, out := &in.IPv6AddressCount, &out.IPv6AddressCount
+		*out = new(int32)
+		**out = **in
+	}
 	if in.SecurityGroupSelectorTerms != nil {
 		in, out := &in.SecurityGroupSelectorTerms, &out.SecurityGroupSelectorTerms
 		*out = make([]SecurityGroupSelectorTerm, len(*in))
@@ -146,7 +375,9 @@ func (in *ECSNodeClassSpec) DeepCopyInto(out *ECSNodeClassSpec) {
 	if in.ImageSelectorTerms != nil {
 		in, out := &in.ImageSelectorTerms, &out.ImageSelectorTerms
 		*out = make([]ImageSelectorTerm, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.KubeletConfiguration != nil {
 		in, out := &in.KubeletConfiguration, &out.KubeletConfiguration
@@ -170,6 +401,11 @@ func (in *ECSNodeClassSpec) DeepCopyInto(out *ECSNodeClassSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.InstanceStorePolicy != nil {
+		in, out := &in.InstanceStorePolicy, &out.InstanceStorePolicy
+		*out = new(string)
+		**out = **in
+	}
 	if in.Tags != nil {
 		in, out := &in.Tags, &out.Tags
 		*out = make(map[string]string, len(*in))
@@ -214,6 +450,23 @@ func (in *ECSNodeClassStatus) DeepCopyInto(out *ECSNodeClassStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CapacityReservations != nil {
+		in, out := &in.CapacityReservations, &out.CapacityReservations
+		*out = make([]CapacityReservation, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeploymentSet != nil {
+		in, out := &in.DeploymentSet, &out.DeploymentSet
+		*out = new(DeploymentSet)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DedicatedHosts != nil {
+		in, out := &in.DedicatedHosts, &out.DedicatedHosts
+		*out = make([]DedicatedHost, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]status.Condition, len(*in))
@@ -258,6 +511,13 @@ func (in *Image) DeepCopy() *Image {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageSelectorTerm) DeepCopyInto(out *ImageSelectorTerm) {
 	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSelectorTerm.
@@ -355,6 +615,28 @@ func (in *KubeletConfiguration) DeepCopy() *KubeletConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Placement) DeepCopyInto(out *Placement) {
+	*out = *in
+	if in.DedicatedHostClusterSelectorTerms != nil {
+		in, out := &in.DedicatedHostClusterSelectorTerms, &out.DedicatedHostClusterSelectorTerms
+		*out = make([]DedicatedHostClusterSelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Placement.
+func (in *Placement) DeepCopy() *Placement {
+	if in == nil {
+		return nil
+	}
+	out := new(Placement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecurityGroup) DeepCopyInto(out *SecurityGroup) {
 	*out = *in
@@ -392,6 +674,56 @@ func (in *SecurityGroupSelectorTerm) DeepCopy() *SecurityGroupSelectorTerm {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpotOptions) DeepCopyInto(out *SpotOptions) {
+	*out = *in
+	if in.MaxPrice != nil {
+		in, out := &in.MaxPrice, &out.MaxPrice
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxPricePercentageOfOnDemand != nil {
+		in, out := &in.MaxPricePercentageOfOnDemand, &out.MaxPricePercentageOfOnDemand
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpotOptions.
+func (in *SpotOptions) DeepCopy() *SpotOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SpotOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpotRiskPolicy) DeepCopyInto(out *SpotRiskPolicy) {
+	*out = *in
+	if in.MaxRiskScore != nil {
+		in, out := &in.MaxRiskScore, &out.MaxRiskScore
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpotRiskPolicy.
+func (in *SpotRiskPolicy) DeepCopy() *SpotRiskPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SpotRiskPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SystemDisk) DeepCopyInto(out *SystemDisk) {
 	*out = *in
@@ -415,6 +747,26 @@ func (in *SystemDisk) DeepCopyInto(out *SystemDisk) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.ProvisionedIOPS != nil {
+		in, out := &in.ProvisionedIOPS, &out.ProvisionedIOPS
+		*out = new(int64)
+		**out = **in
+	}
+	if in.BurstingEnabled != nil {
+		in, out := &in.BurstingEnabled, &out.BurstingEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Encrypted != nil {
+		in, out := &in.Encrypted, &out.Encrypted
+		*out = new(bool)
+		**out = **in
+	}
+	if in.KMSKeyID != nil {
+		in, out := &in.KMSKeyID, &out.KMSKeyID
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SystemDisk.
@@ -0,0 +1,164 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batcher
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
+)
+
+const (
+	// ErrCodeTooFewInstances is reported to the requestors that didn't get an instance when the batched
+	// request was partially fulfilled without any launch error
+	ErrCodeTooFewInstances = "TooFewInstancesReturned"
+)
+
+type CreateAutoProvisioningGroupBatcher struct {
+	batcher *Batcher[ecsclient.CreateAutoProvisioningGroupRequest, ecsclient.CreateAutoProvisioningGroupResponse]
+}
+
+func NewCreateAutoProvisioningGroupBatcher(ctx context.Context, ecsapi sdk.ECSAPI) *CreateAutoProvisioningGroupBatcher {
+	// The launches are throttled per API call, a batch is only counted once
+	limiter := rate.NewLimiter(rate.Limit(1), options.FromContext(ctx).APGCreationQPS)
+	batcherOptions := Options[ecsclient.CreateAutoProvisioningGroupRequest, ecsclient.CreateAutoProvisioningGroupResponse]{
+		Name:        "create_auto_provisioning_group",
+		IdleTimeout: 35 * time.Millisecond,
+		MaxTimeout:  1 * time.Second,
+		// The amount of instances an instant auto provisioning group can launch at once
+		MaxItems:      1_000,
+		RequestHasher: DefaultHasher[ecsclient.CreateAutoProvisioningGroupRequest],
+		BatchExecutor: execCreateAutoProvisioningGroupBatch(ecsapi, limiter),
+	}
+	return &CreateAutoProvisioningGroupBatcher{batcher: NewBatcher(ctx, batcherOptions)}
+}
+
+func (b *CreateAutoProvisioningGroupBatcher) CreateAutoProvisioningGroup(ctx context.Context,
+	request *ecsclient.CreateAutoProvisioningGroupRequest) (*ecsclient.CreateAutoProvisioningGroupResponse, error) {
+	if capacity := tea.StringValue(request.TotalTargetCapacity); capacity != "1" {
+		return nil, fmt.Errorf("expected to receive a single instance only, found %s", capacity)
+	}
+	result := b.batcher.Add(ctx, request)
+	return result.Output, result.Err
+}
+
+func execCreateAutoProvisioningGroupBatch(ecsapi sdk.ECSAPI, limiter *rate.Limiter) BatchExecutor[ecsclient.CreateAutoProvisioningGroupRequest, ecsclient.CreateAutoProvisioningGroupResponse] {
+	return func(ctx context.Context, requests []*ecsclient.CreateAutoProvisioningGroupRequest) []Result[ecsclient.CreateAutoProvisioningGroupResponse] {
+		results := make([]Result[ecsclient.CreateAutoProvisioningGroupResponse], 0, len(requests))
+		// The requests are identical, the first one is copied so the requestor's request is left untouched
+		batchRequest := *requests[0]
+		capacity := tea.String(strconv.Itoa(len(requests)))
+		batchRequest.TotalTargetCapacity = capacity
+		if tea.StringValue(batchRequest.SpotTargetCapacity) == "1" {
+			batchRequest.SpotTargetCapacity = capacity
+		}
+		if tea.StringValue(batchRequest.PayAsYouGoTargetCapacity) == "1" {
+			batchRequest.PayAsYouGoTargetCapacity = capacity
+		}
+
+		resp, err := createAutoProvisioningGroup(ctx, ecsapi, limiter, &batchRequest)
+		if err != nil {
+			for range requests {
+				results = append(results, Result[ecsclient.CreateAutoProvisioningGroupResponse]{Err: err})
+			}
+			return results
+		}
+
+		// we can get partial fulfillment of an auto provisioning group, so we:
+		// 1) split out the single instance IDs and deliver to each requestor
+		// 2) deliver the failed launch results to any remaining requestors for which we don't have an instance
+		var launched, failed []*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult
+		for _, launchResult := range resp.Body.LaunchResults.LaunchResult {
+			if launchResult == nil {
+				continue
+			}
+			if launchResult.InstanceIds == nil || len(launchResult.InstanceIds.InstanceId) == 0 {
+				failed = append(failed, launchResult)
+				continue
+			}
+			launched = append(launched, launchResult)
+		}
+
+		for _, launchResult := range launched {
+			for _, instanceID := range launchResult.InstanceIds.InstanceId {
+				if len(results) >= len(requests) {
+					log.FromContext(ctx).Error(fmt.Errorf("received more instances than requested, ignoring instance %s", tea.StringValue(instanceID)), "received error while batching")
+					continue
+				}
+				single := *launchResult
+				single.Amount = tea.Int32(1)
+				single.InstanceIds = &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResultInstanceIds{InstanceId: []*string{instanceID}}
+				results = append(results, Result[ecsclient.CreateAutoProvisioningGroupResponse]{
+					Output: withLaunchResults(resp, append([]*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{&single}, failed...)),
+				})
+			}
+		}
+
+		if len(results) < len(requests) {
+			// we should receive some sort of error, but just in case
+			if len(failed) == 0 {
+				failed = append(failed, &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{
+					ErrorCode: tea.String(ErrCodeTooFewInstances),
+					ErrorMsg:  tea.String("too few instances returned"),
+				})
+			}
+			for len(results) < len(requests) {
+				results = append(results, Result[ecsclient.CreateAutoProvisioningGroupResponse]{
+					Output: withLaunchResults(resp, failed),
+				})
+			}
+		}
+		return results
+	}
+}
+
+func createAutoProvisioningGroup(ctx context.Context, ecsapi sdk.ECSAPI, limiter *rate.Limiter,
+	request *ecsclient.CreateAutoProvisioningGroupRequest) (*ecsclient.CreateAutoProvisioningGroupResponse, error) {
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit exceeded: %w", err)
+	}
+	resp, err := ecsapi.CreateAutoProvisioningGroupWithOptions(request, &util.RuntimeOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Body == nil || resp.Body.LaunchResults == nil {
+		return nil, fmt.Errorf("invalid response when creating auto provision group: %s", tea.Prettify(resp))
+	}
+	return resp, nil
+}
+
+// withLaunchResults copies the response of the batched request with the launch results of a single requestor
+func withLaunchResults(resp *ecsclient.CreateAutoProvisioningGroupResponse,
+	launchResults []*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult) *ecsclient.CreateAutoProvisioningGroupResponse {
+	body := *resp.Body
+	body.LaunchResults = &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResults{LaunchResult: launchResults}
+	return &ecsclient.CreateAutoProvisioningGroupResponse{
+		Headers:    resp.Headers,
+		StatusCode: resp.StatusCode,
+		Body:       &body,
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
+)
+
+func newTestContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithCancel(options.ToContext(context.Background(), &options.Options{APGCreationQPS: 100}))
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func newTestCreateAutoProvisioningGroupRequest(instanceTypes ...string) *ecsclient.CreateAutoProvisioningGroupRequest {
+	return &ecsclient.CreateAutoProvisioningGroupRequest{
+		RegionId:                 tea.String(fake.DefaultRegion),
+		TotalTargetCapacity:      tea.String("1"),
+		PayAsYouGoTargetCapacity: tea.String("1"),
+		SpotTargetCapacity:       tea.String("0"),
+		LaunchTemplateConfig: lo.Map(instanceTypes, func(instanceType string, _ int) *ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig {
+			return &ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig{
+				InstanceType: tea.String(instanceType),
+				VSwitchId:    tea.String("vsw-test"),
+			}
+		}),
+		LaunchConfiguration: &ecsclient.CreateAutoProvisioningGroupRequestLaunchConfiguration{
+			ImageId: tea.String("image-test"),
+		},
+	}
+}
+
+func newTestCloud(availableIPs int64) (*fake.VPCAPI, *fake.ECSAPI) {
+	vpcAPI := fake.NewVPCAPI()
+	vpcAPI.AddVSwitches(fake.VSwitch{ID: "vsw-test", ZoneID: "cn-beijing-a", AvailableIPAddressCount: availableIPs})
+	return vpcAPI, fake.NewECSAPI(vpcAPI)
+}
+
+func createConcurrently(ctx context.Context, b *CreateAutoProvisioningGroupBatcher,
+	requests []*ecsclient.CreateAutoProvisioningGroupRequest) ([]*ecsclient.CreateAutoProvisioningGroupResponse, []error) {
+	responses := make([]*ecsclient.CreateAutoProvisioningGroupResponse, len(requests))
+	errs := make([]error, len(requests))
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = b.CreateAutoProvisioningGroup(ctx, requests[i])
+		}()
+	}
+	wg.Wait()
+	return responses, errs
+}
+
+func launchedInstanceIDs(resp *ecsclient.CreateAutoProvisioningGroupResponse) []string {
+	launchResult := resp.Body.LaunchResults.LaunchResult[0]
+	if launchResult.InstanceIds == nil {
+		return nil
+	}
+	return tea.StringSliceValue(launchResult.InstanceIds.InstanceId)
+}
+
+func TestCreateAutoProvisioningGroupBatcher_BatchesIdenticalRequests(t *testing.T) {
+	ctx := newTestContext(t)
+	_, ecsAPI := newTestCloud(100)
+	b := NewCreateAutoProvisioningGroupBatcher(ctx, ecsAPI)
+
+	requests := lo.Times(5, func(i int) *ecsclient.CreateAutoProvisioningGroupRequest {
+		return newTestCreateAutoProvisioningGroupRequest("ecs.g7.large")
+	})
+	responses, errs := createConcurrently(ctx, b, requests)
+
+	var instanceIDs []string
+	for i := range responses {
+		require.NoError(t, errs[i])
+		ids := launchedInstanceIDs(responses[i])
+		require.Len(t, ids, 1)
+		instanceIDs = append(instanceIDs, ids...)
+	}
+	assert.Len(t, lo.Uniq(instanceIDs), 5)
+
+	calls := ecsAPI.CreateAutoProvisioningGroupRequests()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "5", tea.StringValue(calls[0].TotalTargetCapacity))
+	assert.Equal(t, "5", tea.StringValue(calls[0].PayAsYouGoTargetCapacity))
+	assert.Equal(t, "0", tea.StringValue(calls[0].SpotTargetCapacity))
+	for _, request := range requests {
+		assert.Equal(t, "1", tea.StringValue(request.TotalTargetCapacity))
+		assert.Equal(t, "1", tea.StringValue(request.PayAsYouGoTargetCapacity))
+	}
+}
+
+func TestCreateAutoProvisioningGroupBatcher_SeparatesDifferentRequests(t *testing.T) {
+	ctx := newTestContext(t)
+	_, ecsAPI := newTestCloud(100)
+	b := NewCreateAutoProvisioningGroupBatcher(ctx, ecsAPI)
+
+	requests := []*ecsclient.CreateAutoProvisioningGroupRequest{
+		newTestCreateAutoProvisioningGroupRequest("ecs.g7.large"),
+		newTestCreateAutoProvisioningGroupRequest("ecs.g7.large"),
+		newTestCreateAutoProvisioningGroupRequest("ecs.g7.xlarge"),
+	}
+	responses, errs := createConcurrently(ctx, b, requests)
+
+	for i := range responses {
+		require.NoError(t, errs[i])
+		require.Len(t, launchedInstanceIDs(responses[i]), 1)
+	}
+	assert.Equal(t, "ecs.g7.xlarge", tea.StringValue(responses[2].Body.LaunchResults.LaunchResult[0].InstanceType))
+	calls := ecsAPI.CreateAutoProvisioningGroupRequests()
+	require.Len(t, calls, 2)
+	assert.ElementsMatch(t, []string{"1", "2"}, lo.Map(calls, func(call *ecsclient.CreateAutoProvisioningGroupRequest, _ int) string {
+		return tea.StringValue(call.TotalTargetCapacity)
+	}))
+}
+
+func TestCreateAutoProvisioningGroupBatcher_PartialFulfillment(t *testing.T) {
+	ctx := newTestContext(t)
+	_, ecsAPI := newTestCloud(3)
+	ecsAPI.AddInsufficientCapacityPools(fake.CapacityPool{InstanceType: "ecs.g7.large", ZoneID: "cn-beijing-a", CapacityType: "on-demand"})
+	b := NewCreateAutoProvisioningGroupBatcher(ctx, ecsAPI)
+
+	requests := lo.Times(5, func(i int) *ecsclient.CreateAutoProvisioningGroupRequest {
+		return newTestCreateAutoProvisioningGroupRequest("ecs.g7.large", "ecs.g7.xlarge")
+	})
+	responses, errs := createConcurrently(ctx, b, requests)
+
+	launched := 0
+	for i := range responses {
+		require.NoError(t, errs[i])
+		launchResults := responses[i].Body.LaunchResults.LaunchResult
+		if ids := launchedInstanceIDs(responses[i]); len(ids) > 0 {
+			launched++
+			require.Len(t, ids, 1)
+			assert.Equal(t, "ecs.g7.xlarge", tea.StringValue(launchResults[0].InstanceType))
+			// The failed launches are kept, so the unavailable offerings can still be recorded
+			assert.Equal(t, fake.ErrCodeNoStock, tea.StringValue(launchResults[1].ErrorCode))
+			continue
+		}
+		assert.Equal(t, []string{fake.ErrCodeNoStock, fake.ErrCodeIPNotEnough}, lo.Map(launchResults,
+			func(launchResult *ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult, _ int) string {
+				return tea.StringValue(launchResult.ErrorCode)
+			}))
+	}
+	assert.Equal(t, 3, launched)
+	assert.Len(t, ecsAPI.Instances(), 3)
+}
+
+func TestCreateAutoProvisioningGroupBatcher_TooFewInstances(t *testing.T) {
+	ctx := newTestContext(t)
+	_, ecsAPI := newTestCloud(100)
+	b := NewCreateAutoProvisioningGroupBatcher(ctx, ecsAPI)
+
+	request := newTestCreateAutoProvisioningGroupRequest()
+	resp, err := b.CreateAutoProvisioningGroup(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Body.LaunchResults.LaunchResult, 1)
+	assert.Equal(t, ErrCodeTooFewInstances, tea.StringValue(resp.Body.LaunchResults.LaunchResult[0].ErrorCode))
+}
+
+func TestCreateAutoProvisioningGroupBatcher_Errors(t *testing.T) {
+	ctx := newTestContext(t)
+	_, ecsAPI := newTestCloud(100)
+	b := NewCreateAutoProvisioningGroupBatcher(ctx, ecsAPI)
+
+	request := newTestCreateAutoProvisioningGroupRequest("ecs.g7.large")
+	request.TotalTargetCapacity = tea.String("2")
+	_, err := b.CreateAutoProvisioningGroup(ctx, request)
+	assert.Error(t, err)
+	assert.Empty(t, ecsAPI.CreateAutoProvisioningGroupRequests())
+
+	apiErr := errors.New("throttled")
+	ecsAPI.CreateAutoProvisioningGroupError.Set(apiErr)
+	requests := lo.Times(3, func(i int) *ecsclient.CreateAutoProvisioningGroupRequest {
+		return newTestCreateAutoProvisioningGroupRequest("ecs.g7.large")
+	})
+	_, errs := createConcurrently(ctx, b, requests)
+	for i := range errs {
+		assert.ErrorIs(t, errs[i], apiErr)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+)
+
+// deleteInstancesParallelism is the amount of instances deleted at once when a batch is retried per instance
+const deleteInstancesParallelism = 10
+
+type DeleteInstancesBatcher struct {
+	batcher *Batcher[ecsclient.DeleteInstancesRequest, ecsclient.DeleteInstancesResponse]
+}
+
+func NewDeleteInstancesBatcher(ctx context.Context, ecsapi sdk.ECSAPI) *DeleteInstancesBatcher {
+	options := Options[ecsclient.DeleteInstancesRequest, ecsclient.DeleteInstancesResponse]{
+		Name:        "delete_instances",
+		IdleTimeout: 100 * time.Millisecond,
+		MaxTimeout:  1 * time.Second,
+		// The amount of instances a DeleteInstances call accepts
+		MaxItems:      100,
+		RequestHasher: deleteInstancesHasher,
+		BatchExecutor: execDeleteInstancesBatch(ecsapi),
+	}
+	return &DeleteInstancesBatcher{batcher: NewBatcher(ctx, options)}
+}
+
+func (b *DeleteInstancesBatcher) DeleteInstances(ctx context.Context, request *ecsclient.DeleteInstancesRequest) (*ecsclient.DeleteInstancesResponse, error) {
+	if len(request.InstanceId) != 1 {
+		return nil, fmt.Errorf("expected to receive a single instance only, found %d", len(request.InstanceId))
+	}
+	result := b.batcher.Add(ctx, request)
+	return result.Output, result.Err
+}
+
+// deleteInstancesHasher hashes the request without the instance, so only the deletions with the same options are batched
+func deleteInstancesHasher(ctx context.Context, request *ecsclient.DeleteInstancesRequest) uint64 {
+	req := *request
+	req.InstanceId = nil
+	return DefaultHasher(ctx, &req)
+}
+
+func execDeleteInstancesBatch(ecsapi sdk.ECSAPI) BatchExecutor[ecsclient.DeleteInstancesRequest, ecsclient.DeleteInstancesResponse] {
+	return func(ctx context.Context, requests []*ecsclient.DeleteInstancesRequest) []Result[ecsclient.DeleteInstancesResponse] {
+		results := make([]Result[ecsclient.DeleteInstancesResponse], len(requests))
+		firstRequest := requests[0]
+
+		// aggregate the instance IDs into 1 request, the same instance may be deleted by several requestors
+		instanceIDs := lo.Uniq(lo.Map(requests, func(req *ecsclient.DeleteInstancesRequest, _ int) string {
+			return tea.StringValue(req.InstanceId[0])
+		}))
+		batchedRequest := *firstRequest
+		batchedRequest.InstanceId = tea.StringSlice(instanceIDs)
+
+		resp, err := ecsapi.DeleteInstancesWithOptions(&batchedRequest, &util.RuntimeOptions{})
+		if err == nil || len(instanceIDs) == 1 {
+			for i := range results {
+				results[i] = Result[ecsclient.DeleteInstancesResponse]{Output: resp, Err: err}
+			}
+			return results
+		}
+
+		// DeleteInstances fails as a whole when any of the instances can't be deleted, e.g. it is already gone or
+		// still being created. So we try to delete them individually now to map the errors back to the requestors.
+		// The requestors keep the error of the batch when the context is done before their instance is retried.
+		log.FromContext(ctx).Error(err, "failed deleting instances, deleting them individually", "count", len(instanceIDs))
+		for i := range results {
+			results[i] = Result[ecsclient.DeleteInstancesResponse]{Err: err}
+		}
+		workqueue.ParallelizeUntil(ctx, deleteInstancesParallelism, len(instanceIDs), func(i int) {
+			req := *firstRequest
+			req.InstanceId = []*string{tea.String(instanceIDs[i])}
+			out, err := ecsapi.DeleteInstancesWithOptions(&req, &util.RuntimeOptions{})
+
+			// Find all indexes where we are requesting this instance and populate with the result
+			for reqID := range requests {
+				if tea.StringValue(requests[reqID].InstanceId[0]) == instanceIDs[i] {
+					results[reqID] = Result[ecsclient.DeleteInstancesResponse]{Output: out, Err: err}
+				}
+			}
+		})
+		return results
+	}
+}
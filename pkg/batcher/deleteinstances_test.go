@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
+)
+
+func newTestDeleteInstancesRequest(instanceID string) *ecsclient.DeleteInstancesRequest {
+	return &ecsclient.DeleteInstancesRequest{
+		RegionId:              tea.String(fake.DefaultRegion),
+		InstanceId:            []*string{tea.String(instanceID)},
+		Force:                 tea.Bool(true),
+		TerminateSubscription: tea.Bool(true),
+	}
+}
+
+func addTestInstances(ecsAPI *fake.ECSAPI, status string, ids ...string) {
+	for _, id := range ids {
+		ecsAPI.AddInstances(&ecsclient.DescribeInstancesResponseBodyInstancesInstance{
+			InstanceId: tea.String(id),
+			Status:     tea.String(status),
+		})
+	}
+}
+
+func deleteConcurrently(ctx context.Context, b *DeleteInstancesBatcher, instanceIDs []string) []error {
+	errs := make([]error, len(instanceIDs))
+	var wg sync.WaitGroup
+	for i := range instanceIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = b.DeleteInstances(ctx, newTestDeleteInstancesRequest(instanceIDs[i]))
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestDeleteInstancesBatcher_BatchesDeletions(t *testing.T) {
+	ctx := newTestContext(t)
+	_, ecsAPI := newTestCloud(100)
+	instanceIDs := []string{"i-1", "i-2", "i-3", "i-4", "i-5"}
+	addTestInstances(ecsAPI, "Running", instanceIDs...)
+	b := NewDeleteInstancesBatcher(ctx, ecsAPI)
+
+	// The same instance may be deleted by several callers at once
+	errs := deleteConcurrently(ctx, b, append(instanceIDs, "i-1"))
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Empty(t, ecsAPI.Instances())
+
+	calls := ecsAPI.DeleteInstancesRequests()
+	require.Len(t, calls, 1)
+	assert.ElementsMatch(t, instanceIDs, tea.StringSliceValue(calls[0].InstanceId))
+}
+
+func TestDeleteInstancesBatcher_MapsErrorsToInstances(t *testing.T) {
+	ctx := newTestContext(t)
+	_, ecsAPI := newTestCloud(100)
+	addTestInstances(ecsAPI, "Running", "i-running-1", "i-running-2")
+	addTestInstances(ecsAPI, "Pending", "i-pending")
+	b := NewDeleteInstancesBatcher(ctx, ecsAPI)
+
+	instanceIDs := []string{"i-running-1", "i-running-2", "i-pending", "i-missing"}
+	errs := deleteConcurrently(ctx, b, instanceIDs)
+
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.True(t, alierrors.IsIncorrectInstanceStatus(errs[2]))
+	assert.True(t, alierrors.IsNotFound(errs[3]))
+	assert.Equal(t, []string{"i-pending"}, lo.Map(ecsAPI.Instances(), func(instance *ecsclient.DescribeInstancesResponseBodyInstancesInstance, _ int) string {
+		return tea.StringValue(instance.InstanceId)
+	}))
+	// The failed batch is retried per instance
+	assert.Len(t, ecsAPI.DeleteInstancesRequests(), 1+len(instanceIDs))
+}
+
+// inFlightECSAPI records the most DeleteInstances calls in flight at once
+type inFlightECSAPI struct {
+	*fake.ECSAPI
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (e *inFlightECSAPI) DeleteInstancesWithOptions(request *ecsclient.DeleteInstancesRequest,
+	runtime *util.RuntimeOptions) (*ecsclient.DeleteInstancesResponse, error) {
+	inFlight := e.inFlight.Add(1)
+	defer e.inFlight.Add(-1)
+	for {
+		maxInFlight := e.maxInFlight.Load()
+		if inFlight <= maxInFlight || e.maxInFlight.CompareAndSwap(maxInFlight, inFlight) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return e.ECSAPI.DeleteInstancesWithOptions(request, runtime)
+}
+
+func TestDeleteInstancesBatcher_BoundsIndividualDeletions(t *testing.T) {
+	ctx := newTestContext(t)
+	_, ecsAPI := newTestCloud(100)
+	instanceIDs := lo.Times(50, func(i int) string { return fmt.Sprintf("i-%02d", i) })
+	addTestInstances(ecsAPI, "Running", instanceIDs...)
+	api := &inFlightECSAPI{ECSAPI: ecsAPI}
+	b := NewDeleteInstancesBatcher(ctx, api)
+
+	// A single missing instance fails the batch, the other instances are deleted individually
+	errs := deleteConcurrently(ctx, b, append(instanceIDs, "i-missing"))
+	for _, err := range errs[:len(instanceIDs)] {
+		assert.NoError(t, err)
+	}
+	assert.True(t, alierrors.IsNotFound(errs[len(instanceIDs)]))
+	assert.Empty(t, ecsAPI.Instances())
+	assert.Len(t, ecsAPI.DeleteInstancesRequests(), 1+len(instanceIDs)+1)
+	assert.LessOrEqual(t, api.maxInFlight.Load(), int32(deleteInstancesParallelism))
+}
+
+func TestDeleteInstancesBatcher_SplitsByOptions(t *testing.T) {
+	ctx := newTestContext(t)
+	_, ecsAPI := newTestCloud(100)
+	addTestInstances(ecsAPI, "Running", "i-1", "i-2")
+	b := NewDeleteInstancesBatcher(ctx, ecsAPI)
+
+	requests := []*ecsclient.DeleteInstancesRequest{newTestDeleteInstancesRequest("i-1"), newTestDeleteInstancesRequest("i-2")}
+	requests[1].Force = tea.Bool(false)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := b.DeleteInstances(ctx, requests[i])
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, ecsAPI.DeleteInstancesRequests(), 2)
+
+	_, err := b.DeleteInstances(ctx, &ecsclient.DeleteInstancesRequest{InstanceId: tea.StringSlice([]string{"i-1", "i-2"})})
+	assert.Error(t, err)
+}
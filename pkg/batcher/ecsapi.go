@@ -0,0 +1,35 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batcher
+
+import (
+	"context"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+)
+
+type ECSAPI struct {
+	*CreateAutoProvisioningGroupBatcher
+	*DeleteInstancesBatcher
+}
+
+func ECS(ctx context.Context, ecsapi sdk.ECSAPI) *ECSAPI {
+	return &ECSAPI{
+		CreateAutoProvisioningGroupBatcher: NewCreateAutoProvisioningGroupBatcher(ctx, ecsapi),
+		DeleteInstancesBatcher:             NewDeleteInstancesBatcher(ctx, ecsapi),
+	}
+}
@@ -46,4 +46,10 @@ const (
 
 	// InstanceTypesAndZonesTTL is the time before we refresh instance types and zones at ECS
 	InstanceTypesAndZonesTTL = 5 * time.Minute
+	// InterruptionEventTTL is the time to remember a handled instance event, so it is not handled again
+	// while ECS keeps reporting it
+	InterruptionEventTTL = time.Hour
+	// SpotRiskWindow is how far back the spot price history and the spot interruptions are looked at
+	// to score the interruption risk of the spot offerings
+	SpotRiskWindow = 24 * time.Hour
 )
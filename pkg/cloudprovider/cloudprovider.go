@@ -44,6 +44,7 @@ import (
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instance"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instancetype"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
 )
 
 const CloudProviderName = "alibabacloud"
@@ -98,6 +99,9 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 	}
 	instance, err := c.instanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
 	if err != nil {
+		if aliErr, ok := alierrors.AsError(err); ok {
+			c.recorder.Publish(cloudproviderevents.NodeClaimFailedToLaunch(nodeClaim, string(aliErr.Category), aliErr.Code, aliErr.Message))
+		}
 		return nil, fmt.Errorf("creating instance, %w", err)
 	}
 	instanceType, _ := lo.Find(instanceTypes, func(i *cloudprovider.InstanceType) bool {
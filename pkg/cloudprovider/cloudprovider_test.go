@@ -0,0 +1,304 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	kcache "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/cache"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/cluster"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/imagefamily"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instance"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instancetype"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/spotrisk"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/vswitch"
+)
+
+const (
+	testClusterID = "c-test"
+	testZoneID    = "cn-beijing-i"
+)
+
+type testRecorder struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (r *testRecorder) Publish(evts ...events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, evts...)
+}
+
+// testEnv wires the cloud provider to the instance type and instance providers backed by the fake ECS and VPC
+type testEnv struct {
+	ctx           context.Context
+	kubeClient    client.Client
+	ecsAPI        *fake.ECSAPI
+	vpcAPI        *fake.VPCAPI
+	nodeClass     *v1alpha1.ECSNodeClass
+	nodePool      *karpv1.NodePool
+	cloudProvider *CloudProvider
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	ctx := options.ToContext(context.Background(), &options.Options{ClusterID: testClusterID, APGCreationQPS: 100})
+	vpcAPI := fake.NewVPCAPI()
+	vpcAPI.AddVSwitches(fake.VSwitch{ID: "vsw-test", ZoneID: testZoneID, AvailableIPAddressCount: 1000})
+	ecsAPI := fake.NewECSAPI(vpcAPI)
+	ecsAPI.AddInstanceTypes(
+		newTestInstanceTypeInfo("ecs.g7.large", "X86"),
+		newTestInstanceTypeInfo("ecs.g8y.large", "ARM"),
+	)
+	pricingProvider := fake.NewPricingProvider()
+	for instanceType, price := range map[string]float64{"ecs.g7.large": 0.5, "ecs.g8y.large": 0.4} {
+		ecsAPI.AddOfferings(
+			fake.CapacityPool{InstanceType: instanceType, ZoneID: testZoneID, CapacityType: karpv1.CapacityTypeOnDemand},
+			fake.CapacityPool{InstanceType: instanceType, ZoneID: testZoneID, CapacityType: karpv1.CapacityTypeSpot},
+		)
+		pricingProvider.SetOnDemandPrice(instanceType, price)
+		pricingProvider.SetSpotPrice(instanceType, testZoneID, price/10)
+	}
+
+	unavailableOfferings := kcache.NewUnavailableOfferings()
+	clusterProvider := cluster.NewCustom()
+	instanceTypeProvider := instancetype.NewDefaultProvider(fake.DefaultRegion, ecsAPI,
+		cache.New(cache.NoExpiration, cache.NoExpiration), unavailableOfferings, pricingProvider,
+		spotrisk.NewDefaultProvider(fake.DefaultRegion, ecsAPI, clock.RealClock{}), clusterProvider)
+	require.NoError(t, instanceTypeProvider.UpdateInstanceTypes(ctx))
+	require.NoError(t, instanceTypeProvider.UpdateInstanceTypeOfferings(ctx))
+	instanceProvider := instance.NewDefaultProvider(ctx, fake.DefaultRegion, ecsAPI, unavailableOfferings,
+		imagefamily.NewDefaultResolver(fake.DefaultRegion, ecsAPI, cache.New(cache.NoExpiration, cache.NoExpiration)),
+		vswitch.NewDefaultProvider(fake.DefaultRegion, vpcAPI, cache.New(cache.NoExpiration, cache.NoExpiration),
+			cache.New(cache.NoExpiration, cache.NoExpiration)),
+		clusterProvider)
+
+	nodeClass := newTestNodeClass()
+	nodePool := &karpv1.NodePool{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: karpv1.NodePoolSpec{Template: karpv1.NodeClaimTemplate{Spec: karpv1.NodeClaimTemplateSpec{
+			NodeClassRef: &karpv1.NodeClassReference{Group: apis.Group, Kind: "ECSNodeClass", Name: nodeClass.Name},
+		}}},
+	}
+	kubeClient := clientfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(nodeClass, nodePool).Build()
+	return &testEnv{
+		ctx:           ctx,
+		kubeClient:    kubeClient,
+		ecsAPI:        ecsAPI,
+		vpcAPI:        vpcAPI,
+		nodeClass:     nodeClass,
+		nodePool:      nodePool,
+		cloudProvider: New(kubeClient, &testRecorder{}, instanceTypeProvider, instanceProvider),
+	}
+}
+
+func newTestInstanceTypeInfo(name, arch string) *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType {
+	return &ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType{
+		InstanceTypeId:              tea.String(name),
+		InstanceTypeFamily:          tea.String(name[:len(name)-len(".large")]),
+		CpuArchitecture:             tea.String(arch),
+		CpuCoreCount:                tea.Int32(2),
+		MemorySize:                  tea.Float32(8),
+		EniQuantity:                 tea.Int32(3),
+		EniPrivateIpAddressQuantity: tea.Int32(6),
+		GPUSpec:                     tea.String(""),
+		GPUAmount:                   tea.Int32(0),
+	}
+}
+
+func newTestImage(id, arch string) v1alpha1.Image {
+	return v1alpha1.Image{
+		ID: id,
+		Requirements: []corev1.NodeSelectorRequirement{
+			{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{arch}},
+		},
+	}
+}
+
+// newTestNodeClass returns a ready ECSNodeClass in the zone of the fake vSwitch with an image for both architectures
+func newTestNodeClass() *v1alpha1.ECSNodeClass {
+	nodeClass := &v1alpha1.ECSNodeClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Status: v1alpha1.ECSNodeClassStatus{
+			VSwitches:      []v1alpha1.VSwitch{{ID: "vsw-test", ZoneID: testZoneID, AvailableIPAddressCount: 1000}},
+			SecurityGroups: []v1alpha1.SecurityGroup{{ID: "sg-test"}},
+			Images: []v1alpha1.Image{
+				newTestImage("amd64-image", karpv1.ArchitectureAmd64),
+				newTestImage("arm64-image", karpv1.ArchitectureArm64),
+			},
+		},
+	}
+	for _, conditionType := range []string{v1alpha1.ConditionTypeVSwitchesReady, v1alpha1.ConditionTypeSecurityGroupsReady,
+		v1alpha1.ConditionTypeImagesReady, v1alpha1.ConditionTypeRAMRoleReady, v1alpha1.ConditionTypeDeploymentSetReady,
+		v1alpha1.ConditionTypeDedicatedHostsReady} {
+		nodeClass.StatusConditions().SetTrue(conditionType)
+	}
+	return nodeClass
+}
+
+func newTestNodeClaim(instanceTypes ...string) *karpv1.NodeClaim {
+	return &karpv1.NodeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "default-abcde",
+			Labels: map[string]string{karpv1.NodePoolLabelKey: "default"},
+		},
+		Spec: karpv1.NodeClaimSpec{
+			NodeClassRef: &karpv1.NodeClassReference{Group: apis.Group, Kind: "ECSNodeClass", Name: "default"},
+			Requirements: []karpv1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key: karpv1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.CapacityTypeOnDemand},
+				}},
+				{NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key: corev1.LabelInstanceTypeStable, Operator: corev1.NodeSelectorOpIn, Values: instanceTypes,
+				}},
+			},
+		},
+	}
+}
+
+// launch creates a NodeClaim through the cloud provider, the returned NodeClaim carries the labels and the provider ID
+// which the lifecycle controller of Karpenter copies onto the NodeClaim
+func (env *testEnv) launch(t *testing.T, instanceTypes ...string) *karpv1.NodeClaim {
+	t.Helper()
+
+	nodeClaim := newTestNodeClaim(instanceTypes...)
+	created, err := env.cloudProvider.Create(env.ctx, nodeClaim)
+	require.NoError(t, err)
+	nodeClaim.Labels = lo.Assign(nodeClaim.Labels, created.Labels)
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, created.Annotations)
+	nodeClaim.Status.ProviderID = created.Status.ProviderID
+	nodeClaim.Status.ImageID = created.Status.ImageID
+	return nodeClaim
+}
+
+// updateNodeClass writes the changed ECSNodeClass of the env
+func (env *testEnv) updateNodeClass(t *testing.T) {
+	t.Helper()
+	require.NoError(t, env.kubeClient.Update(env.ctx, env.nodeClass))
+}
+
+func TestCloudProvider_Create(t *testing.T) {
+	env := newTestEnv(t)
+	env.nodeClass.Annotations = map[string]string{
+		v1alpha1.AnnotationECSNodeClassHash:        env.nodeClass.Hash(),
+		v1alpha1.AnnotationECSNodeClassHashVersion: v1alpha1.ECSNodeClassHashVersion,
+	}
+	env.updateNodeClass(t)
+
+	created, err := env.cloudProvider.Create(env.ctx, newTestNodeClaim("ecs.g8y.large"))
+	require.NoError(t, err)
+	assert.Equal(t, "ecs.g8y.large", created.Labels[corev1.LabelInstanceTypeStable])
+	assert.Equal(t, karpv1.ArchitectureArm64, created.Labels[corev1.LabelArchStable])
+	assert.Equal(t, testZoneID, created.Labels[corev1.LabelTopologyZone])
+	assert.Equal(t, karpv1.CapacityTypeOnDemand, created.Labels[karpv1.CapacityTypeLabelKey])
+	assert.True(t, strings.HasPrefix(created.Status.ProviderID, fake.DefaultRegion+"."), created.Status.ProviderID)
+	assert.Equal(t, "arm64-image", created.Status.ImageID)
+	assert.Equal(t, env.nodeClass.Hash(), created.Annotations[v1alpha1.AnnotationECSNodeClassHash])
+	assert.Equal(t, v1alpha1.ECSNodeClassHashVersion, created.Annotations[v1alpha1.AnnotationECSNodeClassHashVersion])
+	assert.Equal(t, resource.MustParse("2"), created.Status.Capacity[corev1.ResourceCPU])
+	assert.NotEmpty(t, created.Status.Allocatable)
+	assert.Len(t, env.ecsAPI.Instances(), 1)
+}
+
+func TestCloudProvider_CreateNodeClassNotReady(t *testing.T) {
+	env := newTestEnv(t)
+	env.nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeVSwitchesReady, "VSwitchesNotFound", "no vSwitches found")
+	env.updateNodeClass(t)
+
+	_, err := env.cloudProvider.Create(env.ctx, newTestNodeClaim("ecs.g7.large"))
+	assert.True(t, cloudprovider.IsNodeClassNotReadyError(err), err)
+	assert.Empty(t, env.ecsAPI.Instances())
+}
+
+func TestCloudProvider_CreateNodeClassNotFound(t *testing.T) {
+	env := newTestEnv(t)
+	require.NoError(t, env.kubeClient.Delete(env.ctx, env.nodeClass))
+
+	_, err := env.cloudProvider.Create(env.ctx, newTestNodeClaim("ecs.g7.large"))
+	assert.True(t, cloudprovider.IsInsufficientCapacityError(err), err)
+}
+
+func TestCloudProvider_CreateNoCompatibleInstanceTypes(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.cloudProvider.Create(env.ctx, newTestNodeClaim("ecs.unknown.large"))
+	assert.True(t, cloudprovider.IsInsufficientCapacityError(err), err)
+	assert.Empty(t, env.ecsAPI.Instances())
+}
+
+func TestCloudProvider_ListAndGet(t *testing.T) {
+	env := newTestEnv(t)
+	amd64NodeClaim := env.launch(t, "ecs.g7.large")
+	arm64NodeClaim := env.launch(t, "ecs.g8y.large")
+
+	nodeClaims, err := env.cloudProvider.List(env.ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t,
+		[]string{amd64NodeClaim.Status.ProviderID, arm64NodeClaim.Status.ProviderID},
+		lo.Map(nodeClaims, func(nc *karpv1.NodeClaim, _ int) string { return nc.Status.ProviderID }))
+
+	for _, want := range []*karpv1.NodeClaim{amd64NodeClaim, arm64NodeClaim} {
+		got, err := env.cloudProvider.Get(env.ctx, want.Status.ProviderID)
+		require.NoError(t, err)
+		assert.Equal(t, want.Status.ProviderID, got.Status.ProviderID)
+		assert.Equal(t, want.Status.ImageID, got.Status.ImageID)
+		assert.Equal(t, want.Labels[corev1.LabelInstanceTypeStable], got.Labels[corev1.LabelInstanceTypeStable])
+		assert.Equal(t, "default", got.Labels[karpv1.NodePoolLabelKey])
+		assert.NotEmpty(t, got.Status.Capacity)
+	}
+
+	_, err = env.cloudProvider.Get(env.ctx, fake.DefaultRegion+".i-unknown")
+	assert.True(t, cloudprovider.IsNodeClaimNotFoundError(err), err)
+}
+
+func TestCloudProvider_Delete(t *testing.T) {
+	env := newTestEnv(t)
+	nodeClaim := env.launch(t, "ecs.g7.large")
+
+	require.NoError(t, env.cloudProvider.Delete(env.ctx, nodeClaim))
+	assert.Empty(t, env.ecsAPI.Instances())
+	_, err := env.cloudProvider.Get(env.ctx, nodeClaim.Status.ProviderID)
+	assert.True(t, cloudprovider.IsNodeClaimNotFoundError(err), err)
+	err = env.cloudProvider.Delete(env.ctx, nodeClaim)
+	assert.True(t, cloudprovider.IsNodeClaimNotFoundError(err), err)
+}
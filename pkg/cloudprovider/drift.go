@@ -19,9 +19,11 @@ import (
 	"fmt"
 
 	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instance"
@@ -32,9 +34,10 @@ const (
 	VSwitchDrift       cloudprovider.DriftReason = "VSwitchDrift"
 	SecurityGroupDrift cloudprovider.DriftReason = "SecurityGroupDrift"
 	NodeClassDrift     cloudprovider.DriftReason = "NodeClassDrift"
+	ImageDrift         cloudprovider.DriftReason = "ImageDrift"
 )
 
-func (c *CloudProvider) isNodeClassDrifted(ctx context.Context, nodeClaim *karpv1.NodeClaim, _ *karpv1.NodePool, nodeClass *v1alpha1.ECSNodeClass) (cloudprovider.DriftReason, error) {
+func (c *CloudProvider) isNodeClassDrifted(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePool *karpv1.NodePool, nodeClass *v1alpha1.ECSNodeClass) (cloudprovider.DriftReason, error) {
 	// First check if the node class is statically drifted to save on API calls.
 	if drifted := c.areStaticFieldsDrifted(nodeClaim, nodeClass); drifted != "" {
 		return drifted, nil
@@ -43,6 +46,10 @@ func (c *CloudProvider) isNodeClassDrifted(ctx context.Context, nodeClaim *karpv
 	if err != nil {
 		return "", err
 	}
+	imageDrifted, err := c.isImageDrifted(ctx, nodeClaim, nodePool, instance, nodeClass)
+	if err != nil {
+		return "", fmt.Errorf("calculating image drift, %w", err)
+	}
 	securityGroupDrifted, err := c.areSecurityGroupsDrifted(instance, nodeClass)
 	if err != nil {
 		return "", fmt.Errorf("calculating securitygroup drift, %w", err)
@@ -51,7 +58,7 @@ func (c *CloudProvider) isNodeClassDrifted(ctx context.Context, nodeClaim *karpv
 	if err != nil {
 		return "", fmt.Errorf("calculating vSwitch drift, %w", err)
 	}
-	drifted := lo.FindOrElse([]cloudprovider.DriftReason{securityGroupDrifted, vSwitchDrifted}, "", func(i cloudprovider.DriftReason) bool {
+	drifted := lo.FindOrElse([]cloudprovider.DriftReason{imageDrifted, securityGroupDrifted, vSwitchDrifted}, "", func(i cloudprovider.DriftReason) bool {
 		return string(i) != ""
 	})
 	return drifted, nil
@@ -73,6 +80,35 @@ func (c *CloudProvider) areStaticFieldsDrifted(nodeClaim *karpv1.NodeClaim, node
 	return lo.Ternary(nodeClassHash != nodeClaimHash, NodeClassDrift, "")
 }
 
+// Checks if the image is drifted, by checking that the ecs instance image is still one of the images
+// in the nodeclass status that are compatible with the instance type of the node
+func (c *CloudProvider) isImageDrifted(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePool *karpv1.NodePool,
+	instance *instance.Instance, nodeClass *v1alpha1.ECSNodeClass) (cloudprovider.DriftReason, error) {
+	instanceTypes, err := c.GetInstanceTypes(ctx, nodePool)
+	if err != nil {
+		return "", fmt.Errorf("getting instanceTypes, %w", err)
+	}
+	nodeInstanceType, found := lo.Find(instanceTypes, func(instType *cloudprovider.InstanceType) bool {
+		return instType.Name == nodeClaim.Labels[corev1.LabelInstanceTypeStable]
+	})
+	if !found {
+		return "", fmt.Errorf(`finding node instance type "%s"`, nodeClaim.Labels[corev1.LabelInstanceTypeStable])
+	}
+	if len(nodeClass.Status.Images) == 0 {
+		return "", fmt.Errorf("no images exist given constraints")
+	}
+
+	if !lo.ContainsBy(nodeClass.Status.Images, func(image v1alpha1.Image) bool {
+		return image.ID == instance.ImageID && nodeInstanceType.Requirements.Compatible(
+			scheduling.NewNodeSelectorRequirements(image.Requirements...),
+			scheduling.AllowUndefinedWellKnownLabels,
+		) == nil
+	}) {
+		return ImageDrift, nil
+	}
+	return "", nil
+}
+
 // Checks if the security groups are drifted, by comparing the security groups returned from the SecurityGroupProvider
 // to the ecs instance security groups
 func (c *CloudProvider) areSecurityGroupsDrifted(ecsInstance *instance.Instance, nodeClass *v1alpha1.ECSNodeClass) (cloudprovider.DriftReason, error) {
@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+)
+
+func TestCloudProvider_IsDriftedImage(t *testing.T) {
+	tests := []struct {
+		name   string
+		images []v1alpha1.Image
+		want   cloudprovider.DriftReason
+	}{
+		{
+			name: "image is still compatible",
+			images: []v1alpha1.Image{
+				newTestImage("amd64-image", karpv1.ArchitectureAmd64),
+				newTestImage("arm64-image", karpv1.ArchitectureArm64),
+			},
+		},
+		{
+			name: "image is replaced",
+			images: []v1alpha1.Image{
+				newTestImage("newer-amd64-image", karpv1.ArchitectureAmd64),
+				newTestImage("arm64-image", karpv1.ArchitectureArm64),
+			},
+			want: ImageDrift,
+		},
+		{
+			name: "image is incompatible with the instance type",
+			images: []v1alpha1.Image{
+				newTestImage("amd64-image", karpv1.ArchitectureArm64),
+			},
+			want: ImageDrift,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			nodeClaim := env.launch(t, "ecs.g7.large")
+			require.Equal(t, "ecs.g7.large", nodeClaim.Labels[corev1.LabelInstanceTypeStable])
+			require.Equal(t, "amd64-image", nodeClaim.Status.ImageID)
+
+			env.nodeClass.Status.Images = tt.images
+			env.updateNodeClass(t)
+			reason, err := env.cloudProvider.IsDrifted(env.ctx, nodeClaim)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, reason)
+		})
+	}
+}
+
+func TestCloudProvider_IsDriftedNoImages(t *testing.T) {
+	env := newTestEnv(t)
+	nodeClaim := env.launch(t, "ecs.g7.large")
+
+	// The drift can't be told without the images of the ECSNodeClass
+	env.nodeClass.Status.Images = nil
+	env.updateNodeClass(t)
+	_, err := env.cloudProvider.IsDrifted(env.ctx, nodeClaim)
+	assert.Error(t, err)
+}
+
+func TestCloudProvider_IsDrifted(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(nodeClass *v1alpha1.ECSNodeClass)
+		want   cloudprovider.DriftReason
+	}{
+		{
+			name:   "nothing changed",
+			update: func(*v1alpha1.ECSNodeClass) {},
+		},
+		{
+			name: "static fields changed",
+			update: func(nodeClass *v1alpha1.ECSNodeClass) {
+				nodeClass.Spec.Tags = map[string]string{"team": "a"}
+				nodeClass.Annotations[v1alpha1.AnnotationECSNodeClassHash] = nodeClass.Hash()
+			},
+			want: NodeClassDrift,
+		},
+		{
+			name: "hash version changed",
+			update: func(nodeClass *v1alpha1.ECSNodeClass) {
+				nodeClass.Spec.Tags = map[string]string{"team": "a"}
+				nodeClass.Annotations[v1alpha1.AnnotationECSNodeClassHash] = nodeClass.Hash()
+				nodeClass.Annotations[v1alpha1.AnnotationECSNodeClassHashVersion] = "v0"
+			},
+		},
+		{
+			name: "security groups changed",
+			update: func(nodeClass *v1alpha1.ECSNodeClass) {
+				nodeClass.Status.SecurityGroups = []v1alpha1.SecurityGroup{{ID: "sg-test"}, {ID: "sg-other"}}
+			},
+			want: SecurityGroupDrift,
+		},
+		{
+			name: "vSwitch removed",
+			update: func(nodeClass *v1alpha1.ECSNodeClass) {
+				nodeClass.Status.VSwitches = []v1alpha1.VSwitch{{ID: "vsw-other", ZoneID: testZoneID}}
+			},
+			want: VSwitchDrift,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.nodeClass.Annotations = map[string]string{
+				v1alpha1.AnnotationECSNodeClassHash:        env.nodeClass.Hash(),
+				v1alpha1.AnnotationECSNodeClassHashVersion: v1alpha1.ECSNodeClassHashVersion,
+			}
+			env.updateNodeClass(t)
+			nodeClaim := env.launch(t, "ecs.g7.large")
+
+			tt.update(env.nodeClass)
+			env.updateNodeClass(t)
+			reason, err := env.cloudProvider.IsDrifted(env.ctx, nodeClaim)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, reason)
+		})
+	}
+}
+
+func TestCloudProvider_IsDriftedWithoutNodePool(t *testing.T) {
+	env := newTestEnv(t)
+	nodeClaim := env.launch(t, "ecs.g7.large")
+
+	delete(nodeClaim.Labels, karpv1.NodePoolLabelKey)
+	reason, err := env.cloudProvider.IsDrifted(env.ctx, nodeClaim)
+	require.NoError(t, err)
+	assert.Empty(t, reason)
+}
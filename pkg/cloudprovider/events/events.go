@@ -15,6 +15,8 @@ limitations under the License.
 package events
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	v1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/events"
@@ -37,3 +39,13 @@ func NodeClaimFailedToResolveNodeClass(nodeClaim *v1.NodeClaim) events.Event {
 		DedupeValues:   []string{string(nodeClaim.UID)},
 	}
 }
+
+func NodeClaimFailedToLaunch(nodeClaim *v1.NodeClaim, category, code, message string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "FailedToLaunch",
+		Message:        fmt.Sprintf("Failed launching instance (%s), errorCode=%s, errorMessage=%s", category, code, message),
+		DedupeValues:   []string{string(nodeClaim.UID), code},
+	}
+}
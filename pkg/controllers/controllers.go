@@ -37,13 +37,20 @@ import (
 	nodeclassvolumesize "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/controllers/nodeclass/volumesize"
 	providersinstancetype "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/controllers/providers/instancetype"
 	controllerspricing "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/controllers/providers/pricing"
+	controllersspotrisk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/controllers/providers/spotrisk"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/controllers/telemetry"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/capacityreservation"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/dedicatedhost"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/deploymentset"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/imagefamily"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instance"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instanceevent"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instancetype"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/pricing"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/ramrole"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/securitygroup"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/spotrisk"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/vswitch"
 )
 
@@ -52,16 +59,20 @@ func NewControllers(ctx context.Context, mgr manager.Manager, clk clock.Clock, r
 	unavailableOfferings *cache.UnavailableOfferings,
 	cloudProvider cloudprovider.CloudProvider,
 	instanceProvider instance.Provider, instanceTypeProvider instancetype.Provider,
-	pricingProvider pricing.Provider,
+	pricingProvider pricing.Provider, spotRiskProvider spotrisk.Provider,
 	vSwitchProvider vswitch.Provider, securityGroupProvider securitygroup.Provider,
-	imageProvider imagefamily.Provider) []controller.Controller {
+	imageProvider imagefamily.Provider, ramRoleProvider ramrole.Provider,
+	instanceEventProvider instanceevent.Provider, capacityReservationProvider capacityreservation.Provider,
+	deploymentSetProvider deploymentset.Provider, dedicatedHostProvider dedicatedhost.Provider) []controller.Controller {
 
 	controllers := []controller.Controller{
 		nodeclasshash.NewController(kubeClient),
 		nodeclassvolumesize.NewController(kubeClient),
-		nodeclaasstatus.NewController(kubeClient, vSwitchProvider, securityGroupProvider, imageProvider),
-		nodeclasstermination.NewController(kubeClient, recorder),
+		nodeclaasstatus.NewController(kubeClient, vSwitchProvider, securityGroupProvider, imageProvider, ramRoleProvider,
+			capacityReservationProvider, deploymentSetProvider, dedicatedHostProvider),
+		nodeclasstermination.NewController(kubeClient, recorder, deploymentSetProvider),
 		controllerspricing.NewController(pricingProvider),
+		controllersspotrisk.NewController(kubeClient, spotRiskProvider, instanceTypeProvider),
 		nodeclaimgarbagecollection.NewController(kubeClient, cloudProvider),
 		nodeclaimunregisteredtaint.NewController(kubeClient),
 		nodeclaimtagging.NewController(kubeClient, instanceProvider),
@@ -69,7 +80,7 @@ func NewControllers(ctx context.Context, mgr manager.Manager, clk clock.Clock, r
 	}
 
 	if options.FromContext(ctx).Interruption {
-		controllers = append(controllers, interruption.NewController(kubeClient, recorder, unavailableOfferings))
+		controllers = append(controllers, interruption.NewController(kubeClient, recorder, unavailableOfferings, spotRiskProvider, instanceEventProvider))
 	}
 
 	if options.FromContext(ctx).TelemetryShare {
@@ -19,84 +19,131 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/awslabs/operatorpkg/singleton"
+	gocache "github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
+	"go.uber.org/multierr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/workqueue"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
-	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/cache"
 	interruptionevents "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/controllers/interruption/events"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instanceevent"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/spotrisk"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils"
 )
 
 const (
-	ConditionTypeInstanceExpired = "InstanceExpired"
+	pollInterval = 10 * time.Second
 )
 
 // Controller is an AlibabaCloud interruption controller.
+// It polls the ECS instance events and proactively replaces the nodes of the interrupted instances.
 type Controller struct {
 	kubeClient client.Client
 	recorder   events.Recorder
 
+	instanceEventProvider     instanceevent.Provider
 	unavailableOfferingsCache *cache.UnavailableOfferings
+	spotRiskProvider          spotrisk.Provider
+	// handledEvents keeps the IDs of the events that are already handled, the ECS events are
+	// reported until they are finished, so we need to avoid handling them more than once
+	handledEvents *gocache.Cache
 }
 
 func NewController(kubeClient client.Client, recorder events.Recorder,
-	unavailableOfferingsCache *cache.UnavailableOfferings) *Controller {
+	unavailableOfferingsCache *cache.UnavailableOfferings, spotRiskProvider spotrisk.Provider,
+	instanceEventProvider instanceevent.Provider) *Controller {
 	return &Controller{
 		kubeClient: kubeClient,
 		recorder:   recorder,
 
+		instanceEventProvider:     instanceEventProvider,
 		unavailableOfferingsCache: unavailableOfferingsCache,
+		spotRiskProvider:          spotRiskProvider,
+		handledEvents:             gocache.New(cache.InterruptionEventTTL, cache.DefaultCleanupInterval),
 	}
 }
 
-func (c *Controller) Reconcile(ctx context.Context, node *corev1.Node) (reconcile.Result, error) {
-	nodeConditions := node.Status.Conditions
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "interruption")
 
-	nodeCondition, interrupted := lo.Find(nodeConditions, func(condition corev1.NodeCondition) bool {
-		return condition.Type == ConditionTypeInstanceExpired && condition.Status == corev1.ConditionTrue
+	instanceEvents, err := c.instanceEventProvider.List(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing instance events, %w", err)
+	}
+	instanceEvents = lo.Filter(instanceEvents, func(e instanceevent.Event, _ int) bool {
+		_, handled := c.handledEvents.Get(e.ID)
+		return !handled && e.Kind() != instanceevent.NoOpKind
 	})
-
-	if !interrupted {
-		return reconcile.Result{}, nil
+	if len(instanceEvents) == 0 {
+		return reconcile.Result{RequeueAfter: pollInterval}, nil
 	}
 
-	nodeClaim, err := c.getNodeClaimByNodeName(ctx, node.Name)
+	nodeClaimInstanceIDMap, err := c.makeNodeClaimInstanceIDMap(ctx)
 	if err != nil {
-		return reconcile.Result{RequeueAfter: time.Second * 5}, err
+		return reconcile.Result{}, fmt.Errorf("making nodeclaim instance id map, %w", err)
 	}
-
-	zone := nodeClaim.Labels[corev1.LabelTopologyZone]
-	instanceType := nodeClaim.Labels[corev1.LabelInstanceTypeStable]
-	if zone != "" && instanceType != "" {
-		c.unavailableOfferingsCache.MarkUnavailable(ctx, nodeCondition.Reason, instanceType, zone, karpv1.CapacityTypeSpot)
+	nodeInstanceIDMap, err := c.makeNodeInstanceIDMap(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("making node instance id map, %w", err)
 	}
 
-	if err := c.deleteNodeClaim(ctx, nodeClaim, node); err != nil {
-		return reconcile.Result{RequeueAfter: time.Second * 5}, err
+	errs := make([]error, len(instanceEvents))
+	workqueue.ParallelizeUntil(ctx, 10, len(instanceEvents), func(i int) {
+		errs[i] = c.handleInstanceEvent(ctx, nodeClaimInstanceIDMap, nodeInstanceIDMap, instanceEvents[i])
+	})
+	if err = multierr.Combine(errs...); err != nil {
+		return reconcile.Result{}, err
 	}
-
-	return reconcile.Result{}, nil
+	return reconcile.Result{RequeueAfter: pollInterval}, nil
 }
 
 func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 	return controllerruntime.NewControllerManagedBy(m).
 		Named("interruption").
-		For(&corev1.Node{}).
-		WithEventFilter(predicate.NewTypedPredicateFuncs(func(obj client.Object) bool {
-			if label, ok := obj.GetLabels()[karpv1.CapacityTypeLabelKey]; !ok || label != karpv1.CapacityTypeSpot {
-				return false
-			}
-
-			return true
-		})).
-		Complete(reconcile.AsReconciler(m.GetClient(), c))
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
+
+// handleInstanceEvent takes an action against every node involved in the event
+func (c *Controller) handleInstanceEvent(ctx context.Context, nodeClaimInstanceIDMap map[string]*karpv1.NodeClaim,
+	nodeInstanceIDMap map[string]*corev1.Node, instanceEvent instanceevent.Event) error {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instance-event", instanceEvent.Type,
+		"event-id", instanceEvent.ID, "instance-id", instanceEvent.InstanceID))
+
+	nodeClaim, ok := nodeClaimInstanceIDMap[instanceEvent.InstanceID]
+	if !ok {
+		// The instance is not managed by Karpenter, or the nodeclaim is already gone
+		c.handledEvents.SetDefault(instanceEvent.ID, struct{}{})
+		return nil
+	}
+	node := nodeInstanceIDMap[instanceEvent.InstanceID]
+	c.notifyForEvent(instanceEvent, nodeClaim, node)
+
+	if instanceEvent.Kind() == instanceevent.SpotInterruptionKind {
+		zone := nodeClaim.Labels[corev1.LabelTopologyZone]
+		instanceType := nodeClaim.Labels[corev1.LabelInstanceTypeStable]
+		if zone != "" && instanceType != "" {
+			c.unavailableOfferingsCache.MarkUnavailable(ctx, instanceEvent.Reason, instanceType, zone, karpv1.CapacityTypeSpot)
+			c.spotRiskProvider.RecordInterruption(ctx, instanceType, zone)
+		}
+	}
+
+	// Deleting the nodeclaim cordons and drains the node before the instance is terminated
+	if err := c.deleteNodeClaim(ctx, nodeClaim, node); err != nil {
+		return err
+	}
+	c.handledEvents.SetDefault(instanceEvent.ID, struct{}{})
+	return nil
 }
 
 // deleteNodeClaim removes the NodeClaim from the api-server
@@ -112,17 +159,56 @@ func (c *Controller) deleteNodeClaim(ctx context.Context, nodeClaim *karpv1.Node
 	return nil
 }
 
-func (c *Controller) getNodeClaimByNodeName(ctx context.Context, nodeName string) (*karpv1.NodeClaim, error) {
+// notifyForEvent publishes a distinct Kubernetes event for each kind of the instance event
+func (c *Controller) notifyForEvent(instanceEvent instanceevent.Event, nodeClaim *karpv1.NodeClaim, node *corev1.Node) {
+	switch instanceEvent.Kind() {
+	case instanceevent.SpotInterruptionKind:
+		c.recorder.Publish(interruptionevents.SpotInterrupted(node, nodeClaim)...)
+	case instanceevent.ScheduledMaintenanceKind:
+		c.recorder.Publish(interruptionevents.ScheduledMaintenance(node, nodeClaim, instanceEvent.Type)...)
+	case instanceevent.SystemFailureKind:
+		c.recorder.Publish(interruptionevents.SystemFailure(node, nodeClaim, instanceEvent.Type)...)
+	case instanceevent.InstanceStoppedKind:
+		c.recorder.Publish(interruptionevents.Stopping(node, nodeClaim, instanceEvent.Type)...)
+	case instanceevent.InstanceReleasedKind:
+		c.recorder.Publish(interruptionevents.Releasing(node, nodeClaim, instanceEvent.Type)...)
+	}
+}
+
+func (c *Controller) makeNodeClaimInstanceIDMap(ctx context.Context) (map[string]*karpv1.NodeClaim, error) {
+	m := map[string]*karpv1.NodeClaim{}
 	nodeClaimList := &karpv1.NodeClaimList{}
 	if err := c.kubeClient.List(ctx, nodeClaimList); err != nil {
 		return nil, err
 	}
-
-	for ni := range nodeClaimList.Items {
-		if nodeClaimList.Items[ni].Status.NodeName == nodeName {
-			return nodeClaimList.Items[ni].DeepCopy(), nil
+	for i := range nodeClaimList.Items {
+		if nodeClaimList.Items[i].Status.ProviderID == "" {
+			continue
 		}
+		id, err := utils.ParseInstanceID(nodeClaimList.Items[i].Status.ProviderID)
+		if err != nil || id == "" {
+			continue
+		}
+		m[id] = &nodeClaimList.Items[i]
 	}
+	return m, nil
+}
 
-	return nil, fmt.Errorf("no nodeclaim found for node %s", nodeName)
+func (c *Controller) makeNodeInstanceIDMap(ctx context.Context) (map[string]*corev1.Node, error) {
+	m := map[string]*corev1.Node{}
+	nodeList := &corev1.NodeList{}
+	if err := c.kubeClient.List(ctx, nodeList); err != nil {
+		return nil, err
+	}
+	for i := range nodeList.Items {
+		if nodeList.Items[i].Spec.ProviderID == "" {
+			continue
+		}
+		id, err := utils.ParseInstanceID(nodeList.Items[i].Spec.ProviderID)
+		if err != nil || id == "" {
+			continue
+		}
+		m[id] = &nodeList.Items[i]
+	}
+	return m, nil
 }
@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interruption
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/cache"
+	alifake "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instanceevent"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/spotrisk"
+)
+
+type testRecorder struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (r *testRecorder) Publish(evts ...events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, evts...)
+}
+
+func (r *testRecorder) reasons() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var reasons []string
+	for _, e := range r.events {
+		reasons = append(reasons, e.Reason)
+	}
+	return reasons
+}
+
+func newTestNodeClaimAndNode(name, instanceID string) (*karpv1.NodeClaim, *corev1.Node) {
+	labels := map[string]string{
+		corev1.LabelTopologyZone:       "cn-beijing-i",
+		corev1.LabelInstanceTypeStable: "ecs.g7.large",
+	}
+	providerID := "cn-beijing." + instanceID
+	nodeClaim := &karpv1.NodeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status:     karpv1.NodeClaimStatus{ProviderID: providerID, NodeName: name},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec:       corev1.NodeSpec{ProviderID: providerID},
+	}
+	return nodeClaim, node
+}
+
+func Test_Reconcile(t *testing.T) {
+	tests := []struct {
+		name              string
+		event             instanceevent.Event
+		wantReason        string
+		wantSpotMarked    bool
+		wantNodeClaimGone bool
+	}{
+		{
+			name: "spot interruption",
+			event: instanceevent.Event{ID: "e-1", InstanceID: "i-1",
+				Type: instanceevent.SpotInterruptionEventType, Reason: "SpotInterruption"},
+			wantReason:        "SpotInterrupted",
+			wantSpotMarked:    true,
+			wantNodeClaimGone: true,
+		},
+		{
+			name:              "scheduled maintenance",
+			event:             instanceevent.Event{ID: "e-1", InstanceID: "i-1", Type: "SystemMaintenance.Reboot"},
+			wantReason:        "InstanceScheduledMaintenance",
+			wantNodeClaimGone: true,
+		},
+		{
+			name:              "system failure",
+			event:             instanceevent.Event{ID: "e-1", InstanceID: "i-1", Type: "SystemFailure.Redeploy"},
+			wantReason:        "InstanceSystemFailure",
+			wantNodeClaimGone: true,
+		},
+		{
+			name:              "instance stop",
+			event:             instanceevent.Event{ID: "e-1", InstanceID: "i-1", Type: "AccountUnbalanced.Stop"},
+			wantReason:        "InstanceStopping",
+			wantNodeClaimGone: true,
+		},
+		{
+			name:              "instance release",
+			event:             instanceevent.Event{ID: "e-1", InstanceID: "i-1", Type: "AccountUnbalanced.Delete"},
+			wantReason:        "InstanceReleasing",
+			wantNodeClaimGone: true,
+		},
+		{
+			name:  "unknown instance",
+			event: instanceevent.Event{ID: "e-1", InstanceID: "i-unknown", Type: "SystemMaintenance.Reboot"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			nodeClaim, node := newTestNodeClaimAndNode("node-1", "i-1")
+			kubeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(nodeClaim, node).Build()
+			recorder := &testRecorder{}
+			unavailableOfferings := cache.NewUnavailableOfferings()
+			eventProvider := alifake.NewInstanceEventProvider()
+			eventProvider.Add(tt.event)
+			spotRiskProvider := spotrisk.NewDefaultProvider(alifake.DefaultRegion, alifake.NewECSAPI(nil), clock.RealClock{})
+
+			c := NewController(kubeClient, recorder, unavailableOfferings, spotRiskProvider, eventProvider)
+			result, err := c.Reconcile(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, pollInterval, result.RequeueAfter)
+
+			err = kubeClient.Get(ctx, client.ObjectKeyFromObject(nodeClaim), &karpv1.NodeClaim{})
+			assert.Equal(t, tt.wantNodeClaimGone, apierrors.IsNotFound(err))
+			assert.Equal(t, tt.wantSpotMarked, unavailableOfferings.IsUnavailable("ecs.g7.large", "cn-beijing-i", karpv1.CapacityTypeSpot))
+			assert.Equal(t, tt.wantSpotMarked, spotRiskProvider.Score("ecs.g7.large", "cn-beijing-i") > 0)
+			if tt.wantReason != "" {
+				assert.Contains(t, recorder.reasons(), tt.wantReason)
+				assert.Contains(t, recorder.reasons(), "TerminatingOnInterruption")
+			} else {
+				assert.Empty(t, recorder.reasons())
+			}
+
+			// The handled event must not be handled again
+			recorder.events = nil
+			_, err = c.Reconcile(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, recorder.reasons())
+		})
+	}
+}
+
+func Test_ReconcileListError(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	eventProvider := alifake.NewInstanceEventProvider()
+	eventProvider.ListError = errors.New("throttled")
+
+	spotRiskProvider := spotrisk.NewDefaultProvider(alifake.DefaultRegion, alifake.NewECSAPI(nil), clock.RealClock{})
+	c := NewController(kubeClient, &testRecorder{}, cache.NewUnavailableOfferings(), spotRiskProvider, eventProvider)
+	_, err := c.Reconcile(context.Background())
+	assert.Error(t, err)
+}
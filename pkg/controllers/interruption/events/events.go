@@ -15,6 +15,8 @@ limitations under the License.
 package events
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/events"
@@ -39,3 +41,43 @@ func TerminatingOnInterruption(node *corev1.Node, nodeClaim *karpv1.NodeClaim) (
 	}
 	return evts
 }
+
+func SpotInterrupted(node *corev1.Node, nodeClaim *karpv1.NodeClaim) []events.Event {
+	return instanceEvents(node, nodeClaim, "SpotInterrupted", "Spot interruption warning was triggered")
+}
+
+func ScheduledMaintenance(node *corev1.Node, nodeClaim *karpv1.NodeClaim, eventType string) []events.Event {
+	return instanceEvents(node, nodeClaim, "InstanceScheduledMaintenance", fmt.Sprintf("Scheduled maintenance %s was triggered", eventType))
+}
+
+func SystemFailure(node *corev1.Node, nodeClaim *karpv1.NodeClaim, eventType string) []events.Event {
+	return instanceEvents(node, nodeClaim, "InstanceSystemFailure", fmt.Sprintf("System failure %s was triggered", eventType))
+}
+
+func Stopping(node *corev1.Node, nodeClaim *karpv1.NodeClaim, eventType string) []events.Event {
+	return instanceEvents(node, nodeClaim, "InstanceStopping", fmt.Sprintf("Instance is stopping by %s", eventType))
+}
+
+func Releasing(node *corev1.Node, nodeClaim *karpv1.NodeClaim, eventType string) []events.Event {
+	return instanceEvents(node, nodeClaim, "InstanceReleasing", fmt.Sprintf("Instance is releasing by %s", eventType))
+}
+
+func instanceEvents(node *corev1.Node, nodeClaim *karpv1.NodeClaim, reason, message string) (evts []events.Event) {
+	evts = append(evts, events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           corev1.EventTypeWarning,
+		Reason:         reason,
+		Message:        message,
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	})
+	if node != nil {
+		evts = append(evts, events.Event{
+			InvolvedObject: node,
+			Type:           corev1.EventTypeWarning,
+			Reason:         reason,
+			Message:        message,
+			DedupeValues:   []string{string(node.UID)},
+		})
+	}
+	return evts
+}
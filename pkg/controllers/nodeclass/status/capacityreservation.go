@@ -0,0 +1,46 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/capacityreservation"
+)
+
+type CapacityReservation struct {
+	capacityReservationProvider capacityreservation.Provider
+}
+
+func (c *CapacityReservation) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (reconcile.Result, error) {
+	// The capacity reservations are optional, they don't affect the readiness of the ECSNodeClass
+	if len(nodeClass.Spec.CapacityReservationSelectorTerms) == 0 {
+		nodeClass.Status.CapacityReservations = nil
+		return reconcile.Result{}, nil
+	}
+
+	capacityReservations, err := c.capacityReservationProvider.List(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting capacity reservations, %w", err)
+	}
+	nodeClass.Status.CapacityReservations = capacityReservations
+	// The launches use up the private pools, the counts are resolved every minute and replace the launches deducted
+	// by the instance provider in the meantime
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
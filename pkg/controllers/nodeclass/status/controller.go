@@ -31,7 +31,11 @@ import (
 	"sigs.k8s.io/karpenter/pkg/utils/result"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/capacityreservation"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/dedicatedhost"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/deploymentset"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/imagefamily"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/ramrole"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/securitygroup"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/vswitch"
 )
@@ -43,19 +47,29 @@ type nodeClassStatusReconciler interface {
 type Controller struct {
 	kubeClient client.Client
 
-	vSwitch       *VSwitch
-	securityGroup *SecurityGroup
-	image         *Image
+	vSwitch             *VSwitch
+	securityGroup       *SecurityGroup
+	image               *Image
+	ramRole             *RAMRole
+	capacityReservation *CapacityReservation
+	deploymentSet       *DeploymentSet
+	dedicatedHost       *DedicatedHost
 }
 
 func NewController(kubeClient client.Client, vSwitchProvider vswitch.Provider,
-	securityGroupProvider securitygroup.Provider, imageProvider imagefamily.Provider) *Controller {
+	securityGroupProvider securitygroup.Provider, imageProvider imagefamily.Provider, ramRoleProvider ramrole.Provider,
+	capacityReservationProvider capacityreservation.Provider, deploymentSetProvider deploymentset.Provider,
+	dedicatedHostProvider dedicatedhost.Provider) *Controller {
 	return &Controller{
 		kubeClient: kubeClient,
 
-		vSwitch:       &VSwitch{vSwitchProvider: vSwitchProvider},
-		securityGroup: &SecurityGroup{securityGroupProvider: securityGroupProvider},
-		image:         &Image{imageProvider: imageProvider},
+		vSwitch:             &VSwitch{vSwitchProvider: vSwitchProvider},
+		securityGroup:       &SecurityGroup{securityGroupProvider: securityGroupProvider},
+		image:               &Image{imageProvider: imageProvider},
+		ramRole:             &RAMRole{ramRoleProvider: ramRoleProvider},
+		capacityReservation: &CapacityReservation{capacityReservationProvider: capacityReservationProvider},
+		deploymentSet:       &DeploymentSet{deploymentSetProvider: deploymentSetProvider},
+		dedicatedHost:       &DedicatedHost{dedicatedHostProvider: dedicatedHostProvider},
 	}
 }
 
@@ -84,6 +98,10 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeC
 		c.vSwitch,
 		c.securityGroup,
 		c.image,
+		c.ramRole,
+		c.capacityReservation,
+		c.deploymentSet,
+		c.dedicatedHost,
 	} {
 		res, err := reconciler.Reconcile(ctx, nodeClass)
 		errs = multierr.Append(errs, err)
@@ -0,0 +1,54 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/dedicatedhost"
+)
+
+type DedicatedHost struct {
+	dedicatedHostProvider dedicatedhost.Provider
+}
+
+func (d *DedicatedHost) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (reconcile.Result, error) {
+	if !nodeClass.Spec.Placement.OnDedicatedHosts() {
+		nodeClass.Status.DedicatedHosts = nil
+		nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeDedicatedHostsReady)
+		return reconcile.Result{}, nil
+	}
+
+	dedicatedHosts, err := d.dedicatedHostProvider.List(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting dedicated hosts, %w", err)
+	}
+	if len(dedicatedHosts) == 0 {
+		nodeClass.Status.DedicatedHosts = nil
+		nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeDedicatedHostsReady, "DedicatedHostsNotFound",
+			"Placement did not match any available DedicatedHost")
+		// The dedicated hosts may be allocated after the nodeclass, so we need to check them again later
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	nodeClass.Status.DedicatedHosts = dedicatedHosts
+	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeDedicatedHostsReady)
+	// The available vCPUs and memory change with every launch, so they are refreshed more often than the other resources
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/deploymentset"
+)
+
+type DeploymentSet struct {
+	deploymentSetProvider deploymentset.Provider
+}
+
+func (d *DeploymentSet) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (reconcile.Result, error) {
+	if nodeClass.Spec.DeploymentSetSelector == nil {
+		nodeClass.Status.DeploymentSet = nil
+		nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeDeploymentSetReady)
+		return reconcile.Result{}, nil
+	}
+
+	deploymentSet, err := d.deploymentSetProvider.Get(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting deployment set, %w", err)
+	}
+	if deploymentSet == nil {
+		nodeClass.Status.DeploymentSet = nil
+		nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeDeploymentSetReady, "DeploymentSetNotFound",
+			"DeploymentSetSelector did not match any DeploymentSet")
+		// The deployment set may be created after the nodeclass, so we need to check it again later
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	nodeClass.Status.DeploymentSet = deploymentSet
+	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeDeploymentSetReady)
+	// Each instance takes a slot of the deployment set in its zone, the full zones are left out of the offerings
+	// until the capacities are resolved again
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/ramrole"
+)
+
+type RAMRole struct {
+	ramRoleProvider ramrole.Provider
+}
+
+func (r *RAMRole) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (reconcile.Result, error) {
+	if nodeClass.Spec.RAMRole == "" {
+		nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeRAMRoleReady)
+		return reconcile.Result{}, nil
+	}
+
+	role, err := r.ramRoleProvider.Get(ctx, nodeClass.Spec.RAMRole)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting ram role, %w", err)
+	}
+	if role == nil {
+		nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeRAMRoleReady, "RAMRoleNotFound",
+			fmt.Sprintf("RAM role %q does not exist", nodeClass.Spec.RAMRole))
+		// The role may be created after the nodeclass, so we need to check it again later
+		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
+	}
+
+	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeRAMRoleReady)
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
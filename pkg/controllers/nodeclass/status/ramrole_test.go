@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/ramrole"
+)
+
+func TestRAMRole_Reconcile(t *testing.T) {
+	ramAPI := fake.NewRAMAPI()
+	reconciler := &RAMRole{ramRoleProvider: ramrole.NewDefaultProvider(ramAPI, cache.New(cache.NoExpiration, cache.NoExpiration))}
+
+	// Without a RAM role there is nothing to check
+	nodeClass := &v1alpha1.ECSNodeClass{}
+	_, err := reconciler.Reconcile(context.Background(), nodeClass)
+	require.NoError(t, err)
+	assert.True(t, nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeRAMRoleReady).IsTrue())
+
+	// The missing role is checked again later
+	nodeClass.Spec.RAMRole = "KarpenterNodeRole"
+	result, err := reconciler.Reconcile(context.Background(), nodeClass)
+	require.NoError(t, err)
+	assert.NotZero(t, result.RequeueAfter)
+	condition := nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeRAMRoleReady)
+	assert.True(t, condition.IsFalse())
+	assert.Equal(t, "RAMRoleNotFound", condition.Reason)
+
+	ramAPI.AddRoles("KarpenterNodeRole")
+	_, err = reconciler.Reconcile(context.Background(), nodeClass)
+	require.NoError(t, err)
+	assert.True(t, nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeRAMRoleReady).IsTrue())
+
+	// The condition is kept when the role can't be checked
+	ramAPI.GetRoleError.Set(fake.NewThrottlingError())
+	nodeClass.Spec.RAMRole = "OtherRole"
+	_, err = reconciler.Reconcile(context.Background(), nodeClass)
+	assert.Error(t, err)
+	assert.True(t, nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeRAMRoleReady).IsTrue())
+}
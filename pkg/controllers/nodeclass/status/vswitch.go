@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	vpc "github.com/alibabacloud-go/vpc-20160428/v6/client"
@@ -30,6 +31,9 @@ import (
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/vswitch"
 )
 
+// availableIPAddressCountThreshold is the change of a vSwitch available IP address count that is written to the status
+const availableIPAddressCountThreshold = 16
+
 type VSwitch struct {
 	vSwitchProvider vswitch.Provider
 }
@@ -41,23 +45,71 @@ func (v *VSwitch) Reconcile(ctx context.Context, nodeClass *v1alpha1.ECSNodeClas
 	}
 	if len(vSwitches) == 0 {
 		nodeClass.Status.VSwitches = nil
-		nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeVSwitchesReady, "vSwitchesNotFound", "VSwitchSelector did not match any VSwitches")
+		_ = nodeClass.StatusConditions().Clear(v1alpha1.ConditionTypeVSwitchIPsExhausted)
+		message := "VSwitchSelector did not match any VSwitches"
+		if nodeClass.Spec.IPv6AddressCount != nil {
+			message = "VSwitchSelector did not match any VSwitches with IPv6 enabled"
+		}
+		if lo.FromPtr(nodeClass.Spec.AssociatePublicIPAddress) {
+			message += ", the VSwitches shared by other accounts can't assign public IP addresses"
+		}
+		nodeClass.StatusConditions().SetFalse(v1alpha1.ConditionTypeVSwitchesReady, "vSwitchesNotFound", message)
 		// If users have omitted the necessary tags and later add them, we need to reprocess the information.
 		// Returning 'ok' in this case means that the ecsnodeclass will remain in an unready state until the component is restarted.
 		return reconcile.Result{RequeueAfter: time.Second * 15}, nil
 	}
-	sort.Slice(vSwitches, func(i, j int) bool {
-		if int(*vSwitches[i].AvailableIpAddressCount) != int(*vSwitches[j].AvailableIpAddressCount) {
-			return int(*vSwitches[i].AvailableIpAddressCount) > int(*vSwitches[j].AvailableIpAddressCount)
-		}
-		return *vSwitches[i].VSwitchId < *vSwitches[j].VSwitchId
+	previousVSwitches := lo.SliceToMap(nodeClass.Status.VSwitches, func(vSwitch v1alpha1.VSwitch) (string, v1alpha1.VSwitch) {
+		return vSwitch.ID, vSwitch
 	})
-	nodeClass.Status.VSwitches = lo.Map(vSwitches, func(ecsvSwitch *vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch, _ int) v1alpha1.VSwitch {
+	statusVSwitches := lo.Map(vSwitches, func(ecsvSwitch *vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch, _ int) v1alpha1.VSwitch {
+		availableIPAddressCount := lo.FromPtr(ecsvSwitch.AvailableIpAddressCount)
+		if previous, ok := previousVSwitches[*ecsvSwitch.VSwitchId]; ok && !availableIPAddressCountChanged(previous.AvailableIPAddressCount, availableIPAddressCount) {
+			availableIPAddressCount = previous.AvailableIPAddressCount
+		}
 		return v1alpha1.VSwitch{
-			ID:     *ecsvSwitch.VSwitchId,
-			ZoneID: *ecsvSwitch.ZoneId,
+			ID:                      *ecsvSwitch.VSwitchId,
+			ZoneID:                  *ecsvSwitch.ZoneId,
+			AvailableIPAddressCount: availableIPAddressCount,
+			IPv6CIDRBlock:           lo.FromPtr(ecsvSwitch.Ipv6CidrBlock),
+		}
+	})
+	sort.Slice(statusVSwitches, func(i, j int) bool {
+		if statusVSwitches[i].AvailableIPAddressCount != statusVSwitches[j].AvailableIPAddressCount {
+			return statusVSwitches[i].AvailableIPAddressCount > statusVSwitches[j].AvailableIPAddressCount
 		}
+		return statusVSwitches[i].ID < statusVSwitches[j].ID
 	})
+	nodeClass.Status.VSwitches = statusVSwitches
 	nodeClass.StatusConditions().SetTrue(v1alpha1.ConditionTypeVSwitchesReady)
+
+	if zones := exhaustedZones(nodeClass.Status.VSwitches); len(zones) > 0 {
+		nodeClass.StatusConditions().SetTrueWithReason(v1alpha1.ConditionTypeVSwitchIPsExhausted, "ZoneIPsExhausted",
+			fmt.Sprintf("VSwitches in zones %s have no available IP addresses", strings.Join(zones, ", ")))
+	} else {
+		_ = nodeClass.StatusConditions().Clear(v1alpha1.ConditionTypeVSwitchIPsExhausted)
+	}
 	return reconcile.Result{RequeueAfter: time.Minute}, nil
 }
+
+// availableIPAddressCountChanged tells whether the available IP address count moved enough to be written to the
+// status, the count changes with every launch and would otherwise update the status on each reconcile. Running
+// out of IPs is always written, so the exhausted zones are reported right away.
+func availableIPAddressCountChanged(previous, current int64) bool {
+	if (previous <= 0) != (current <= 0) {
+		return true
+	}
+	return max(previous-current, current-previous) >= availableIPAddressCountThreshold
+}
+
+// exhaustedZones returns the zones where none of the vSwitches has an available IP address
+func exhaustedZones(vSwitches []v1alpha1.VSwitch) []string {
+	availableIPAddressCount := map[string]int64{}
+	for _, vSwitch := range vSwitches {
+		availableIPAddressCount[vSwitch.ZoneID] += vSwitch.AvailableIPAddressCount
+	}
+	zones := lo.Keys(lo.PickBy(availableIPAddressCount, func(_ string, count int64) bool {
+		return count <= 0
+	}))
+	sort.Strings(zones)
+	return zones
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/vswitch"
+)
+
+func TestVSwitch_ReconcileAvailableIPAddressCount(t *testing.T) {
+	vpcAPI := fake.NewVPCAPI()
+	vSwitchCache := cache.New(cache.NoExpiration, cache.NoExpiration)
+	reconciler := &VSwitch{vSwitchProvider: vswitch.NewDefaultProvider(fake.DefaultRegion, vpcAPI, vSwitchCache,
+		cache.New(cache.NoExpiration, cache.NoExpiration))}
+	nodeClass := &v1alpha1.ECSNodeClass{Spec: v1alpha1.ECSNodeClassSpec{
+		VSwitchSelectorTerms: []v1alpha1.VSwitchSelectorTerm{{ID: "vsw-a"}, {ID: "vsw-b"}},
+	}}
+	reconcile := func(vSwitches ...fake.VSwitch) {
+		t.Helper()
+		vpcAPI.Reset()
+		vpcAPI.AddVSwitches(vSwitches...)
+		vSwitchCache.Flush()
+		_, err := reconciler.Reconcile(context.Background(), nodeClass)
+		require.NoError(t, err)
+	}
+
+	reconcile(fake.VSwitch{ID: "vsw-a", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 100},
+		fake.VSwitch{ID: "vsw-b", ZoneID: "cn-beijing-b", AvailableIPAddressCount: 20})
+	assert.Equal(t, []v1alpha1.VSwitch{
+		{ID: "vsw-a", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 100},
+		{ID: "vsw-b", ZoneID: "cn-beijing-b", AvailableIPAddressCount: 20},
+	}, nodeClass.Status.VSwitches)
+
+	// Small changes of the counts are not written to the status
+	reconcile(fake.VSwitch{ID: "vsw-a", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 90},
+		fake.VSwitch{ID: "vsw-b", ZoneID: "cn-beijing-b", AvailableIPAddressCount: 10})
+	assert.Equal(t, []v1alpha1.VSwitch{
+		{ID: "vsw-a", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 100},
+		{ID: "vsw-b", ZoneID: "cn-beijing-b", AvailableIPAddressCount: 20},
+	}, nodeClass.Status.VSwitches)
+	assert.Nil(t, nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeVSwitchIPsExhausted))
+
+	// Running out of IPs is written right away
+	reconcile(fake.VSwitch{ID: "vsw-a", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 60},
+		fake.VSwitch{ID: "vsw-b", ZoneID: "cn-beijing-b", AvailableIPAddressCount: 0})
+	assert.Equal(t, []v1alpha1.VSwitch{
+		{ID: "vsw-a", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 60},
+		{ID: "vsw-b", ZoneID: "cn-beijing-b", AvailableIPAddressCount: 0},
+	}, nodeClass.Status.VSwitches)
+	assert.True(t, nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeVSwitchIPsExhausted).IsTrue())
+}
+
+func TestVSwitch_ReconcilePublicIP(t *testing.T) {
+	vpcAPI := fake.NewVPCAPI()
+	vpcAPI.AddVSwitches(fake.VSwitch{ID: "vsw-a", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 100, ShareType: "Shared"})
+	reconciler := &VSwitch{vSwitchProvider: vswitch.NewDefaultProvider(fake.DefaultRegion, vpcAPI,
+		cache.New(cache.NoExpiration, cache.NoExpiration), cache.New(cache.NoExpiration, cache.NoExpiration))}
+	nodeClass := &v1alpha1.ECSNodeClass{Spec: v1alpha1.ECSNodeClassSpec{
+		VSwitchSelectorTerms:     []v1alpha1.VSwitchSelectorTerm{{ID: "vsw-a"}},
+		AssociatePublicIPAddress: lo.ToPtr(true),
+	}}
+
+	_, err := reconciler.Reconcile(context.Background(), nodeClass)
+	require.NoError(t, err)
+	assert.Empty(t, nodeClass.Status.VSwitches)
+	condition := nodeClass.StatusConditions().Get(v1alpha1.ConditionTypeVSwitchesReady)
+	assert.True(t, condition.IsFalse())
+	assert.Contains(t, condition.Message, "can't assign public IP addresses")
+}
@@ -39,17 +39,20 @@ import (
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/deploymentset"
 )
 
 type Controller struct {
-	kubeClient client.Client
-	recorder   events.Recorder
+	kubeClient            client.Client
+	recorder              events.Recorder
+	deploymentSetProvider deploymentset.Provider
 }
 
-func NewController(kubeClient client.Client, recorder events.Recorder) *Controller {
+func NewController(kubeClient client.Client, recorder events.Recorder, deploymentSetProvider deploymentset.Provider) *Controller {
 	return &Controller{
-		kubeClient: kubeClient,
-		recorder:   recorder,
+		kubeClient:            kubeClient,
+		recorder:              recorder,
+		deploymentSetProvider: deploymentSetProvider,
 	}
 }
 
@@ -75,6 +78,12 @@ func (c *Controller) finalize(ctx context.Context, nodeClass *v1alpha1.ECSNodeCl
 		c.recorder.Publish(WaitingOnNodeClaimTerminationEvent(nodeClass, lo.Map(nodeClaimList.Items, func(nc karpv1.NodeClaim, _ int) string { return nc.Name })))
 		return reconcile.Result{RequeueAfter: time.Minute * 10}, nil // periodically fire the event
 	}
+	// The deployment sets created for the ECSNodeClass can be deleted once all of its instances are gone
+	if selector := nodeClass.Spec.DeploymentSetSelector; selector != nil && selector.Strategy != "" {
+		if err := c.deploymentSetProvider.Delete(ctx, nodeClass); err != nil {
+			return reconcile.Result{}, fmt.Errorf("deleting deployment sets, %w", err)
+		}
+	}
 	controllerutil.RemoveFinalizer(nodeClass, v1alpha1.TerminationFinalizer)
 	if !equality.Semantic.DeepEqual(stored, nodeClass) {
 		if err := c.kubeClient.Patch(ctx, nodeClass, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
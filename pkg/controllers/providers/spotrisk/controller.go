@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spotrisk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instancetype"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/spotrisk"
+)
+
+type Controller struct {
+	kubeClient           client.Client
+	spotRiskProvider     spotrisk.Provider
+	instanceTypeProvider instancetype.Provider
+}
+
+func NewController(kubeClient client.Client, spotRiskProvider spotrisk.Provider, instanceTypeProvider instancetype.Provider) *Controller {
+	return &Controller{
+		kubeClient:           kubeClient,
+		spotRiskProvider:     spotRiskProvider,
+		instanceTypeProvider: instanceTypeProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.spotrisk")
+
+	instanceTypes, err := c.spotInstanceTypes(ctx)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := c.spotRiskProvider.UpdateSpotPriceHistory(ctx, instanceTypes); err != nil {
+		return reconcile.Result{}, fmt.Errorf("updating spot price history, %w", err)
+	}
+	return reconcile.Result{RequeueAfter: time.Hour}, nil
+}
+
+// spotInstanceTypes returns the instance types with a spot offering in the node classes which have a spot risk policy,
+// the spot price history of the other instance types isn't used
+func (c *Controller) spotInstanceTypes(ctx context.Context) ([]string, error) {
+	nodeClassList := &v1alpha1.ECSNodeClassList{}
+	if err := c.kubeClient.List(ctx, nodeClassList); err != nil {
+		return nil, fmt.Errorf("listing node classes, %w", err)
+	}
+	instanceTypes := sets.New[string]()
+	for i := range nodeClassList.Items {
+		nodeClass := &nodeClassList.Items[i]
+		if nodeClass.Spec.SpotRiskPolicy == nil || !nodeClass.DeletionTimestamp.IsZero() {
+			continue
+		}
+		its, err := c.instanceTypeProvider.List(ctx, nodeClass.Spec.KubeletConfiguration, nodeClass)
+		if err != nil {
+			return nil, fmt.Errorf("listing instance types of node class %s, %w", nodeClass.Name, err)
+		}
+		for _, it := range its {
+			// The offerings excluded by the policy are still scored, so they can be launched again once their risk drops
+			if lo.ContainsBy(it.Offerings, func(o cloudprovider.Offering) bool {
+				return o.Requirements.Get(karpv1.CapacityTypeLabelKey).Any() == karpv1.CapacityTypeSpot
+			}) {
+				instanceTypes.Insert(it.Name)
+			}
+		}
+	}
+	return sets.List(instanceTypes), nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.spotrisk").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
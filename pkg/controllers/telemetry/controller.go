@@ -39,7 +39,6 @@ import (
 	"sigs.k8s.io/karpenter/pkg/utils/resources"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/cloudprovider"
-	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/controllers/interruption"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils"
 )
@@ -50,6 +49,9 @@ const (
 	interruptionEventPath    = "/clusters/%s/interruptionevent"
 
 	karpenterInitializedKey = "karpenter.sh/initialized"
+
+	// ConditionTypeInstanceExpired is set on the node by node-problem-detector when the spot instance is interrupted
+	ConditionTypeInstanceExpired = "InstanceExpired"
 )
 
 type Controller struct {
@@ -73,7 +75,7 @@ func (c *Controller) Reconcile(ctx context.Context, node *corev1.Node) (reconcil
 	})
 
 	_, interrupted := lo.Find(node.Status.Conditions, func(condition corev1.NodeCondition) bool {
-		return condition.Type == interruption.ConditionTypeInstanceExpired && condition.Status == corev1.ConditionTrue
+		return condition.Type == ConditionTypeInstanceExpired && condition.Status == corev1.ConditionTrue
 	})
 
 	if interrupted {
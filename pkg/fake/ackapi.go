@@ -0,0 +1,174 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	ackclient "github.com/alibabacloud-go/cs-20151215/v5/client"
+	"github.com/alibabacloud-go/tea/tea"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+)
+
+const (
+	DefaultClusterCNI     = "terway-eniip"
+	DefaultRuntime        = "containerd"
+	DefaultRuntimeVersion = "1.6.28"
+)
+
+var _ sdk.ACKAPI = (*ACKAPI)(nil)
+
+// ACKAPI is an in-memory ACK managed cluster
+type ACKAPI struct {
+	mu             sync.RWMutex
+	clusterCNI     string
+	attachScript   string
+	runtime        string
+	runtimeVersion string
+	// kubernetes version without the "v" prefix -> images
+	images map[string][]*ackclient.DescribeKubernetesVersionMetadataResponseBodyImages
+
+	DescribeClusterAttachScriptsError      AtomicError
+	DescribeClusterDetailError             AtomicError
+	DescribeClusterNodePoolsError          AtomicError
+	DescribeKubernetesVersionMetadataError AtomicError
+}
+
+func NewACKAPI() *ACKAPI {
+	a := &ACKAPI{}
+	a.Reset()
+	return a
+}
+
+// Reset restores a terway cluster with a containerd default node pool and no images
+func (a *ACKAPI) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.clusterCNI = DefaultClusterCNI
+	a.attachScript = fmt.Sprintf("curl http://aliacs-k8s.oss.aliyuncs.com/public/pkg/run/attach/attach_node.sh | bash -s -- "+
+		"--openapi-token token --ack-region-id %s --runtime %s --runtime-version %s", DefaultRegion, DefaultRuntime, DefaultRuntimeVersion)
+	a.runtime = DefaultRuntime
+	a.runtimeVersion = DefaultRuntimeVersion
+	a.images = map[string][]*ackclient.DescribeKubernetesVersionMetadataResponseBodyImages{}
+
+	a.DescribeClusterAttachScriptsError.Reset()
+	a.DescribeClusterDetailError.Reset()
+	a.DescribeClusterNodePoolsError.Reset()
+	a.DescribeKubernetesVersionMetadataError.Reset()
+}
+
+func (a *ACKAPI) SetClusterCNI(cni string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.clusterCNI = cni
+}
+
+// AddImages adds the images supported by the kubernetes version, e.g. 1.31.1-aliyun.1
+func (a *ACKAPI) AddImages(kubernetesVersion string, images ...*ackclient.DescribeKubernetesVersionMetadataResponseBodyImages) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	version := strings.TrimPrefix(kubernetesVersion, "v")
+	a.images[version] = append(a.images[version], images...)
+}
+
+func (a *ACKAPI) DescribeClusterAttachScripts(_ *string, _ *ackclient.DescribeClusterAttachScriptsRequest) (*ackclient.DescribeClusterAttachScriptsResponse, error) {
+	if err := a.DescribeClusterAttachScriptsError.Get(); err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return &ackclient.DescribeClusterAttachScriptsResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body:       tea.String(a.attachScript),
+	}, nil
+}
+
+func (a *ACKAPI) DescribeClusterDetail(clusterID *string) (*ackclient.DescribeClusterDetailResponse, error) {
+	if err := a.DescribeClusterDetailError.Get(); err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	metaData, err := json.Marshal(map[string]interface{}{
+		"Capabilities": map[string]string{"Network": a.clusterCNI},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ackclient.DescribeClusterDetailResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ackclient.DescribeClusterDetailResponseBody{
+			ClusterId:   clusterID,
+			ClusterType: tea.String("ManagedKubernetes"),
+			RegionId:    tea.String(DefaultRegion),
+			State:       tea.String("running"),
+			MetaData:    tea.String(string(metaData)),
+		},
+	}, nil
+}
+
+func (a *ACKAPI) DescribeClusterNodePools(_ *string, _ *ackclient.DescribeClusterNodePoolsRequest) (*ackclient.DescribeClusterNodePoolsResponse, error) {
+	if err := a.DescribeClusterNodePoolsError.Get(); err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return &ackclient.DescribeClusterNodePoolsResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ackclient.DescribeClusterNodePoolsResponseBody{
+			Nodepools: []*ackclient.DescribeClusterNodePoolsResponseBodyNodepools{{
+				NodepoolInfo: &ackclient.DescribeClusterNodePoolsResponseBodyNodepoolsNodepoolInfo{
+					Name:      tea.String("default-nodepool"),
+					IsDefault: tea.Bool(true),
+				},
+				KubernetesConfig: &ackclient.DescribeClusterNodePoolsResponseBodyNodepoolsKubernetesConfig{
+					Runtime:        tea.String(a.runtime),
+					RuntimeVersion: tea.String(a.runtimeVersion),
+				},
+				Status: &ackclient.DescribeClusterNodePoolsResponseBodyNodepoolsStatus{
+					HealthyNodes: tea.Int64(1),
+				},
+			}},
+		},
+	}, nil
+}
+
+func (a *ACKAPI) DescribeKubernetesVersionMetadata(request *ackclient.DescribeKubernetesVersionMetadataRequest) (*ackclient.DescribeKubernetesVersionMetadataResponse, error) {
+	if err := a.DescribeKubernetesVersionMetadataError.Get(); err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	version := tea.StringValue(request.KubernetesVersion)
+	return &ackclient.DescribeKubernetesVersionMetadataResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: []*ackclient.DescribeKubernetesVersionMetadataResponseBody{{
+			Version: tea.String(version),
+			Images:  a.images[version],
+		}},
+	}, nil
+}
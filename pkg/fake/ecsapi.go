@@ -0,0 +1,1550 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+)
+
+const (
+	// MaxTagQueryResults is the amount of resources DescribeInstances returns at most when filtering by tags
+	MaxTagQueryResults = 1000
+
+	ErrCodeNoStock = "OperationDenied.NoStock"
+	// ErrCodeIPNotEnough is reported when the vSwitch of a launch template config runs out of IPs
+	ErrCodeIPNotEnough = "InvalidVSwitchId.IpNotEnough"
+	// ErrCodeDeploymentSetNoStock is reported when the deployment set has no capacity left in the zone
+	ErrCodeDeploymentSetNoStock = "DeploymentSet.NoInstanceStock"
+	// ErrCodePrivatePoolNoStock is reported when the private pools have no capacity left for the reserved launch
+	ErrCodePrivatePoolNoStock = "PrivatePool.InsufficientCapacity"
+
+	defaultMaxResults = 10
+	// maxDeleteInstances is the amount of instances a DeleteInstances call accepts
+	maxDeleteInstances = 100
+	// maxDescribeInstanceIDs is the amount of instance IDs a DescribeInstances call accepts
+	maxDescribeInstanceIDs = 100
+	// maxTagResourcesResults is the page size of ListTagResources
+	maxTagResourcesResults = 50
+)
+
+var _ sdk.ECSAPI = (*ECSAPI)(nil)
+
+// CapacityPool is a combination of instance type, zone and capacity type which instances are launched from
+type CapacityPool struct {
+	InstanceType string
+	ZoneID       string
+	CapacityType string
+}
+
+// ECSAPI is an in-memory ECS. Instance types, offerings, security groups, images and events are seeded by the
+// tests, instances are created by the auto provisioning group launches and kept until they are deleted.
+type ECSAPI struct {
+	mu     sync.RWMutex
+	vpcAPI *VPCAPI
+
+	instanceTypes             []*ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType
+	offerings                 []CapacityPool
+	systemDiskCategories      []string
+	securityGroups            []*ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup
+	images                    []*ecsclient.DescribeImagesResponseBodyImagesImage
+	instanceEvents            []*ecsclient.DescribeInstanceHistoryEventsResponseBodyInstanceSystemEventSetInstanceSystemEventType
+	spotPriceHistory          []*ecsclient.DescribeSpotPriceHistoryResponseBodySpotPricesSpotPriceType
+	capacityReservations      []*ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItem
+	elasticityAssurances      []*ecsclient.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItem
+	deploymentSets            []*ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet
+	deploymentSetTags         map[string]map[string]string
+	dedicatedHosts            []*ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost
+	dedicatedHostClusters     []*ecsclient.DescribeDedicatedHostClustersResponseBodyDedicatedHostClustersDedicatedHostCluster
+	insufficientCapacityPools []CapacityPool
+	instances                 map[string]*ecsclient.DescribeInstancesResponseBodyInstancesInstance
+	createAPGRequests         []*ecsclient.CreateAutoProvisioningGroupRequest
+	deleteInstancesRequests   []*ecsclient.DeleteInstancesRequest
+	describeInstancesRequests []*ecsclient.DescribeInstancesRequest
+	runInstancesRequests      []*ecsclient.RunInstancesRequest
+
+	AddTagsError                       AtomicError
+	CreateAutoProvisioningGroupError   AtomicError
+	CreateDeploymentSetError           AtomicError
+	DeleteDeploymentSetError           AtomicError
+	DeleteInstancesError               AtomicError
+	DescribeAvailableResourceError     AtomicError
+	DescribeCapacityReservationsError  AtomicError
+	DescribeDedicatedHostClustersError AtomicError
+	DescribeDedicatedHostsError        AtomicError
+	DescribeDeploymentSetsError        AtomicError
+	DescribeElasticityAssurancesError  AtomicError
+	DescribeImagesError                AtomicError
+	DescribeInstanceHistoryEventsError AtomicError
+	DescribeInstanceTypesError         AtomicError
+	DescribeInstancesError             AtomicError
+	DescribeSecurityGroupsError        AtomicError
+	DescribeSpotPriceHistoryError      AtomicError
+	ListTagResourcesError              AtomicError
+	RunInstancesError                  AtomicError
+}
+
+// NewECSAPI returns an empty ECS, the vSwitches of the launched instances are looked up in the given VPC
+func NewECSAPI(vpcAPI *VPCAPI) *ECSAPI {
+	return &ECSAPI{
+		vpcAPI:            vpcAPI,
+		deploymentSetTags: map[string]map[string]string{},
+		instances:         map[string]*ecsclient.DescribeInstancesResponseBodyInstancesInstance{},
+	}
+}
+
+// Reset removes all the seeded resources, the instances and the injected errors
+func (e *ECSAPI) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.instanceTypes = nil
+	e.offerings = nil
+	e.systemDiskCategories = nil
+	e.securityGroups = nil
+	e.images = nil
+	e.instanceEvents = nil
+	e.spotPriceHistory = nil
+	e.capacityReservations = nil
+	e.elasticityAssurances = nil
+	e.deploymentSets = nil
+	e.deploymentSetTags = map[string]map[string]string{}
+	e.dedicatedHosts = nil
+	e.dedicatedHostClusters = nil
+	e.insufficientCapacityPools = nil
+	e.instances = map[string]*ecsclient.DescribeInstancesResponseBodyInstancesInstance{}
+	e.createAPGRequests = nil
+	e.deleteInstancesRequests = nil
+	e.describeInstancesRequests = nil
+	e.runInstancesRequests = nil
+
+	for _, err := range []*AtomicError{&e.AddTagsError, &e.CreateAutoProvisioningGroupError, &e.CreateDeploymentSetError,
+		&e.DeleteDeploymentSetError, &e.DeleteInstancesError, &e.DescribeAvailableResourceError, &e.DescribeCapacityReservationsError,
+		&e.DescribeDedicatedHostClustersError, &e.DescribeDedicatedHostsError, &e.DescribeDeploymentSetsError,
+		&e.DescribeElasticityAssurancesError, &e.DescribeImagesError, &e.DescribeInstanceHistoryEventsError,
+		&e.DescribeInstanceTypesError, &e.DescribeInstancesError, &e.DescribeSecurityGroupsError, &e.DescribeSpotPriceHistoryError,
+		&e.ListTagResourcesError, &e.RunInstancesError} {
+		err.Reset()
+	}
+}
+
+func (e *ECSAPI) AddInstanceTypes(instanceTypes ...*ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.instanceTypes = append(e.instanceTypes, instanceTypes...)
+}
+
+// AddOfferings makes the capacity pools available, they are reported with stock by DescribeAvailableResource
+func (e *ECSAPI) AddOfferings(offerings ...CapacityPool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.offerings = append(e.offerings, offerings...)
+}
+
+// SetSystemDiskCategories sets the system disk categories that are available for every instance type
+func (e *ECSAPI) SetSystemDiskCategories(categories ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.systemDiskCategories = categories
+}
+
+func (e *ECSAPI) AddSecurityGroups(securityGroups ...*ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.securityGroups = append(e.securityGroups, securityGroups...)
+}
+
+func (e *ECSAPI) AddImages(images ...*ecsclient.DescribeImagesResponseBodyImagesImage) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.images = append(e.images, images...)
+}
+
+func (e *ECSAPI) AddInstanceEvents(events ...*ecsclient.DescribeInstanceHistoryEventsResponseBodyInstanceSystemEventSetInstanceSystemEventType) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.instanceEvents = append(e.instanceEvents, events...)
+}
+
+// AddSpotPriceHistory seeds the spot prices reported by DescribeSpotPriceHistory, the timestamps are in the
+// yyyy-MM-ddTHH:mm:ssZ format
+func (e *ECSAPI) AddSpotPriceHistory(prices ...*ecsclient.DescribeSpotPriceHistoryResponseBodySpotPricesSpotPriceType) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spotPriceHistory = append(e.spotPriceHistory, prices...)
+}
+
+// AddCapacityReservations seeds the capacity reservations, the instances launched into their private pools consume
+// the available amount of the allocated resources
+func (e *ECSAPI) AddCapacityReservations(capacityReservations ...*ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItem) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.capacityReservations = append(e.capacityReservations, capacityReservations...)
+}
+
+// AddElasticityAssurances seeds the elasticity assurances, the instances launched into their private pools consume
+// the available amount of the allocated resources
+func (e *ECSAPI) AddElasticityAssurances(elasticityAssurances ...*ecsclient.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItem) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.elasticityAssurances = append(e.elasticityAssurances, elasticityAssurances...)
+}
+
+// AddDeploymentSet seeds a deployment set with its tags, the instances launched into it consume the available amount
+// of the capacity of their zone
+func (e *ECSAPI) AddDeploymentSet(deploymentSet *ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet, tags map[string]string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.deploymentSets = append(e.deploymentSets, deploymentSet)
+	e.deploymentSetTags[tea.StringValue(deploymentSet.DeploymentSetId)] = tags
+}
+
+// DeploymentSets returns the seeded and the created deployment sets
+func (e *ECSAPI) DeploymentSets() []*ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]*ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet{}, e.deploymentSets...)
+}
+
+// AddDedicatedHosts seeds dedicated hosts, the instances launched onto them consume their available vCPUs and memory
+func (e *ECSAPI) AddDedicatedHosts(hosts ...*ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dedicatedHosts = append(e.dedicatedHosts, hosts...)
+}
+
+// AddDedicatedHostClusters seeds dedicated host clusters, their hosts are seeded separately
+func (e *ECSAPI) AddDedicatedHostClusters(clusters ...*ecsclient.DescribeDedicatedHostClustersResponseBodyDedicatedHostClustersDedicatedHostCluster) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dedicatedHostClusters = append(e.dedicatedHostClusters, clusters...)
+}
+
+// AddInsufficientCapacityPools makes the launches from the capacity pools fail with NoStock
+func (e *ECSAPI) AddInsufficientCapacityPools(pools ...CapacityPool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.insufficientCapacityPools = append(e.insufficientCapacityPools, pools...)
+}
+
+// AddInstances adds instances which are not launched by an auto provisioning group
+func (e *ECSAPI) AddInstances(instances ...*ecsclient.DescribeInstancesResponseBodyInstancesInstance) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, instance := range instances {
+		e.instances[tea.StringValue(instance.InstanceId)] = instance
+	}
+}
+
+// Instance returns the instance with the given ID
+func (e *ECSAPI) Instance(id string) (*ecsclient.DescribeInstancesResponseBodyInstancesInstance, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	instance, ok := e.instances[id]
+	return instance, ok
+}
+
+// Instances returns all the instances ordered by ID
+func (e *ECSAPI) Instances() []*ecsclient.DescribeInstancesResponseBodyInstancesInstance {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.sortedInstances()
+}
+
+// SetInstanceStatus changes the status of the instance, e.g. to Stopped
+func (e *ECSAPI) SetInstanceStatus(id, status string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if instance, ok := e.instances[id]; ok {
+		instance.Status = tea.String(status)
+	}
+}
+
+// LockForRecycling locks the spot instance as ECS does before it is reclaimed
+func (e *ECSAPI) LockForRecycling(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if instance, ok := e.instances[id]; ok {
+		instance.OperationLocks = &ecsclient.DescribeInstancesResponseBodyInstancesInstanceOperationLocks{
+			LockReason: []*ecsclient.DescribeInstancesResponseBodyInstancesInstanceOperationLocksLockReason{
+				{LockReason: tea.String("recycling")},
+			},
+		}
+	}
+}
+
+// CreateAutoProvisioningGroupRequests returns the received launch requests in order
+func (e *ECSAPI) CreateAutoProvisioningGroupRequests() []*ecsclient.CreateAutoProvisioningGroupRequest {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]*ecsclient.CreateAutoProvisioningGroupRequest{}, e.createAPGRequests...)
+}
+
+// RunInstancesRequests returns the received RunInstances requests in order
+func (e *ECSAPI) RunInstancesRequests() []*ecsclient.RunInstancesRequest {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]*ecsclient.RunInstancesRequest{}, e.runInstancesRequests...)
+}
+
+// DescribeInstancesRequests returns the received DescribeInstances requests in order, one per page
+func (e *ECSAPI) DescribeInstancesRequests() []*ecsclient.DescribeInstancesRequest {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]*ecsclient.DescribeInstancesRequest{}, e.describeInstancesRequests...)
+}
+
+// DeleteInstancesRequests returns the DeleteInstances requests received so far
+func (e *ECSAPI) DeleteInstancesRequests() []*ecsclient.DeleteInstancesRequest {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]*ecsclient.DeleteInstancesRequest{}, e.deleteInstancesRequests...)
+}
+
+func (e *ECSAPI) sortedInstances() []*ecsclient.DescribeInstancesResponseBodyInstancesInstance {
+	instances := lo.Values(e.instances)
+	sort.Slice(instances, func(i, j int) bool {
+		return tea.StringValue(instances[i].InstanceId) < tea.StringValue(instances[j].InstanceId)
+	})
+	return instances
+}
+
+func (e *ECSAPI) AddTagsWithOptions(request *ecsclient.AddTagsRequest, _ *util.RuntimeOptions) (*ecsclient.AddTagsResponse, error) {
+	if err := e.AddTagsError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	instance, ok := e.instances[tea.StringValue(request.ResourceId)]
+	if !ok {
+		return nil, NewNotFoundError("InvalidResourceId.NotFound", "The specified ResourceIds are not found in our records.")
+	}
+	tags := lo.Assign(instanceTags(instance), lo.SliceToMap(request.Tag, func(t *ecsclient.AddTagsRequestTag) (string, string) {
+		return tea.StringValue(t.Key), tea.StringValue(t.Value)
+	}))
+	instance.Tags = toInstanceTags(tags)
+
+	return &ecsclient.AddTagsResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body:       &ecsclient.AddTagsResponseBody{RequestId: requestID()},
+	}, nil
+}
+
+// CreateAutoProvisioningGroupWithOptions launches the target capacity from the launch template configs in order,
+// skipping the capacity pools marked as insufficient and the vSwitches running out of IPs. Unlike ECS it doesn't
+// sort the configs by price. When the group only launches from private pools, every instance consumes the capacity
+// of one of the given capacity reservations or elasticity assurances.
+func (e *ECSAPI) CreateAutoProvisioningGroupWithOptions(request *ecsclient.CreateAutoProvisioningGroupRequest,
+	_ *util.RuntimeOptions) (*ecsclient.CreateAutoProvisioningGroupResponse, error) {
+	if err := e.CreateAutoProvisioningGroupError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.createAPGRequests = append(e.createAPGRequests, request)
+	capacityType := karpv1.CapacityTypeOnDemand
+	spotStrategy := "NoSpot"
+	if n, _ := strconv.Atoi(tea.StringValue(request.SpotTargetCapacity)); n > 0 {
+		capacityType = karpv1.CapacityTypeSpot
+		spotStrategy = "SpotAsPriceGo"
+	}
+	var privatePoolIDs []string
+	if options := request.ResourcePoolOptions; options != nil && tea.StringValue(options.Strategy) == "PrivatePoolOnly" {
+		capacityType = v1alpha1.CapacityTypeReserved
+		privatePoolIDs = tea.StringSliceValue(options.PrivatePoolIds)
+	}
+	remaining, err := strconv.Atoi(tea.StringValue(request.TotalTargetCapacity))
+	if err != nil {
+		return nil, fmt.Errorf("parsing the total target capacity, %w", err)
+	}
+	var deploymentSet *ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet
+	if launchConfiguration := request.LaunchConfiguration; launchConfiguration != nil && tea.StringValue(launchConfiguration.DeploymentSetId) != "" {
+		var ok bool
+		if deploymentSet, ok = e.deploymentSet(tea.StringValue(launchConfiguration.DeploymentSetId)); !ok {
+			return nil, NewNotFoundError("InvalidDeploymentSetId.NotFound", fmt.Sprintf("The specified deployment set %s does not exist.",
+				tea.StringValue(launchConfiguration.DeploymentSetId)))
+		}
+	}
+
+	var launched, failed []*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult
+	for _, config := range request.LaunchTemplateConfig {
+		if remaining == 0 {
+			break
+		}
+		instanceType := tea.StringValue(config.InstanceType)
+		zoneID, err := e.vSwitchZone(tea.StringValue(config.VSwitchId))
+		if err != nil {
+			return nil, err
+		}
+		if lo.Contains(e.insufficientCapacityPools, CapacityPool{InstanceType: instanceType, ZoneID: zoneID, CapacityType: capacityType}) {
+			failed = append(failed, &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{
+				ErrorCode:    tea.String(ErrCodeNoStock),
+				ErrorMsg:     tea.String(fmt.Sprintf("The requested resource %s is sold out in the zone %s", instanceType, zoneID)),
+				InstanceType: tea.String(instanceType),
+				SpotStrategy: tea.String(spotStrategy),
+				ZoneId:       tea.String(zoneID),
+			})
+			continue
+		}
+
+		var instanceIDs []*string
+		for ; remaining > 0; remaining-- {
+			privatePoolID, consumePrivatePool, ok := e.privatePool(privatePoolIDs, instanceType, zoneID)
+			if capacityType == v1alpha1.CapacityTypeReserved && !ok {
+				failed = append(failed, &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{
+					ErrorCode:    tea.String(ErrCodePrivatePoolNoStock),
+					ErrorMsg:     tea.String(fmt.Sprintf("The private pools have no capacity left for %s in the zone %s", instanceType, zoneID)),
+					InstanceType: tea.String(instanceType),
+					SpotStrategy: tea.String(spotStrategy),
+					ZoneId:       tea.String(zoneID),
+				})
+				break
+			}
+			if capacity := deploymentSetCapacity(deploymentSet, zoneID); capacity != nil && tea.Int32Value(capacity.AvailableAmount) <= 0 {
+				failed = append(failed, &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{
+					ErrorCode:    tea.String(ErrCodeDeploymentSetNoStock),
+					ErrorMsg:     tea.String(fmt.Sprintf("The deployment set has no capacity left in the zone %s", zoneID)),
+					InstanceType: tea.String(instanceType),
+					SpotStrategy: tea.String(spotStrategy),
+					ZoneId:       tea.String(zoneID),
+				})
+				break
+			}
+			if _, err := e.vpcAPI.consumeIPAddress(tea.StringValue(config.VSwitchId)); err != nil {
+				failed = append(failed, &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{
+					ErrorCode:    tea.String(ErrCodeIPNotEnough),
+					ErrorMsg:     tea.String(err.Error()),
+					InstanceType: tea.String(instanceType),
+					SpotStrategy: tea.String(spotStrategy),
+					ZoneId:       tea.String(zoneID),
+				})
+				break
+			}
+			if ok {
+				consumePrivatePool()
+			}
+			instanceID := randomID("i-")
+			e.instances[instanceID] = e.newInstance(instanceID, request, config, zoneID, spotStrategy, privatePoolID)
+			if deploymentSet != nil {
+				placeIntoDeploymentSet(deploymentSet, instanceID, zoneID, 1)
+			}
+			instanceIDs = append(instanceIDs, tea.String(instanceID))
+		}
+		if len(instanceIDs) == 0 {
+			continue
+		}
+		launched = append(launched, &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{
+			Amount:       tea.Int32(int32(len(instanceIDs))),
+			InstanceIds:  &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResultInstanceIds{InstanceId: instanceIDs},
+			InstanceType: tea.String(instanceType),
+			SpotStrategy: tea.String(spotStrategy),
+			ZoneId:       tea.String(zoneID),
+		})
+	}
+
+	// The launched instances come first, the same as ECS
+	return &ecsclient.CreateAutoProvisioningGroupResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.CreateAutoProvisioningGroupResponseBody{
+			RequestId:               requestID(),
+			AutoProvisioningGroupId: tea.String(randomID("apg-")),
+			LaunchResults:           &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResults{LaunchResult: append(launched, failed...)},
+		},
+	}, nil
+}
+
+func (e *ECSAPI) vSwitchZone(id string) (string, error) {
+	if e.vpcAPI == nil {
+		return "", fmt.Errorf("no VPC to look up the vSwitch %s", id)
+	}
+	vSwitch, ok := e.vpcAPI.VSwitch(id)
+	if !ok {
+		return "", NewNotFoundError("InvalidVSwitchId.NotFound", fmt.Sprintf("The specified vSwitch %s does not exist.", id))
+	}
+	return vSwitch.ZoneID, nil
+}
+
+// privatePool returns the first of the private pools with capacity left for the instance type in the zone and the
+// function consuming an instance of it
+func (e *ECSAPI) privatePool(ids []string, instanceType, zoneID string) (string, func(), bool) {
+	matches := func(id string, resourceInstanceType, resourceZoneID *string, availableAmount *int32) bool {
+		return lo.Contains(ids, id) && tea.StringValue(resourceInstanceType) == instanceType &&
+			tea.StringValue(resourceZoneID) == zoneID && tea.Int32Value(availableAmount) > 0
+	}
+	for _, capacityReservation := range e.capacityReservations {
+		if capacityReservation.AllocatedResources == nil {
+			continue
+		}
+		for _, r := range capacityReservation.AllocatedResources.AllocatedResource {
+			if matches(tea.StringValue(capacityReservation.PrivatePoolOptionsId), r.InstanceType, r.ZoneId, r.AvailableAmount) {
+				return tea.StringValue(capacityReservation.PrivatePoolOptionsId), func() {
+					r.AvailableAmount = tea.Int32(tea.Int32Value(r.AvailableAmount) - 1)
+					r.UsedAmount = tea.Int32(tea.Int32Value(r.UsedAmount) + 1)
+				}, true
+			}
+		}
+	}
+	for _, elasticityAssurance := range e.elasticityAssurances {
+		if elasticityAssurance.AllocatedResources == nil {
+			continue
+		}
+		for _, r := range elasticityAssurance.AllocatedResources.AllocatedResource {
+			if matches(tea.StringValue(elasticityAssurance.PrivatePoolOptionsId), r.InstanceType, r.ZoneId, r.AvailableAmount) {
+				return tea.StringValue(elasticityAssurance.PrivatePoolOptionsId), func() {
+					r.AvailableAmount = tea.Int32(tea.Int32Value(r.AvailableAmount) - 1)
+					r.UsedAmount = tea.Int32(tea.Int32Value(r.UsedAmount) + 1)
+				}, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+func (e *ECSAPI) deploymentSet(id string) (*ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet, bool) {
+	return lo.Find(e.deploymentSets, func(ds *ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet) bool {
+		return tea.StringValue(ds.DeploymentSetId) == id
+	})
+}
+
+// deploymentSetCapacity returns the capacity of the deployment set in the zone, nil is returned when the deployment
+// set has no limit reported for the zone
+func deploymentSetCapacity(deploymentSet *ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet,
+	zoneID string) *ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSetCapacitiesCapacity {
+	if deploymentSet == nil || deploymentSet.Capacities == nil {
+		return nil
+	}
+	capacity, _ := lo.Find(deploymentSet.Capacities.Capacity, func(c *ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSetCapacitiesCapacity) bool {
+		return tea.StringValue(c.ZoneId) == zoneID
+	})
+	return capacity
+}
+
+// placeIntoDeploymentSet adds the instance to the deployment set when delta is 1 and removes it when delta is -1
+func placeIntoDeploymentSet(deploymentSet *ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet, instanceID, zoneID string, delta int32) {
+	if deploymentSet.InstanceIds == nil {
+		deploymentSet.InstanceIds = &ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSetInstanceIds{}
+	}
+	if delta > 0 {
+		deploymentSet.InstanceIds.InstanceId = append(deploymentSet.InstanceIds.InstanceId, tea.String(instanceID))
+	} else {
+		deploymentSet.InstanceIds.InstanceId = lo.Reject(deploymentSet.InstanceIds.InstanceId, func(id *string, _ int) bool {
+			return tea.StringValue(id) == instanceID
+		})
+	}
+	deploymentSet.InstanceAmount = tea.Int32(tea.Int32Value(deploymentSet.InstanceAmount) + delta)
+	if capacity := deploymentSetCapacity(deploymentSet, zoneID); capacity != nil {
+		capacity.AvailableAmount = tea.Int32(tea.Int32Value(capacity.AvailableAmount) - delta)
+		capacity.UsedAmount = tea.Int32(tea.Int32Value(capacity.UsedAmount) + delta)
+	}
+}
+
+func (e *ECSAPI) dedicatedHost(id string) (*ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost, bool) {
+	return lo.Find(e.dedicatedHosts, func(host *ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost) bool {
+		return tea.StringValue(host.DedicatedHostId) == id
+	})
+}
+
+// fitsOnDedicatedHost returns true when the available dedicated host of the zone supports the instance type and has
+// enough vCPUs and memory left for it
+func (e *ECSAPI) fitsOnDedicatedHost(host *ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost, instanceType, zoneID string) bool {
+	if tea.StringValue(host.ZoneId) != zoneID || tea.StringValue(host.Status) != "Available" || host.Capacity == nil ||
+		host.SupportedInstanceTypesList == nil || !lo.Contains(tea.StringSliceValue(host.SupportedInstanceTypesList.SupportedInstanceTypesList), instanceType) {
+		return false
+	}
+	info, ok := lo.Find(e.instanceTypes, func(it *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType) bool {
+		return tea.StringValue(it.InstanceTypeId) == instanceType
+	})
+	if !ok {
+		return true
+	}
+	return tea.Int32Value(host.Capacity.AvailableVcpus) >= tea.Int32Value(info.CpuCoreCount) &&
+		tea.Float32Value(host.Capacity.AvailableMemory) >= tea.Float32Value(info.MemorySize)
+}
+
+// placeOntoDedicatedHost consumes the vCPUs and the memory of the instance when delta is 1 and gives them back when
+// delta is -1
+func placeOntoDedicatedHost(host *ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost,
+	instance *ecsclient.DescribeInstancesResponseBodyInstancesInstance, delta int32) {
+	if host.Capacity == nil {
+		return
+	}
+	host.Capacity.AvailableVcpus = tea.Int32(tea.Int32Value(host.Capacity.AvailableVcpus) - delta*tea.Int32Value(instance.Cpu))
+	host.Capacity.AvailableMemory = tea.Float32(tea.Float32Value(host.Capacity.AvailableMemory) - float32(delta)*float32(tea.Int32Value(instance.Memory))/1024)
+}
+
+func (e *ECSAPI) newInstance(id string, request *ecsclient.CreateAutoProvisioningGroupRequest,
+	config *ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig, zoneID, spotStrategy, privatePoolID string) *ecsclient.DescribeInstancesResponseBodyInstancesInstance {
+	launchConfiguration := request.LaunchConfiguration
+	if launchConfiguration == nil {
+		launchConfiguration = &ecsclient.CreateAutoProvisioningGroupRequestLaunchConfiguration{}
+	}
+	tags := lo.SliceToMap(launchConfiguration.Tag, func(t *ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationTag) (string, string) {
+		return tea.StringValue(t.Key), tea.StringValue(t.Value)
+	})
+	securityGroupIDs := launchConfiguration.SecurityGroupIds
+	if len(securityGroupIDs) == 0 && launchConfiguration.SecurityGroupId != nil {
+		securityGroupIDs = []*string{launchConfiguration.SecurityGroupId}
+	}
+
+	instance := &ecsclient.DescribeInstancesResponseBodyInstancesInstance{
+		InstanceId:         tea.String(id),
+		InstanceType:       config.InstanceType,
+		RegionId:           tea.String(lo.Ternary(request.RegionId != nil, tea.StringValue(request.RegionId), DefaultRegion)),
+		ZoneId:             tea.String(zoneID),
+		ImageId:            lo.Ternary(config.ImageId != nil, config.ImageId, launchConfiguration.ImageId),
+		Status:             tea.String("Running"),
+		SpotStrategy:       tea.String(spotStrategy),
+		InstanceChargeType: tea.String("PostPaid"),
+		CreationTime:       tea.String(time.Now().UTC().Format("2006-01-02T15:04Z")),
+		SecurityGroupIds:   &ecsclient.DescribeInstancesResponseBodyInstancesInstanceSecurityGroupIds{SecurityGroupId: securityGroupIDs},
+		VpcAttributes:      &ecsclient.DescribeInstancesResponseBodyInstancesInstanceVpcAttributes{VSwitchId: config.VSwitchId},
+		Tags:               toInstanceTags(tags),
+	}
+	if instanceType, ok := lo.Find(e.instanceTypes, func(it *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType) bool {
+		return tea.StringValue(it.InstanceTypeId) == tea.StringValue(config.InstanceType)
+	}); ok {
+		instance.Cpu = instanceType.CpuCoreCount
+		instance.Memory = tea.Int32(int32(tea.Float32Value(instanceType.MemorySize) * 1024))
+	}
+	if launchConfiguration.DeploymentSetId != nil {
+		instance.DeploymentSetId = launchConfiguration.DeploymentSetId
+	}
+	if tea.Int32Value(launchConfiguration.InternetMaxBandwidthOut) > 0 {
+		instance.InternetChargeType = launchConfiguration.InternetChargeType
+		instance.InternetMaxBandwidthOut = launchConfiguration.InternetMaxBandwidthOut
+		instance.InternetMaxBandwidthIn = launchConfiguration.InternetMaxBandwidthIn
+		instance.PublicIpAddress = &ecsclient.DescribeInstancesResponseBodyInstancesInstancePublicIpAddress{
+			IpAddress: tea.StringSlice([]string{randomPublicIP()}),
+		}
+	}
+	if privatePoolID != "" {
+		instance.EcsCapacityReservationAttr = &ecsclient.DescribeInstancesResponseBodyInstancesInstanceEcsCapacityReservationAttr{
+			CapacityReservationId:         tea.String(privatePoolID),
+			CapacityReservationPreference: tea.String("target"),
+		}
+	}
+	return instance
+}
+
+// CreateDeploymentSetWithOptions creates an empty deployment set, no capacity limit is reported for its zones
+func (e *ECSAPI) CreateDeploymentSetWithOptions(request *ecsclient.CreateDeploymentSetRequest,
+	_ *util.RuntimeOptions) (*ecsclient.CreateDeploymentSetResponse, error) {
+	if err := e.CreateDeploymentSetError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	id := randomID("ds-")
+	e.deploymentSets = append(e.deploymentSets, &ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet{
+		DeploymentSetId:          tea.String(id),
+		DeploymentSetName:        request.DeploymentSetName,
+		DeploymentSetDescription: request.Description,
+		DeploymentStrategy:       request.Strategy,
+		Strategy:                 request.Strategy,
+		CreationTime:             tea.String(time.Now().UTC().Format("2006-01-02T15:04Z")),
+		InstanceAmount:           tea.Int32(0),
+	})
+	return &ecsclient.CreateDeploymentSetResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.CreateDeploymentSetResponseBody{
+			RequestId:       requestID(),
+			DeploymentSetId: tea.String(id),
+		},
+	}, nil
+}
+
+// DeleteDeploymentSetWithOptions deletes the deployment set, the same as ECS the request fails when instances are
+// still placed into it
+func (e *ECSAPI) DeleteDeploymentSetWithOptions(request *ecsclient.DeleteDeploymentSetRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DeleteDeploymentSetResponse, error) {
+	if err := e.DeleteDeploymentSetError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	id := tea.StringValue(request.DeploymentSetId)
+	deploymentSet, ok := e.deploymentSet(id)
+	if !ok {
+		return nil, NewNotFoundError("InvalidDeploymentSetId.NotFound", fmt.Sprintf("The specified deployment set %s does not exist.", id))
+	}
+	if tea.Int32Value(deploymentSet.InstanceAmount) > 0 {
+		return nil, &tea.SDKError{
+			Code:       tea.String("Forbidden.DeploymentSetNotEmpty"),
+			Message:    tea.String(fmt.Sprintf("The deployment set %s still contains instances.", id)),
+			StatusCode: tea.Int(http.StatusForbidden),
+		}
+	}
+	e.deploymentSets = lo.Without(e.deploymentSets, deploymentSet)
+	delete(e.deploymentSetTags, id)
+	return &ecsclient.DeleteDeploymentSetResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body:       &ecsclient.DeleteDeploymentSetResponseBody{RequestId: requestID()},
+	}, nil
+}
+
+// DeleteInstancesWithOptions deletes all the instances or none of them, the same as ECS the request fails when
+// any of the instances doesn't exist or is still being created
+func (e *ECSAPI) DeleteInstancesWithOptions(request *ecsclient.DeleteInstancesRequest, _ *util.RuntimeOptions) (*ecsclient.DeleteInstancesResponse, error) {
+	if err := e.DeleteInstancesError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.deleteInstancesRequests = append(e.deleteInstancesRequests, request)
+	if len(request.InstanceId) == 0 || len(request.InstanceId) > maxDeleteInstances {
+		return nil, &tea.SDKError{
+			Code:       tea.String("InvalidParameter.InstanceIdCountExceeded"),
+			Message:    tea.String(fmt.Sprintf("The count of InstanceId must be between 1 and %d.", maxDeleteInstances)),
+			StatusCode: tea.Int(http.StatusBadRequest),
+		}
+	}
+	for _, id := range request.InstanceId {
+		instance, ok := e.instances[tea.StringValue(id)]
+		if !ok {
+			return nil, NewNotFoundError("InvalidInstanceId.NotFound", "The specified InstanceId does not exist.")
+		}
+		if lo.Contains([]string{"Pending", "Starting"}, tea.StringValue(instance.Status)) {
+			return nil, NewIncorrectInstanceStatusError()
+		}
+	}
+	for _, id := range request.InstanceId {
+		instance := e.instances[tea.StringValue(id)]
+		delete(e.instances, tea.StringValue(id))
+		if e.vpcAPI != nil && instance.VpcAttributes != nil {
+			e.vpcAPI.releaseIPAddress(tea.StringValue(instance.VpcAttributes.VSwitchId))
+		}
+		if deploymentSet, ok := e.deploymentSet(tea.StringValue(instance.DeploymentSetId)); ok {
+			placeIntoDeploymentSet(deploymentSet, tea.StringValue(id), tea.StringValue(instance.ZoneId), -1)
+		}
+		if instance.DedicatedHostAttribute != nil {
+			if host, ok := e.dedicatedHost(tea.StringValue(instance.DedicatedHostAttribute.DedicatedHostId)); ok {
+				placeOntoDedicatedHost(host, instance, -1)
+			}
+		}
+	}
+
+	return &ecsclient.DeleteInstancesResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body:       &ecsclient.DeleteInstancesResponseBody{RequestId: requestID()},
+	}, nil
+}
+
+// DescribeAvailableResourceWithOptions reports the seeded offerings for the InstanceType destination and the
+// system disk categories for the SystemDisk destination
+func (e *ECSAPI) DescribeAvailableResourceWithOptions(request *ecsclient.DescribeAvailableResourceRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DescribeAvailableResourceResponse, error) {
+	if err := e.DescribeAvailableResourceError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	// zone -> resources
+	resources := map[string][]string{}
+	resourceType := tea.StringValue(request.DestinationResource)
+	switch resourceType {
+	case "InstanceType":
+		capacityType := lo.Ternary(tea.StringValue(request.SpotStrategy) == "SpotAsPriceGo" || tea.StringValue(request.SpotStrategy) == "SpotWithPriceLimit",
+			karpv1.CapacityTypeSpot, karpv1.CapacityTypeOnDemand)
+		for _, o := range e.offerings {
+			if o.CapacityType == capacityType && !lo.Contains(resources[o.ZoneID], o.InstanceType) {
+				resources[o.ZoneID] = append(resources[o.ZoneID], o.InstanceType)
+			}
+		}
+	case "SystemDisk":
+		for _, o := range e.offerings {
+			if o.InstanceType == tea.StringValue(request.InstanceType) {
+				resources[o.ZoneID] = e.systemDiskCategories
+			}
+		}
+	default:
+		return nil, fmt.Errorf("destination resource %s is not supported", resourceType)
+	}
+
+	zones := lo.Keys(resources)
+	sort.Strings(zones)
+	return &ecsclient.DescribeAvailableResourceResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeAvailableResourceResponseBody{
+			RequestId: requestID(),
+			AvailableZones: &ecsclient.DescribeAvailableResourceResponseBodyAvailableZones{
+				AvailableZone: lo.Map(zones, func(zone string, _ int) *ecsclient.DescribeAvailableResourceResponseBodyAvailableZonesAvailableZone {
+					return &ecsclient.DescribeAvailableResourceResponseBodyAvailableZonesAvailableZone{
+						RegionId:       request.RegionId,
+						ZoneId:         tea.String(zone),
+						Status:         tea.String("Available"),
+						StatusCategory: tea.String("WithStock"),
+						AvailableResources: &ecsclient.DescribeAvailableResourceResponseBodyAvailableZonesAvailableZoneAvailableResources{
+							AvailableResource: []*ecsclient.DescribeAvailableResourceResponseBodyAvailableZonesAvailableZoneAvailableResourcesAvailableResource{{
+								Type: tea.String(resourceType),
+								SupportedResources: &ecsclient.DescribeAvailableResourceResponseBodyAvailableZonesAvailableZoneAvailableResourcesAvailableResourceSupportedResources{
+									SupportedResource: lo.Map(resources[zone], func(value string, _ int) *ecsclient.DescribeAvailableResourceResponseBodyAvailableZonesAvailableZoneAvailableResourcesAvailableResourceSupportedResourcesSupportedResource {
+										return &ecsclient.DescribeAvailableResourceResponseBodyAvailableZonesAvailableZoneAvailableResourcesAvailableResourceSupportedResourcesSupportedResource{
+											Value:          tea.String(value),
+											Status:         tea.String("Available"),
+											StatusCategory: tea.String("WithStock"),
+										}
+									}),
+								},
+							}},
+						},
+					}
+				}),
+			},
+		},
+	}, nil
+}
+
+// DescribeCapacityReservationsWithOptions filters the seeded capacity reservations by private pool IDs, status and tags
+func (e *ECSAPI) DescribeCapacityReservationsWithOptions(request *ecsclient.DescribeCapacityReservationsRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DescribeCapacityReservationsResponse, error) {
+	if err := e.DescribeCapacityReservationsError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var ids []string
+	if request.PrivatePoolOptions != nil && request.PrivatePoolOptions.Ids != nil {
+		if err := json.Unmarshal([]byte(tea.StringValue(request.PrivatePoolOptions.Ids)), &ids); err != nil {
+			return nil, fmt.Errorf("invalid PrivatePoolOptions.Ids %s, %w", tea.StringValue(request.PrivatePoolOptions.Ids), err)
+		}
+	}
+	filters := lo.Map(request.Tag, func(t *ecsclient.DescribeCapacityReservationsRequestTag, _ int) tagFilter {
+		return tagFilter{key: tea.StringValue(t.Key), value: t.Value}
+	})
+	matched := lo.Filter(e.capacityReservations, func(item *ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItem, _ int) bool {
+		if len(ids) != 0 && !lo.Contains(ids, tea.StringValue(item.PrivatePoolOptionsId)) {
+			return false
+		}
+		if request.Status != nil && tea.StringValue(request.Status) != tea.StringValue(item.Status) {
+			return false
+		}
+		tags := map[string]string{}
+		if item.Tags != nil {
+			tags = lo.SliceToMap(item.Tags.Tag, func(t *ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItemTagsTag) (string, string) {
+				return tea.StringValue(t.TagKey), tea.StringValue(t.TagValue)
+			})
+		}
+		return matchTags(filters, tags)
+	})
+
+	page, nextToken, err := paginate(matched, request.NextToken, int(tea.Int32Value(request.MaxResults)))
+	if err != nil {
+		return nil, err
+	}
+	return &ecsclient.DescribeCapacityReservationsResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeCapacityReservationsResponseBody{
+			RequestId:  requestID(),
+			NextToken:  nextToken,
+			TotalCount: tea.Int32(int32(len(matched))),
+			CapacityReservationSet: &ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSet{
+				CapacityReservationItem: page,
+			},
+		},
+	}, nil
+}
+
+// DescribeDedicatedHostClustersWithOptions filters the seeded dedicated host clusters by IDs, zone and tags
+func (e *ECSAPI) DescribeDedicatedHostClustersWithOptions(request *ecsclient.DescribeDedicatedHostClustersRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DescribeDedicatedHostClustersResponse, error) {
+	if err := e.DescribeDedicatedHostClustersError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var ids []string
+	if request.DedicatedHostClusterIds != nil {
+		if err := json.Unmarshal([]byte(tea.StringValue(request.DedicatedHostClusterIds)), &ids); err != nil {
+			return nil, fmt.Errorf("invalid DedicatedHostClusterIds %s, %w", tea.StringValue(request.DedicatedHostClusterIds), err)
+		}
+	}
+	filters := lo.Map(request.Tag, func(t *ecsclient.DescribeDedicatedHostClustersRequestTag, _ int) tagFilter {
+		return tagFilter{key: tea.StringValue(t.Key), value: t.Value}
+	})
+	matched := lo.Filter(e.dedicatedHostClusters, func(cluster *ecsclient.DescribeDedicatedHostClustersResponseBodyDedicatedHostClustersDedicatedHostCluster, _ int) bool {
+		if len(ids) != 0 && !lo.Contains(ids, tea.StringValue(cluster.DedicatedHostClusterId)) {
+			return false
+		}
+		if request.ZoneId != nil && tea.StringValue(request.ZoneId) != tea.StringValue(cluster.ZoneId) {
+			return false
+		}
+		tags := map[string]string{}
+		if cluster.Tags != nil {
+			tags = lo.SliceToMap(cluster.Tags.Tag, func(t *ecsclient.DescribeDedicatedHostClustersResponseBodyDedicatedHostClustersDedicatedHostClusterTagsTag) (string, string) {
+				return tea.StringValue(t.TagKey), tea.StringValue(t.TagValue)
+			})
+		}
+		return matchTags(filters, tags)
+	})
+
+	pageSize := int(lo.Ternary(tea.Int32Value(request.PageSize) > 0, tea.Int32Value(request.PageSize), defaultMaxResults))
+	pageNumber := int(max(tea.Int32Value(request.PageNumber), 1))
+	return &ecsclient.DescribeDedicatedHostClustersResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeDedicatedHostClustersResponseBody{
+			RequestId:  requestID(),
+			PageNumber: tea.Int32(int32(pageNumber)),
+			PageSize:   tea.Int32(int32(pageSize)),
+			TotalCount: tea.Int32(int32(len(matched))),
+			DedicatedHostClusters: &ecsclient.DescribeDedicatedHostClustersResponseBodyDedicatedHostClusters{
+				DedicatedHostCluster: lo.Slice(matched, (pageNumber-1)*pageSize, pageNumber*pageSize),
+			},
+		},
+	}, nil
+}
+
+// DescribeDedicatedHostsWithOptions filters the seeded dedicated hosts by IDs, cluster, zone and status
+func (e *ECSAPI) DescribeDedicatedHostsWithOptions(request *ecsclient.DescribeDedicatedHostsRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DescribeDedicatedHostsResponse, error) {
+	if err := e.DescribeDedicatedHostsError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var ids []string
+	if request.DedicatedHostIds != nil {
+		if err := json.Unmarshal([]byte(tea.StringValue(request.DedicatedHostIds)), &ids); err != nil {
+			return nil, fmt.Errorf("invalid DedicatedHostIds %s, %w", tea.StringValue(request.DedicatedHostIds), err)
+		}
+	}
+	matched := lo.Filter(e.dedicatedHosts, func(host *ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost, _ int) bool {
+		if len(ids) != 0 && !lo.Contains(ids, tea.StringValue(host.DedicatedHostId)) {
+			return false
+		}
+		if request.DedicatedHostClusterId != nil && tea.StringValue(request.DedicatedHostClusterId) != tea.StringValue(host.DedicatedHostClusterId) {
+			return false
+		}
+		if request.ZoneId != nil && tea.StringValue(request.ZoneId) != tea.StringValue(host.ZoneId) {
+			return false
+		}
+		return request.Status == nil || tea.StringValue(request.Status) == tea.StringValue(host.Status)
+	})
+
+	page, nextToken, err := paginate(matched, request.NextToken, int(tea.Int32Value(request.MaxResults)))
+	if err != nil {
+		return nil, err
+	}
+	return &ecsclient.DescribeDedicatedHostsResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeDedicatedHostsResponseBody{
+			RequestId:      requestID(),
+			NextToken:      nextToken,
+			TotalCount:     tea.Int32(int32(len(matched))),
+			DedicatedHosts: &ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHosts{DedicatedHost: page},
+		},
+	}, nil
+}
+
+// DescribeDeploymentSetsWithOptions filters the deployment sets by IDs, name and strategy
+func (e *ECSAPI) DescribeDeploymentSetsWithOptions(request *ecsclient.DescribeDeploymentSetsRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DescribeDeploymentSetsResponse, error) {
+	if err := e.DescribeDeploymentSetsError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var ids []string
+	if request.DeploymentSetIds != nil {
+		if err := json.Unmarshal([]byte(tea.StringValue(request.DeploymentSetIds)), &ids); err != nil {
+			return nil, fmt.Errorf("invalid DeploymentSetIds %s, %w", tea.StringValue(request.DeploymentSetIds), err)
+		}
+	}
+	matched := lo.Filter(e.deploymentSets, func(ds *ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet, _ int) bool {
+		if len(ids) != 0 && !lo.Contains(ids, tea.StringValue(ds.DeploymentSetId)) {
+			return false
+		}
+		if request.DeploymentSetName != nil && tea.StringValue(request.DeploymentSetName) != tea.StringValue(ds.DeploymentSetName) {
+			return false
+		}
+		return request.Strategy == nil || tea.StringValue(request.Strategy) == tea.StringValue(ds.Strategy)
+	})
+
+	pageSize := int(lo.Ternary(tea.Int32Value(request.PageSize) > 0, tea.Int32Value(request.PageSize), defaultMaxResults))
+	pageNumber := int(max(tea.Int32Value(request.PageNumber), 1))
+	return &ecsclient.DescribeDeploymentSetsResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeDeploymentSetsResponseBody{
+			RequestId:  requestID(),
+			PageNumber: tea.Int32(int32(pageNumber)),
+			PageSize:   tea.Int32(int32(pageSize)),
+			TotalCount: tea.Int32(int32(len(matched))),
+			DeploymentSets: &ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSets{
+				DeploymentSet: lo.Slice(matched, (pageNumber-1)*pageSize, pageNumber*pageSize),
+			},
+		},
+	}, nil
+}
+
+// DescribeElasticityAssurancesWithOptions filters the seeded elasticity assurances by private pool IDs, status and tags
+func (e *ECSAPI) DescribeElasticityAssurancesWithOptions(request *ecsclient.DescribeElasticityAssurancesRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DescribeElasticityAssurancesResponse, error) {
+	if err := e.DescribeElasticityAssurancesError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var ids []string
+	if request.PrivatePoolOptions != nil && request.PrivatePoolOptions.Ids != nil {
+		if err := json.Unmarshal([]byte(tea.StringValue(request.PrivatePoolOptions.Ids)), &ids); err != nil {
+			return nil, fmt.Errorf("invalid PrivatePoolOptions.Ids %s, %w", tea.StringValue(request.PrivatePoolOptions.Ids), err)
+		}
+	}
+	filters := lo.Map(request.Tag, func(t *ecsclient.DescribeElasticityAssurancesRequestTag, _ int) tagFilter {
+		return tagFilter{key: tea.StringValue(t.Key), value: t.Value}
+	})
+	matched := lo.Filter(e.elasticityAssurances, func(item *ecsclient.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItem, _ int) bool {
+		if len(ids) != 0 && !lo.Contains(ids, tea.StringValue(item.PrivatePoolOptionsId)) {
+			return false
+		}
+		if request.Status != nil && tea.StringValue(request.Status) != tea.StringValue(item.Status) {
+			return false
+		}
+		tags := map[string]string{}
+		if item.Tags != nil {
+			tags = lo.SliceToMap(item.Tags.Tag, func(t *ecsclient.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItemTagsTag) (string, string) {
+				return tea.StringValue(t.TagKey), tea.StringValue(t.TagValue)
+			})
+		}
+		return matchTags(filters, tags)
+	})
+
+	page, nextToken, err := paginate(matched, request.NextToken, int(tea.Int32Value(request.MaxResults)))
+	if err != nil {
+		return nil, err
+	}
+	return &ecsclient.DescribeElasticityAssurancesResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeElasticityAssurancesResponseBody{
+			RequestId:  requestID(),
+			NextToken:  nextToken,
+			TotalCount: tea.Int32(int32(len(matched))),
+			ElasticityAssuranceSet: &ecsclient.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSet{
+				ElasticityAssuranceItem: page,
+			},
+		},
+	}, nil
+}
+
+func (e *ECSAPI) DescribeImages(request *ecsclient.DescribeImagesRequest) (*ecsclient.DescribeImagesResponse, error) {
+	if err := e.DescribeImagesError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	filters := lo.Map(request.Tag, func(t *ecsclient.DescribeImagesRequestTag, _ int) tagFilter {
+		return tagFilter{key: tea.StringValue(t.Key), value: t.Value}
+	})
+	matched := lo.Filter(e.images, func(image *ecsclient.DescribeImagesResponseBodyImagesImage, _ int) bool {
+		if request.ImageId != nil && tea.StringValue(request.ImageId) != tea.StringValue(image.ImageId) {
+			return false
+		}
+		if request.ImageName != nil && tea.StringValue(request.ImageName) != tea.StringValue(image.ImageName) {
+			return false
+		}
+		if request.ImageOwnerAlias != nil && tea.StringValue(request.ImageOwnerAlias) != tea.StringValue(image.ImageOwnerAlias) {
+			return false
+		}
+		if request.Architecture != nil && tea.StringValue(request.Architecture) != tea.StringValue(image.Architecture) {
+			return false
+		}
+		return matchTags(filters, imageTags(image))
+	})
+
+	pageSize := int(lo.Ternary(tea.Int32Value(request.PageSize) > 0, tea.Int32Value(request.PageSize), defaultMaxResults))
+	pageNumber := int(max(tea.Int32Value(request.PageNumber), 1))
+	return &ecsclient.DescribeImagesResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeImagesResponseBody{
+			RequestId:  requestID(),
+			RegionId:   request.RegionId,
+			PageNumber: tea.Int32(int32(pageNumber)),
+			PageSize:   tea.Int32(int32(pageSize)),
+			TotalCount: tea.Int32(int32(len(matched))),
+			Images: &ecsclient.DescribeImagesResponseBodyImages{
+				Image: lo.Slice(matched, (pageNumber-1)*pageSize, pageNumber*pageSize),
+			},
+		},
+	}, nil
+}
+
+func (e *ECSAPI) DescribeInstanceHistoryEventsWithOptions(request *ecsclient.DescribeInstanceHistoryEventsRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DescribeInstanceHistoryEventsResponse, error) {
+	if err := e.DescribeInstanceHistoryEventsError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	filters := lo.Map(request.Tag, func(t *ecsclient.DescribeInstanceHistoryEventsRequestTag, _ int) tagFilter {
+		return tagFilter{key: tea.StringValue(t.Key), value: t.Value}
+	})
+	cycleStatuses := tea.StringSliceValue(request.InstanceEventCycleStatus)
+	matched := lo.Filter(e.instanceEvents, func(event *ecsclient.DescribeInstanceHistoryEventsResponseBodyInstanceSystemEventSetInstanceSystemEventType, _ int) bool {
+		if len(cycleStatuses) != 0 && (event.EventCycleStatus == nil || !lo.Contains(cycleStatuses, tea.StringValue(event.EventCycleStatus.Name))) {
+			return false
+		}
+		if len(filters) == 0 {
+			return true
+		}
+		// The tags are the tags of the instance the event belongs to
+		instance, ok := e.instances[tea.StringValue(event.InstanceId)]
+		return ok && matchTags(filters, instanceTags(instance))
+	})
+
+	page, nextToken, err := paginate(matched, request.NextToken, int(tea.Int64Value(request.MaxResults)))
+	if err != nil {
+		return nil, err
+	}
+	return &ecsclient.DescribeInstanceHistoryEventsResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeInstanceHistoryEventsResponseBody{
+			RequestId:  requestID(),
+			NextToken:  nextToken,
+			TotalCount: tea.Int32(int32(len(matched))),
+			InstanceSystemEventSet: &ecsclient.DescribeInstanceHistoryEventsResponseBodyInstanceSystemEventSet{
+				InstanceSystemEventType: page,
+			},
+		},
+	}, nil
+}
+
+func (e *ECSAPI) DescribeInstanceTypesWithOptions(request *ecsclient.DescribeInstanceTypesRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DescribeInstanceTypesResponse, error) {
+	if err := e.DescribeInstanceTypesError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	page, nextToken, err := paginate(e.instanceTypes, request.NextToken, int(tea.Int64Value(request.MaxResults)))
+	if err != nil {
+		return nil, err
+	}
+	return &ecsclient.DescribeInstanceTypesResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeInstanceTypesResponseBody{
+			RequestId:     requestID(),
+			NextToken:     nextToken,
+			InstanceTypes: &ecsclient.DescribeInstanceTypesResponseBodyInstanceTypes{InstanceType: page},
+		},
+	}, nil
+}
+
+func (e *ECSAPI) DescribeInstancesWithOptions(request *ecsclient.DescribeInstancesRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DescribeInstancesResponse, error) {
+	if err := e.DescribeInstancesError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// The request is reused for the next page, record a copy of it
+	recorded := *request
+	e.describeInstancesRequests = append(e.describeInstancesRequests, &recorded)
+
+	var instanceIDs []string
+	if request.InstanceIds != nil {
+		if err := json.Unmarshal([]byte(tea.StringValue(request.InstanceIds)), &instanceIDs); err != nil {
+			return nil, fmt.Errorf("invalid InstanceIds %s, %w", tea.StringValue(request.InstanceIds), err)
+		}
+		if len(instanceIDs) > maxDescribeInstanceIDs {
+			return nil, &tea.SDKError{
+				Code:       tea.String("InvalidInstanceIds.Malformed"),
+				Message:    tea.String(fmt.Sprintf("The amount of specified InstanceIds exceeds the limit %d.", maxDescribeInstanceIDs)),
+				StatusCode: tea.Int(http.StatusBadRequest),
+			}
+		}
+	}
+	filters := lo.Map(request.Tag, func(t *ecsclient.DescribeInstancesRequestTag, _ int) tagFilter {
+		return tagFilter{key: tea.StringValue(t.Key), value: t.Value}
+	})
+	matched := lo.Filter(e.sortedInstances(), func(instance *ecsclient.DescribeInstancesResponseBodyInstancesInstance, _ int) bool {
+		if request.InstanceIds != nil && !lo.Contains(instanceIDs, tea.StringValue(instance.InstanceId)) {
+			return false
+		}
+		if request.Status != nil && tea.StringValue(request.Status) != tea.StringValue(instance.Status) {
+			return false
+		}
+		if request.ZoneId != nil && tea.StringValue(request.ZoneId) != tea.StringValue(instance.ZoneId) {
+			return false
+		}
+		if request.LockReason != nil && (instance.OperationLocks == nil ||
+			!lo.ContainsBy(instance.OperationLocks.LockReason, func(l *ecsclient.DescribeInstancesResponseBodyInstancesInstanceOperationLocksLockReason) bool {
+				return tea.StringValue(l.LockReason) == tea.StringValue(request.LockReason)
+			})) {
+			return false
+		}
+		return matchTags(filters, instanceTags(instance))
+	})
+	// The same as ECS, the instances beyond the limit are silently dropped when filtering by tags
+	if len(filters) > 0 && len(matched) > MaxTagQueryResults {
+		matched = matched[:MaxTagQueryResults]
+	}
+
+	maxResults := int(lo.Ternary(tea.Int32Value(request.MaxResults) > 0, tea.Int32Value(request.MaxResults), defaultMaxResults))
+	page, nextToken, err := paginate(matched, request.NextToken, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	return &ecsclient.DescribeInstancesResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeInstancesResponseBody{
+			RequestId:  requestID(),
+			NextToken:  nextToken,
+			TotalCount: tea.Int32(int32(len(matched))),
+			Instances:  &ecsclient.DescribeInstancesResponseBodyInstances{Instance: page},
+		},
+	}, nil
+}
+
+func (e *ECSAPI) DescribeSecurityGroupsWithOptions(request *ecsclient.DescribeSecurityGroupsRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DescribeSecurityGroupsResponse, error) {
+	if err := e.DescribeSecurityGroupsError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	filters := lo.Map(request.Tag, func(t *ecsclient.DescribeSecurityGroupsRequestTag, _ int) tagFilter {
+		return tagFilter{key: tea.StringValue(t.Key), value: t.Value}
+	})
+	matched := lo.Filter(e.securityGroups, func(sg *ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroup, _ int) bool {
+		if request.SecurityGroupId != nil && tea.StringValue(request.SecurityGroupId) != tea.StringValue(sg.SecurityGroupId) {
+			return false
+		}
+		if request.SecurityGroupName != nil && tea.StringValue(request.SecurityGroupName) != tea.StringValue(sg.SecurityGroupName) {
+			return false
+		}
+		if request.VpcId != nil && tea.StringValue(request.VpcId) != tea.StringValue(sg.VpcId) {
+			return false
+		}
+		tags := map[string]string{}
+		if sg.Tags != nil {
+			tags = lo.SliceToMap(sg.Tags.Tag, func(t *ecsclient.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroupTagsTag) (string, string) {
+				return tea.StringValue(t.TagKey), tea.StringValue(t.TagValue)
+			})
+		}
+		return matchTags(filters, tags)
+	})
+
+	maxResults := int(lo.Ternary(tea.Int32Value(request.MaxResults) > 0, tea.Int32Value(request.MaxResults), defaultMaxResults))
+	page, nextToken, err := paginate(matched, request.NextToken, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	return &ecsclient.DescribeSecurityGroupsResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeSecurityGroupsResponseBody{
+			RequestId:      requestID(),
+			RegionId:       request.RegionId,
+			NextToken:      nextToken,
+			SecurityGroups: &ecsclient.DescribeSecurityGroupsResponseBodySecurityGroups{SecurityGroup: page},
+		},
+	}, nil
+}
+
+// ListTagResourcesWithOptions lists the tags of the instances, one tag resource per tag of every matching instance
+func (e *ECSAPI) DescribeSpotPriceHistoryWithOptions(request *ecsclient.DescribeSpotPriceHistoryRequest,
+	_ *util.RuntimeOptions) (*ecsclient.DescribeSpotPriceHistoryResponse, error) {
+	if err := e.DescribeSpotPriceHistoryError.Get(); err != nil {
+		return nil, err
+	}
+	if tea.StringValue(request.InstanceType) == "" || tea.StringValue(request.NetworkType) == "" {
+		return nil, fmt.Errorf("the instance type and the network type are required")
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	matched := lo.Filter(e.spotPriceHistory, func(price *ecsclient.DescribeSpotPriceHistoryResponseBodySpotPricesSpotPriceType, _ int) bool {
+		if tea.StringValue(price.InstanceType) != tea.StringValue(request.InstanceType) {
+			return false
+		}
+		if request.ZoneId != nil && tea.StringValue(price.ZoneId) != tea.StringValue(request.ZoneId) {
+			return false
+		}
+		// The timestamps share the same format, so they are ordered as strings
+		if request.StartTime != nil && tea.StringValue(price.Timestamp) < tea.StringValue(request.StartTime) {
+			return false
+		}
+		return request.EndTime == nil || tea.StringValue(price.Timestamp) <= tea.StringValue(request.EndTime)
+	})
+	return &ecsclient.DescribeSpotPriceHistoryResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.DescribeSpotPriceHistoryResponseBody{
+			RequestId:  requestID(),
+			NextOffset: tea.Int32(0),
+			SpotPrices: &ecsclient.DescribeSpotPriceHistoryResponseBodySpotPrices{
+				SpotPriceType: matched,
+			},
+		},
+	}, nil
+}
+
+func (e *ECSAPI) ListTagResourcesWithOptions(request *ecsclient.ListTagResourcesRequest,
+	_ *util.RuntimeOptions) (*ecsclient.ListTagResourcesResponse, error) {
+	if err := e.ListTagResourcesError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	// The tags of the resources in the order of their IDs
+	var resources []lo.Entry[string, map[string]string]
+	switch resourceType := tea.StringValue(request.ResourceType); resourceType {
+	case "instance":
+		resources = lo.Map(e.sortedInstances(), func(instance *ecsclient.DescribeInstancesResponseBodyInstancesInstance, _ int) lo.Entry[string, map[string]string] {
+			return lo.Entry[string, map[string]string]{Key: tea.StringValue(instance.InstanceId), Value: instanceTags(instance)}
+		})
+	case "deploymentset":
+		resources = lo.Entries(e.deploymentSetTags)
+		sort.Slice(resources, func(i, j int) bool { return resources[i].Key < resources[j].Key })
+	default:
+		return nil, fmt.Errorf("unsupported resource type %q", resourceType)
+	}
+
+	filters := lo.Map(request.Tag, func(t *ecsclient.ListTagResourcesRequestTag, _ int) tagFilter {
+		return tagFilter{key: tea.StringValue(t.Key), value: t.Value}
+	})
+	resourceIDs := tea.StringSliceValue(request.ResourceId)
+	var tagResources []*ecsclient.ListTagResourcesResponseBodyTagResourcesTagResource
+	for _, resource := range resources {
+		if len(resourceIDs) > 0 && !lo.Contains(resourceIDs, resource.Key) {
+			continue
+		}
+		tags := resource.Value
+		if !matchTags(filters, tags) {
+			continue
+		}
+		keys := lo.Keys(tags)
+		sort.Strings(keys)
+		for _, key := range keys {
+			tagResources = append(tagResources, &ecsclient.ListTagResourcesResponseBodyTagResourcesTagResource{
+				ResourceId:   tea.String(resource.Key),
+				ResourceType: request.ResourceType,
+				TagKey:       tea.String(key),
+				TagValue:     tea.String(tags[key]),
+			})
+		}
+	}
+
+	page, nextToken, err := paginate(tagResources, request.NextToken, maxTagResourcesResults)
+	if err != nil {
+		return nil, err
+	}
+	return &ecsclient.ListTagResourcesResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.ListTagResourcesResponseBody{
+			RequestId:    requestID(),
+			NextToken:    nextToken,
+			TagResources: &ecsclient.ListTagResourcesResponseBodyTagResources{TagResource: page},
+		},
+	}, nil
+}
+
+// RunInstancesWithOptions launches a single instance of the requested instance type into the zone of the vSwitch.
+// It fails with the same errors as the auto provisioning group launches report in their results, and places the
+// instance onto the requested dedicated host, onto a host of the requested cluster or onto any host accepting
+// automatic deployment when the tenancy is host.
+func (e *ECSAPI) RunInstancesWithOptions(request *ecsclient.RunInstancesRequest,
+	_ *util.RuntimeOptions) (*ecsclient.RunInstancesResponse, error) {
+	if err := e.RunInstancesError.Get(); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.runInstancesRequests = append(e.runInstancesRequests, request)
+	instanceType := tea.StringValue(request.InstanceType)
+	zoneID, err := e.vSwitchZone(tea.StringValue(request.VSwitchId))
+	if err != nil {
+		return nil, err
+	}
+	capacityType := karpv1.CapacityTypeOnDemand
+	spotStrategy := lo.Ternary(tea.StringValue(request.SpotStrategy) != "", tea.StringValue(request.SpotStrategy), "NoSpot")
+	if spotStrategy != "NoSpot" {
+		capacityType = karpv1.CapacityTypeSpot
+	}
+	var privatePoolIDs []string
+	if options := request.PrivatePoolOptions; options != nil && tea.StringValue(options.MatchCriteria) == "Target" {
+		capacityType = v1alpha1.CapacityTypeReserved
+		privatePoolIDs = []string{tea.StringValue(options.Id)}
+	}
+	noStock := &tea.SDKError{
+		Code:       tea.String(ErrCodeNoStock),
+		Message:    tea.String(fmt.Sprintf("The requested resource %s is sold out in the zone %s", instanceType, zoneID)),
+		StatusCode: tea.Int(http.StatusForbidden),
+	}
+	if lo.Contains(e.insufficientCapacityPools, CapacityPool{InstanceType: instanceType, ZoneID: zoneID, CapacityType: capacityType}) {
+		return nil, noStock
+	}
+
+	var host *ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost
+	if id := tea.StringValue(request.DedicatedHostId); id != "" {
+		var ok bool
+		if host, ok = e.dedicatedHost(id); !ok {
+			return nil, NewNotFoundError("InvalidDedicatedHostId.NotFound", fmt.Sprintf("The specified dedicated host %s does not exist.", id))
+		}
+	}
+	if host != nil || request.SchedulerOptions != nil || tea.StringValue(request.Tenancy) == "host" {
+		candidates := lo.Filter(e.dedicatedHosts, func(h *ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost, _ int) bool {
+			switch {
+			case host != nil:
+				return h == host
+			case request.SchedulerOptions != nil && request.SchedulerOptions.DedicatedHostClusterId != nil:
+				return tea.StringValue(h.DedicatedHostClusterId) == tea.StringValue(request.SchedulerOptions.DedicatedHostClusterId)
+			default:
+				return tea.StringValue(h.AutoPlacement) == "on"
+			}
+		})
+		var ok bool
+		if host, ok = lo.Find(candidates, func(h *ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost) bool {
+			return e.fitsOnDedicatedHost(h, instanceType, zoneID)
+		}); !ok {
+			return nil, noStock
+		}
+	}
+
+	privatePoolID, consumePrivatePool, ok := e.privatePool(privatePoolIDs, instanceType, zoneID)
+	if capacityType == v1alpha1.CapacityTypeReserved && !ok {
+		return nil, &tea.SDKError{
+			Code:       tea.String(ErrCodePrivatePoolNoStock),
+			Message:    tea.String(fmt.Sprintf("The private pool has no capacity left for %s in the zone %s", instanceType, zoneID)),
+			StatusCode: tea.Int(http.StatusForbidden),
+		}
+	}
+	if _, err := e.vpcAPI.consumeIPAddress(tea.StringValue(request.VSwitchId)); err != nil {
+		return nil, &tea.SDKError{
+			Code:       tea.String(ErrCodeIPNotEnough),
+			Message:    tea.String(err.Error()),
+			StatusCode: tea.Int(http.StatusForbidden),
+		}
+	}
+	if ok {
+		consumePrivatePool()
+	}
+
+	// The instance is built the same way as the ones of the auto provisioning groups
+	instanceID := randomID("i-")
+	instance := e.newInstance(instanceID, &ecsclient.CreateAutoProvisioningGroupRequest{
+		RegionId: request.RegionId,
+		LaunchConfiguration: &ecsclient.CreateAutoProvisioningGroupRequestLaunchConfiguration{
+			ImageId:                 request.ImageId,
+			SecurityGroupId:         request.SecurityGroupId,
+			SecurityGroupIds:        request.SecurityGroupIds,
+			DeploymentSetId:         request.DeploymentSetId,
+			InternetChargeType:      request.InternetChargeType,
+			InternetMaxBandwidthOut: request.InternetMaxBandwidthOut,
+			InternetMaxBandwidthIn:  request.InternetMaxBandwidthIn,
+			Tag: lo.Map(request.Tag, func(t *ecsclient.RunInstancesRequestTag, _ int) *ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationTag {
+				return &ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationTag{Key: t.Key, Value: t.Value}
+			}),
+		},
+	}, &ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig{
+		InstanceType: request.InstanceType,
+		VSwitchId:    request.VSwitchId,
+	}, zoneID, spotStrategy, privatePoolID)
+	instance.HpcClusterId = request.HpcClusterId
+	if host != nil {
+		instance.DedicatedHostAttribute = &ecsclient.DescribeInstancesResponseBodyInstancesInstanceDedicatedHostAttribute{
+			DedicatedHostId:        host.DedicatedHostId,
+			DedicatedHostClusterId: host.DedicatedHostClusterId,
+			DedicatedHostName:      host.DedicatedHostName,
+		}
+		placeOntoDedicatedHost(host, instance, 1)
+	}
+	e.instances[instanceID] = instance
+
+	return &ecsclient.RunInstancesResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.RunInstancesResponseBody{
+			RequestId:      requestID(),
+			InstanceIdSets: &ecsclient.RunInstancesResponseBodyInstanceIdSets{InstanceIdSet: []*string{tea.String(instanceID)}},
+		},
+	}, nil
+}
+
+func instanceTags(instance *ecsclient.DescribeInstancesResponseBodyInstancesInstance) map[string]string {
+	if instance.Tags == nil {
+		return map[string]string{}
+	}
+	return lo.SliceToMap(instance.Tags.Tag, func(t *ecsclient.DescribeInstancesResponseBodyInstancesInstanceTagsTag) (string, string) {
+		return tea.StringValue(t.TagKey), tea.StringValue(t.TagValue)
+	})
+}
+
+func toInstanceTags(tags map[string]string) *ecsclient.DescribeInstancesResponseBodyInstancesInstanceTags {
+	keys := lo.Keys(tags)
+	sort.Strings(keys)
+	return &ecsclient.DescribeInstancesResponseBodyInstancesInstanceTags{
+		Tag: lo.Map(keys, func(k string, _ int) *ecsclient.DescribeInstancesResponseBodyInstancesInstanceTagsTag {
+			return &ecsclient.DescribeInstancesResponseBodyInstancesInstanceTagsTag{TagKey: tea.String(k), TagValue: tea.String(tags[k])}
+		}),
+	}
+}
+
+func imageTags(image *ecsclient.DescribeImagesResponseBodyImagesImage) map[string]string {
+	if image.Tags == nil {
+		return map[string]string{}
+	}
+	return lo.SliceToMap(image.Tags.Tag, func(t *ecsclient.DescribeImagesResponseBodyImagesImageTagsTag) (string, string) {
+		return tea.StringValue(t.TagKey), tea.StringValue(t.TagValue)
+	})
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instanceevent"
+)
+
+// InstanceEventProvider is a local source of the ECS instance events
+type InstanceEventProvider struct {
+	mu        sync.RWMutex
+	events    []instanceevent.Event
+	ListError error
+}
+
+func NewInstanceEventProvider() *InstanceEventProvider {
+	return &InstanceEventProvider{}
+}
+
+func (p *InstanceEventProvider) List(_ context.Context) ([]instanceevent.Event, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.ListError != nil {
+		return nil, p.ListError
+	}
+	return append([]instanceevent.Event{}, p.events...), nil
+}
+
+// Add appends the events which will be returned by the following List calls
+func (p *InstanceEventProvider) Add(events ...instanceevent.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, events...)
+}
+
+func (p *InstanceEventProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = nil
+	p.ListError = nil
+}
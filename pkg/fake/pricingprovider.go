@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/samber/lo"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/pricing"
+)
+
+var _ pricing.Provider = (*PricingProvider)(nil)
+
+// PricingProvider serves static prices, so the instance types can be resolved without the price server
+type PricingProvider struct {
+	mu sync.RWMutex
+	// instance type -> price
+	onDemandPrices map[string]float64
+	// instance type -> zone -> price
+	spotPrices map[string]map[string]float64
+}
+
+func NewPricingProvider() *PricingProvider {
+	return &PricingProvider{
+		onDemandPrices: map[string]float64{},
+		spotPrices:     map[string]map[string]float64{},
+	}
+}
+
+func (p *PricingProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onDemandPrices = map[string]float64{}
+	p.spotPrices = map[string]map[string]float64{}
+}
+
+func (p *PricingProvider) SetOnDemandPrice(instanceType string, price float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onDemandPrices[instanceType] = price
+}
+
+func (p *PricingProvider) SetSpotPrice(instanceType, zone string, price float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.spotPrices[instanceType]; !ok {
+		p.spotPrices[instanceType] = map[string]float64{}
+	}
+	p.spotPrices[instanceType][zone] = price
+}
+
+func (p *PricingProvider) LivenessProbe(_ *http.Request) error {
+	return nil
+}
+
+func (p *PricingProvider) InstanceTypes() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return lo.Uniq(append(lo.Keys(p.onDemandPrices), lo.Keys(p.spotPrices)...))
+}
+
+func (p *PricingProvider) OnDemandPrice(instanceType string) (float64, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	price, ok := p.onDemandPrices[instanceType]
+	return price, ok
+}
+
+func (p *PricingProvider) SpotPrice(instanceType string, zone string) (float64, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	price, ok := p.spotPrices[instanceType][zone]
+	return price, ok
+}
+
+func (p *PricingProvider) UpdateOnDemandPricing(_ context.Context) error {
+	return nil
+}
+
+func (p *PricingProvider) UpdateSpotPricing(_ context.Context) error {
+	return nil
+}
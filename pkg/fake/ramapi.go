@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"sync"
+
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+)
+
+var _ sdk.RAMAPI = (*RAMAPI)(nil)
+
+// RAMAPI is an in-memory RAM, only GetRole is supported
+type RAMAPI struct {
+	mu sync.RWMutex
+	// role name -> ARN
+	roles    map[string]string
+	getRoles int
+
+	GetRoleError AtomicError
+}
+
+func NewRAMAPI() *RAMAPI {
+	r := &RAMAPI{}
+	r.Reset()
+	return r
+}
+
+// Reset removes all the roles, the call count and the injected errors
+func (r *RAMAPI) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.roles = map[string]string{}
+	r.getRoles = 0
+	r.GetRoleError.Reset()
+}
+
+// AddRoles adds the roles with the given names, their ARN is derived from the name
+func (r *RAMAPI) AddRoles(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		r.roles[name] = fmt.Sprintf("acs:ram::1234567890:role/%s", name)
+	}
+}
+
+// GetRoleCount returns the amount of received GetRole calls
+func (r *RAMAPI) GetRoleCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.getRoles
+}
+
+func (r *RAMAPI) CallApi(params *openapi.Params, request *openapi.OpenApiRequest,
+	_ *util.RuntimeOptions) (map[string]interface{}, error) {
+	if action := tea.StringValue(params.Action); action != "GetRole" {
+		return nil, fmt.Errorf("unsupported action %s", action)
+	}
+	if err := r.GetRoleError.Get(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.getRoles++
+	name := tea.StringValue(request.Query["RoleName"])
+	arn, ok := r.roles[name]
+	if !ok {
+		return nil, NewNotFoundError("EntityNotExist.Role", fmt.Sprintf("The role not exists: %s.", name))
+	}
+	return map[string]interface{}{
+		"statusCode": 200,
+		"body": map[string]interface{}{
+			"RequestId": tea.StringValue(requestID()),
+			"Role": map[string]interface{}{
+				"RoleName": name,
+				"Arn":      arn,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+const (
+	DefaultRegion = "cn-beijing"
+)
+
+// AtomicError is an error injected into a fake API, it is returned by every call until it is reset
+type AtomicError struct {
+	mu  sync.RWMutex
+	err error
+}
+
+func (e *AtomicError) Set(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.err = err
+}
+
+func (e *AtomicError) Get() error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.err
+}
+
+func (e *AtomicError) Reset() {
+	e.Set(nil)
+}
+
+// NewNotFoundError returns the error the SDK returns when the requested resource doesn't exist
+func NewNotFoundError(code, message string) error {
+	return &tea.SDKError{
+		Code:       tea.String(code),
+		Message:    tea.String(message),
+		StatusCode: tea.Int(http.StatusNotFound),
+	}
+}
+
+// NewIncorrectInstanceStatusError returns the error the SDK returns when the status of the instance doesn't
+// support the operation
+func NewIncorrectInstanceStatusError() error {
+	return &tea.SDKError{
+		Code:       tea.String("IncorrectInstanceStatus"),
+		Message:    tea.String("The current status of the resource does not support this operation."),
+		StatusCode: tea.Int(http.StatusForbidden),
+	}
+}
+
+// NewThrottlingError returns the error the SDK returns when the request is throttled
+func NewThrottlingError() error {
+	return &tea.SDKError{
+		Code:       tea.String("Throttling"),
+		Message:    tea.String("Request was denied due to request throttling."),
+		StatusCode: tea.Int(http.StatusBadRequest),
+	}
+}
+
+func randomID(prefix string) string {
+	b := make([]byte, 10)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// randomPublicIP returns a public IPv4 address of the 47.96.0.0/16 range
+func randomPublicIP() string {
+	b := make([]byte, 2)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("47.96.%d.%d", b[0], b[1])
+}
+
+func requestID() *string {
+	return tea.String(randomID("req-"))
+}
+
+// tagFilter is a tag condition of a describe request, a nil value matches any value of the key
+type tagFilter struct {
+	key   string
+	value *string
+}
+
+func matchTags(filters []tagFilter, tags map[string]string) bool {
+	for _, f := range filters {
+		v, ok := tags[f.key]
+		if !ok {
+			return false
+		}
+		if f.value != nil && *f.value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// paginate returns a page of the items and the token of the next page, the token is the offset of the next page
+func paginate[T any](items []T, nextToken *string, maxResults int) ([]T, *string, error) {
+	start := 0
+	if tea.StringValue(nextToken) != "" {
+		var err error
+		if start, err = strconv.Atoi(tea.StringValue(nextToken)); err != nil || start < 0 || start > len(items) {
+			return nil, nil, fmt.Errorf("invalid next token %q", tea.StringValue(nextToken))
+		}
+	}
+	if maxResults <= 0 {
+		maxResults = len(items)
+	}
+	end := min(start+maxResults, len(items))
+	if end == len(items) {
+		return items[start:end], nil, nil
+	}
+	return items[start:end], tea.String(strconv.Itoa(end)), nil
+}
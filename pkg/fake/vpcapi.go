@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"sync"
+
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	vpcclient "github.com/alibabacloud-go/vpc-20160428/v6/client"
+	"github.com/samber/lo"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+)
+
+var _ sdk.VPCAPI = (*VPCAPI)(nil)
+
+// VSwitch is an in-memory vSwitch
+type VSwitch struct {
+	ID                      string
+	ZoneID                  string
+	AvailableIPAddressCount int64
+	Tags                    map[string]string
+	// IPv6CIDRBlock enables IPv6 on the vSwitch when it is set
+	IPv6CIDRBlock string
+	// ShareType is Shared when the vSwitch is shared by another account
+	ShareType string
+}
+
+// VPCAPI is an in-memory VPC, the vSwitches keep track of the available IPs consumed by the launched instances
+type VPCAPI struct {
+	mu        sync.RWMutex
+	vSwitches []*VSwitch
+
+	DescribeVSwitchesError AtomicError
+}
+
+func NewVPCAPI() *VPCAPI {
+	return &VPCAPI{}
+}
+
+// Reset removes all the vSwitches and the injected errors
+func (v *VPCAPI) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.vSwitches = nil
+	v.DescribeVSwitchesError.Reset()
+}
+
+func (v *VPCAPI) AddVSwitches(vSwitches ...VSwitch) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for i := range vSwitches {
+		v.vSwitches = append(v.vSwitches, lo.ToPtr(vSwitches[i]))
+	}
+}
+
+// VSwitch returns a copy of the vSwitch with the given ID
+func (v *VPCAPI) VSwitch(id string) (VSwitch, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	vSwitch, ok := lo.Find(v.vSwitches, func(s *VSwitch) bool { return s.ID == id })
+	if !ok {
+		return VSwitch{}, false
+	}
+	return *vSwitch, true
+}
+
+// consumeIPAddress takes an IP address from the vSwitch for a launched instance and returns the zone of the vSwitch
+func (v *VPCAPI) consumeIPAddress(id string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	vSwitch, ok := lo.Find(v.vSwitches, func(s *VSwitch) bool { return s.ID == id })
+	if !ok {
+		return "", NewNotFoundError("InvalidVSwitchId.NotFound", fmt.Sprintf("The specified vSwitch %s does not exist.", id))
+	}
+	if vSwitch.AvailableIPAddressCount <= 0 {
+		return "", fmt.Errorf("the vSwitch %s has no available IP address", id)
+	}
+	vSwitch.AvailableIPAddressCount--
+	return vSwitch.ZoneID, nil
+}
+
+// releaseIPAddress gives the IP address of a deleted instance back to the vSwitch
+func (v *VPCAPI) releaseIPAddress(id string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if vSwitch, ok := lo.Find(v.vSwitches, func(s *VSwitch) bool { return s.ID == id }); ok {
+		vSwitch.AvailableIPAddressCount++
+	}
+}
+
+func (v *VPCAPI) DescribeVSwitchesWithOptions(request *vpcclient.DescribeVSwitchesRequest,
+	_ *util.RuntimeOptions) (*vpcclient.DescribeVSwitchesResponse, error) {
+	if err := v.DescribeVSwitchesError.Get(); err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	filters := lo.Map(request.Tag, func(t *vpcclient.DescribeVSwitchesRequestTag, _ int) tagFilter {
+		return tagFilter{key: tea.StringValue(t.Key), value: t.Value}
+	})
+	matched := lo.Filter(v.vSwitches, func(s *VSwitch, _ int) bool {
+		if request.VSwitchId != nil && tea.StringValue(request.VSwitchId) != s.ID {
+			return false
+		}
+		if request.ZoneId != nil && tea.StringValue(request.ZoneId) != s.ZoneID {
+			return false
+		}
+		return matchTags(filters, s.Tags)
+	})
+
+	pageSize := int(max(tea.Int32Value(request.PageSize), 1))
+	pageNumber := int(max(tea.Int32Value(request.PageNumber), 1))
+	page := lo.Slice(matched, (pageNumber-1)*pageSize, pageNumber*pageSize)
+
+	return &vpcclient.DescribeVSwitchesResponse{
+		StatusCode: tea.Int32(200),
+		Body: &vpcclient.DescribeVSwitchesResponseBody{
+			RequestId:  requestID(),
+			PageNumber: tea.Int32(int32(pageNumber)),
+			PageSize:   tea.Int32(int32(pageSize)),
+			TotalCount: tea.Int32(int32(len(matched))),
+			VSwitches: &vpcclient.DescribeVSwitchesResponseBodyVSwitches{
+				VSwitch: lo.Map(page, func(s *VSwitch, _ int) *vpcclient.DescribeVSwitchesResponseBodyVSwitchesVSwitch {
+					return &vpcclient.DescribeVSwitchesResponseBodyVSwitchesVSwitch{
+						VSwitchId:               tea.String(s.ID),
+						ZoneId:                  tea.String(s.ZoneID),
+						AvailableIpAddressCount: tea.Int64(s.AvailableIPAddressCount),
+						Status:                  tea.String("Available"),
+						EnabledIpv6:             tea.Bool(s.IPv6CIDRBlock != ""),
+						Ipv6CidrBlock:           tea.String(s.IPv6CIDRBlock),
+						ShareType:               lo.EmptyableToPtr(s.ShareType),
+						Tags: &vpcclient.DescribeVSwitchesResponseBodyVSwitchesVSwitchTags{
+							Tag: lo.MapToSlice(s.Tags, func(k, v string) *vpcclient.DescribeVSwitchesResponseBodyVSwitchesVSwitchTagsTag {
+								return &vpcclient.DescribeVSwitchesResponseBodyVSwitchesVSwitchTagsTag{Key: tea.String(k), Value: tea.String(v)}
+							}),
+						},
+					}
+				}),
+			},
+		},
+	}, nil
+}
@@ -19,7 +19,9 @@ import (
 	"os"
 
 	ackclient "github.com/alibabacloud-go/cs-20151215/v5/client"
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
 	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
 	vpc "github.com/alibabacloud-go/vpc-20160428/v6/client"
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
@@ -30,12 +32,18 @@ import (
 
 	alicache "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/cache"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/capacityreservation"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/cluster"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/dedicatedhost"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/deploymentset"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/imagefamily"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instance"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instanceevent"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/instancetype"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/pricing"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/ramrole"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/securitygroup"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/spotrisk"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/version"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/vswitch"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/client"
@@ -49,15 +57,21 @@ func init() {
 type Operator struct {
 	*operator.Operator
 
-	UnavailableOfferingsCache *alicache.UnavailableOfferings
-	InstanceProvider          instance.Provider
-	PricingProvider           pricing.Provider
-	VSwitchProvider           vswitch.Provider
-	SecurityGroupProvider     securitygroup.Provider
-	ImageProvider             imagefamily.Provider
-	ImageResolver             imagefamily.Resolver
-	VersionProvider           version.Provider
-	InstanceTypeProvider      instancetype.Provider
+	UnavailableOfferingsCache   *alicache.UnavailableOfferings
+	InstanceProvider            instance.Provider
+	PricingProvider             pricing.Provider
+	SpotRiskProvider            spotrisk.Provider
+	VSwitchProvider             vswitch.Provider
+	SecurityGroupProvider       securitygroup.Provider
+	ImageProvider               imagefamily.Provider
+	ImageResolver               imagefamily.Resolver
+	VersionProvider             version.Provider
+	InstanceTypeProvider        instancetype.Provider
+	RAMRoleProvider             ramrole.Provider
+	InstanceEventProvider       instanceevent.Provider
+	CapacityReservationProvider capacityreservation.Provider
+	DeploymentSetProvider       deploymentset.Provider
+	DedicatedHostProvider       dedicatedhost.Provider
 }
 
 func NewOperator(ctx context.Context, operator *operator.Operator) (context.Context, *Operator) {
@@ -82,6 +96,14 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		os.Exit(1)
 	}
 
+	ramClientConfig := *clientConfig
+	ramClientConfig.Endpoint = tea.String(ramrole.Endpoint)
+	ramClient, err := openapi.NewClient(&ramClientConfig)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to create RAM client")
+		os.Exit(1)
+	}
+
 	region := *ecsClient.RegionId
 
 	pricingProvider, err := pricing.NewDefaultProvider(ctx, region)
@@ -93,16 +115,22 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 	versionProvider := version.NewDefaultProvider(operator.KubernetesInterface, cache.New(alicache.KubernetesVersionTTL, alicache.DefaultCleanupInterval))
 	vSwitchProvider := vswitch.NewDefaultProvider(region, vpcClient, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval), cache.New(alicache.AvailableIPAddressTTL, alicache.DefaultCleanupInterval))
 	securityGroupProvider := securitygroup.NewDefaultProvider(region, ecsClient, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
+	capacityReservationProvider := capacityreservation.NewDefaultProvider(region, ecsClient, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
+	deploymentSetProvider := deploymentset.NewDefaultProvider(region, ecsClient, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
+	dedicatedHostProvider := dedicatedhost.NewDefaultProvider(region, ecsClient, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
 	clusterProvider := cluster.NewClusterProvider(ctx, ackClient, region)
 	imageProvider := imagefamily.NewDefaultProvider(region, ecsClient, clusterProvider, versionProvider, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
+	ramRoleProvider := ramrole.NewDefaultProvider(ramClient, cache.New(alicache.DefaultTTL, alicache.DefaultCleanupInterval))
 	imageResolver := imagefamily.NewDefaultResolver(region, ecsClient, cache.New(alicache.InstanceTypeAvailableDiskTTL, alicache.DefaultCleanupInterval))
 
+	spotRiskProvider := spotrisk.NewDefaultProvider(region, ecsClient, operator.Clock)
+
 	unavailableOfferingsCache := alicache.NewUnavailableOfferings()
 	instanceTypeProvider := instancetype.NewDefaultProvider(
 		*ecsClient.RegionId, ecsClient,
 		cache.New(alicache.InstanceTypesAndZonesTTL, alicache.DefaultCleanupInterval),
 		unavailableOfferingsCache,
-		pricingProvider, clusterProvider)
+		pricingProvider, spotRiskProvider, clusterProvider)
 
 	instanceProvider := instance.NewDefaultProvider(
 		ctx,
@@ -117,14 +145,20 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 	return ctx, &Operator{
 		Operator: operator,
 
-		UnavailableOfferingsCache: unavailableOfferingsCache,
-		InstanceProvider:          instanceProvider,
-		PricingProvider:           pricingProvider,
-		VSwitchProvider:           vSwitchProvider,
-		SecurityGroupProvider:     securityGroupProvider,
-		ImageProvider:             imageProvider,
-		ImageResolver:             imageResolver,
-		VersionProvider:           versionProvider,
-		InstanceTypeProvider:      instanceTypeProvider,
+		UnavailableOfferingsCache:   unavailableOfferingsCache,
+		InstanceProvider:            instanceProvider,
+		PricingProvider:             pricingProvider,
+		SpotRiskProvider:            spotRiskProvider,
+		VSwitchProvider:             vSwitchProvider,
+		SecurityGroupProvider:       securityGroupProvider,
+		ImageProvider:               imageProvider,
+		ImageResolver:               imageResolver,
+		VersionProvider:             versionProvider,
+		InstanceTypeProvider:        instanceTypeProvider,
+		RAMRoleProvider:             ramRoleProvider,
+		InstanceEventProvider:       instanceevent.NewDefaultProvider(region, ecsClient),
+		CapacityReservationProvider: capacityReservationProvider,
+		DeploymentSetProvider:       deploymentSetProvider,
+		DedicatedHostProvider:       dedicatedHostProvider,
 	}
 }
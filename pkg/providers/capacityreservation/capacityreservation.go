@@ -0,0 +1,244 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityreservation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/mitchellh/hashstructure/v2"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
+)
+
+const (
+	// The private pool IDs of the capacity reservations and the elasticity assurances
+	capacityReservationIDPrefix = "crp-"
+	elasticityAssuranceIDPrefix = "eap-"
+
+	// statusActive is the status of the private pools which instances can be launched into
+	statusActive = "Active"
+)
+
+type Provider interface {
+	List(context.Context, *v1alpha1.ECSNodeClass) ([]v1alpha1.CapacityReservation, error)
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	region string
+	ecsapi sdk.ECSAPI
+	cache  *cache.Cache
+	cm     *pretty.ChangeMonitor
+}
+
+func NewDefaultProvider(region string, ecsapi sdk.ECSAPI, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		region: region,
+		ecsapi: ecsapi,
+		cm:     pretty.NewChangeMonitor(),
+		cache:  cache,
+	}
+}
+
+// List returns the remaining capacity of the active capacity reservations and elasticity assurances selected by the
+// ECSNodeClass, with an entry per private pool, instance type and zone
+func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) ([]v1alpha1.CapacityReservation, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	terms := nodeClass.Spec.CapacityReservationSelectorTerms
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	hash, err := hashstructure.Hash(terms, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	if err != nil {
+		return nil, err
+	}
+	if capacityReservations, ok := p.cache.Get(fmt.Sprint(hash)); ok {
+		return append([]v1alpha1.CapacityReservation{}, capacityReservations.([]v1alpha1.CapacityReservation)...), nil
+	}
+
+	// The terms may select the same private pool more than once
+	privatePools := map[string][]v1alpha1.CapacityReservation{}
+	for _, term := range terms {
+		if term.ID == "" || strings.HasPrefix(term.ID, capacityReservationIDPrefix) {
+			if err := p.describeCapacityReservations(capacityReservationsRequest(term), func(id string, capacityReservations []v1alpha1.CapacityReservation) {
+				privatePools[id] = capacityReservations
+			}); err != nil {
+				return nil, fmt.Errorf("describing capacity reservations %+v, %w", term, err)
+			}
+		}
+		if term.ID == "" || strings.HasPrefix(term.ID, elasticityAssuranceIDPrefix) {
+			if err := p.describeElasticityAssurances(elasticityAssurancesRequest(term), func(id string, capacityReservations []v1alpha1.CapacityReservation) {
+				privatePools[id] = capacityReservations
+			}); err != nil {
+				return nil, fmt.Errorf("describing elasticity assurances %+v, %w", term, err)
+			}
+		}
+	}
+
+	capacityReservations := lo.Flatten(lo.Values(privatePools))
+	sort.Slice(capacityReservations, func(i, j int) bool {
+		a, b := capacityReservations[i], capacityReservations[j]
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		if a.InstanceType != b.InstanceType {
+			return a.InstanceType < b.InstanceType
+		}
+		return a.ZoneID < b.ZoneID
+	})
+	if p.cm.HasChanged(fmt.Sprintf("capacity-reservations/%s", nodeClass.Name), lo.Keys(privatePools)) {
+		log.FromContext(ctx).
+			WithValues("capacity-reservations", lo.Keys(privatePools)).
+			V(1).Info("discovered capacity reservations")
+	}
+	p.cache.SetDefault(fmt.Sprint(hash), capacityReservations)
+	return append([]v1alpha1.CapacityReservation{}, capacityReservations...), nil
+}
+
+func (p *DefaultProvider) describeCapacityReservations(request *ecs.DescribeCapacityReservationsRequest,
+	process func(string, []v1alpha1.CapacityReservation)) error {
+	runtime := &util.RuntimeOptions{}
+	request.RegionId = tea.String(p.region)
+	request.MaxResults = tea.Int32(100)
+	request.Status = tea.String(statusActive)
+	for {
+		output, err := p.ecsapi.DescribeCapacityReservationsWithOptions(request, runtime)
+		if err != nil {
+			return err
+		} else if output == nil || output.Body == nil {
+			return fmt.Errorf("unexpected null value was returned")
+		} else if output.Body.CapacityReservationSet == nil {
+			return alierrors.WithRequestID(tea.StringValue(output.Body.RequestId), fmt.Errorf("unexpected null value was returned"))
+		}
+
+		items := output.Body.CapacityReservationSet.CapacityReservationItem
+		for _, item := range items {
+			if item.AllocatedResources == nil {
+				continue
+			}
+			id := tea.StringValue(item.PrivatePoolOptionsId)
+			process(id, lo.Map(item.AllocatedResources.AllocatedResource, func(r *ecs.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItemAllocatedResourcesAllocatedResource, _ int) v1alpha1.CapacityReservation {
+				return v1alpha1.CapacityReservation{
+					ID:                     id,
+					Type:                   v1alpha1.CapacityReservationTypeCapacityReservation,
+					InstanceType:           tea.StringValue(r.InstanceType),
+					ZoneID:                 tea.StringValue(r.ZoneId),
+					AvailableInstanceCount: tea.Int32Value(r.AvailableAmount),
+				}
+			}))
+		}
+		request.NextToken = output.Body.NextToken
+		if request.NextToken == nil || *request.NextToken == "" || len(items) == 0 {
+			break
+		}
+	}
+	return nil
+}
+
+func (p *DefaultProvider) describeElasticityAssurances(request *ecs.DescribeElasticityAssurancesRequest,
+	process func(string, []v1alpha1.CapacityReservation)) error {
+	runtime := &util.RuntimeOptions{}
+	request.RegionId = tea.String(p.region)
+	request.MaxResults = tea.Int32(100)
+	request.Status = tea.String(statusActive)
+	for {
+		output, err := p.ecsapi.DescribeElasticityAssurancesWithOptions(request, runtime)
+		if err != nil {
+			return err
+		} else if output == nil || output.Body == nil {
+			return fmt.Errorf("unexpected null value was returned")
+		} else if output.Body.ElasticityAssuranceSet == nil {
+			return alierrors.WithRequestID(tea.StringValue(output.Body.RequestId), fmt.Errorf("unexpected null value was returned"))
+		}
+
+		items := output.Body.ElasticityAssuranceSet.ElasticityAssuranceItem
+		for _, item := range items {
+			if item.AllocatedResources == nil {
+				continue
+			}
+			id := tea.StringValue(item.PrivatePoolOptionsId)
+			process(id, lo.Map(item.AllocatedResources.AllocatedResource, func(r *ecs.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItemAllocatedResourcesAllocatedResource, _ int) v1alpha1.CapacityReservation {
+				return v1alpha1.CapacityReservation{
+					ID:                     id,
+					Type:                   v1alpha1.CapacityReservationTypeElasticityAssurance,
+					InstanceType:           tea.StringValue(r.InstanceType),
+					ZoneID:                 tea.StringValue(r.ZoneId),
+					AvailableInstanceCount: tea.Int32Value(r.AvailableAmount),
+				}
+			}))
+		}
+		request.NextToken = output.Body.NextToken
+		if request.NextToken == nil || *request.NextToken == "" || len(items) == 0 {
+			break
+		}
+	}
+	return nil
+}
+
+func capacityReservationsRequest(term v1alpha1.CapacityReservationSelectorTerm) *ecs.DescribeCapacityReservationsRequest {
+	request := &ecs.DescribeCapacityReservationsRequest{}
+	if term.ID != "" {
+		request.PrivatePoolOptions = &ecs.DescribeCapacityReservationsRequestPrivatePoolOptions{Ids: privatePoolIDs(term.ID)}
+		return request
+	}
+	for k, v := range term.Tags {
+		tag := &ecs.DescribeCapacityReservationsRequestTag{Key: tea.String(k)}
+		if v != "*" {
+			tag.Value = tea.String(v)
+		}
+		request.Tag = append(request.Tag, tag)
+	}
+	return request
+}
+
+func elasticityAssurancesRequest(term v1alpha1.CapacityReservationSelectorTerm) *ecs.DescribeElasticityAssurancesRequest {
+	request := &ecs.DescribeElasticityAssurancesRequest{}
+	if term.ID != "" {
+		request.PrivatePoolOptions = &ecs.DescribeElasticityAssurancesRequestPrivatePoolOptions{Ids: privatePoolIDs(term.ID)}
+		return request
+	}
+	for k, v := range term.Tags {
+		tag := &ecs.DescribeElasticityAssurancesRequestTag{Key: tea.String(k)}
+		if v != "*" {
+			tag.Value = tea.String(v)
+		}
+		request.Tag = append(request.Tag, tag)
+	}
+	return request
+}
+
+// privatePoolIDs returns the private pool ID as the JSON array the describe APIs accept
+func privatePoolIDs(id string) *string {
+	ids, _ := json.Marshal([]string{id})
+	return tea.String(string(ids))
+}
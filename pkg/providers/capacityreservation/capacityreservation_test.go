@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityreservation
+
+import (
+	"context"
+	"testing"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+)
+
+func newTestCapacityReservation(id, status string, tags map[string]string) *ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItem {
+	item := &ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItem{
+		PrivatePoolOptionsId: tea.String(id),
+		Status:               tea.String(status),
+		AllocatedResources: &ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItemAllocatedResources{
+			AllocatedResource: []*ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItemAllocatedResourcesAllocatedResource{
+				{InstanceType: tea.String("ecs.g7.large"), ZoneId: tea.String("cn-hangzhou-i"), TotalAmount: tea.Int32(3), UsedAmount: tea.Int32(1), AvailableAmount: tea.Int32(2)},
+			},
+		},
+		Tags: &ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItemTags{},
+	}
+	for k, v := range tags {
+		item.Tags.Tag = append(item.Tags.Tag, &ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItemTagsTag{
+			TagKey: tea.String(k), TagValue: tea.String(v),
+		})
+	}
+	return item
+}
+
+func TestDefaultProvider_List(t *testing.T) {
+	ecsAPI := fake.NewECSAPI(nil)
+	ecsAPI.AddCapacityReservations(
+		newTestCapacityReservation("crp-a", "Active", map[string]string{"team": "a"}),
+		newTestCapacityReservation("crp-b", "Active", map[string]string{"team": "b"}),
+		newTestCapacityReservation("crp-released", "Released", map[string]string{"team": "a"}),
+	)
+	ecsAPI.AddElasticityAssurances(&ecsclient.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItem{
+		PrivatePoolOptionsId: tea.String("eap-a"),
+		Status:               tea.String("Active"),
+		AllocatedResources: &ecsclient.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItemAllocatedResources{
+			AllocatedResource: []*ecsclient.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItemAllocatedResourcesAllocatedResource{
+				{InstanceType: tea.String("ecs.g7.xlarge"), ZoneId: tea.String("cn-hangzhou-j"), TotalAmount: tea.Int32(1), AvailableAmount: tea.Int32(1)},
+			},
+		},
+		Tags: &ecsclient.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItemTags{
+			Tag: []*ecsclient.DescribeElasticityAssurancesResponseBodyElasticityAssuranceSetElasticityAssuranceItemTagsTag{
+				{TagKey: tea.String("team"), TagValue: tea.String("a")},
+			},
+		},
+	})
+	crA := v1alpha1.CapacityReservation{ID: "crp-a", Type: v1alpha1.CapacityReservationTypeCapacityReservation,
+		InstanceType: "ecs.g7.large", ZoneID: "cn-hangzhou-i", AvailableInstanceCount: 2}
+	crB := v1alpha1.CapacityReservation{ID: "crp-b", Type: v1alpha1.CapacityReservationTypeCapacityReservation,
+		InstanceType: "ecs.g7.large", ZoneID: "cn-hangzhou-i", AvailableInstanceCount: 2}
+	eaA := v1alpha1.CapacityReservation{ID: "eap-a", Type: v1alpha1.CapacityReservationTypeElasticityAssurance,
+		InstanceType: "ecs.g7.xlarge", ZoneID: "cn-hangzhou-j", AvailableInstanceCount: 1}
+
+	tests := []struct {
+		name  string
+		terms []v1alpha1.CapacityReservationSelectorTerm
+		want  []v1alpha1.CapacityReservation
+	}{
+		{
+			name: "no terms",
+		},
+		{
+			name:  "capacity reservation id",
+			terms: []v1alpha1.CapacityReservationSelectorTerm{{ID: "crp-b"}},
+			want:  []v1alpha1.CapacityReservation{crB},
+		},
+		{
+			name:  "elasticity assurance id",
+			terms: []v1alpha1.CapacityReservationSelectorTerm{{ID: "eap-a"}},
+			want:  []v1alpha1.CapacityReservation{eaA},
+		},
+		{
+			name:  "released",
+			terms: []v1alpha1.CapacityReservationSelectorTerm{{ID: "crp-released"}},
+			want:  []v1alpha1.CapacityReservation{},
+		},
+		{
+			name:  "tags",
+			terms: []v1alpha1.CapacityReservationSelectorTerm{{Tags: map[string]string{"team": "a"}}},
+			want:  []v1alpha1.CapacityReservation{crA, eaA},
+		},
+		{
+			name:  "wildcard tag and overlapping id",
+			terms: []v1alpha1.CapacityReservationSelectorTerm{{Tags: map[string]string{"team": "*"}}, {ID: "crp-a"}},
+			want:  []v1alpha1.CapacityReservation{crA, crB, eaA},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewDefaultProvider(fake.DefaultRegion, ecsAPI, cache.New(cache.NoExpiration, cache.NoExpiration))
+			nodeClass := &v1alpha1.ECSNodeClass{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+				Spec:       v1alpha1.ECSNodeClassSpec{CapacityReservationSelectorTerms: tt.terms},
+			}
+
+			capacityReservations, err := provider.List(context.Background(), nodeClass)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, capacityReservations)
+		})
+	}
+}
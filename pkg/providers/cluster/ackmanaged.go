@@ -34,8 +34,10 @@ import (
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
 )
 
@@ -47,14 +49,14 @@ const (
 type ACKManaged struct {
 	clusterID string
 	region    string
-	ackClient *ackclient.Client
+	ackClient sdk.ACKAPI
 
 	muClusterCNI sync.RWMutex
 	clusterCNI   string
 	cache        *cache.Cache
 }
 
-func NewACKManaged(clusterID string, region string, ackClient *ackclient.Client, cache *cache.Cache) *ACKManaged {
+func NewACKManaged(clusterID string, region string, ackClient sdk.ACKAPI, cache *cache.Cache) *ACKManaged {
 	return &ACKManaged{
 		clusterID: clusterID,
 		region:    region,
@@ -143,7 +145,9 @@ func (a *ACKManaged) UserData(ctx context.Context,
 	taints []corev1.Taint,
 	kubeletCfg *v1alpha1.KubeletConfiguration,
 	userData *string,
-	formatDataDisk bool) (string, error) {
+	formatDataDisk bool,
+	instanceStorePolicy *string,
+	ipFamilies []corev1.IPFamily) (string, error) {
 
 	attach, err := a.getClusterAttachScripts(formatDataDisk, ctx)
 	if err != nil {
@@ -152,6 +156,14 @@ func (a *ACKManaged) UserData(ctx context.Context,
 	ackScript := a.ackBootstrap(attach, labels, taints, kubeletCfg)
 	cloudInit := NewCloudInit()
 
+	// The local disks are mounted before the node joins the cluster, so containerd and kubelet start on them
+	if err := cloudInit.Merge(instanceStoreScript(instanceStorePolicy)); err != nil {
+		return "", err
+	}
+	// kubelet reads the node IPs when it is started by the bootstrap
+	if err := cloudInit.Merge(nodeIPScript(ipFamilies)); err != nil {
+		return "", err
+	}
 	if err := cloudInit.Merge(&ackScript); err != nil {
 		return "", err
 	}
@@ -282,8 +294,11 @@ func (a *ACKManaged) ackBootstrap(respStr string, labels map[string]string, tain
 
 func (a *ACKManaged) formatLabels(labels map[string]string) string {
 	labelsFormatted := fmt.Sprintf("%s,ack.aliyun.com=%s", defaultNodeLabel, a.clusterID)
-	for key, value := range labels {
-		labelsFormatted = fmt.Sprintf("%s,%s=%s", labelsFormatted, key, value)
+	// Keep the user data stable for the same labels, so the launches of the same nodepool can be batched
+	keys := lo.Keys(labels)
+	sort.Strings(keys)
+	for _, key := range keys {
+		labelsFormatted = fmt.Sprintf("%s,%s=%s", labelsFormatted, key, labels[key])
 	}
 	return labelsFormatted
 }
@@ -299,15 +314,45 @@ type NodeConfig struct {
 }
 
 type ACKKubeletConfig struct {
-	MaxPods *int32 `json:"maxPods,omitempty"`
+	ClusterDNS                  []string          `json:"clusterDNS,omitempty"`
+	MaxPods                     *int32            `json:"maxPods,omitempty"`
+	PodsPerCore                 *int32            `json:"podsPerCore,omitempty"`
+	SystemReserved              map[string]string `json:"systemReserved,omitempty"`
+	KubeReserved                map[string]string `json:"kubeReserved,omitempty"`
+	EvictionHard                map[string]string `json:"evictionHard,omitempty"`
+	EvictionSoft                map[string]string `json:"evictionSoft,omitempty"`
+	EvictionSoftGracePeriod     map[string]string `json:"evictionSoftGracePeriod,omitempty"`
+	EvictionMaxPodGracePeriod   *int32            `json:"evictionMaxPodGracePeriod,omitempty"`
+	ImageGCHighThresholdPercent *int32            `json:"imageGCHighThresholdPercent,omitempty"`
+	ImageGCLowThresholdPercent  *int32            `json:"imageGCLowThresholdPercent,omitempty"`
+	CPUCFSQuota                 *bool             `json:"cpuCFSQuota,omitempty"`
 }
 
 func convertNodeClassKubeletConfigToACKNodeConfig(kubeletCfg *v1alpha1.KubeletConfiguration) string {
+	if kubeletCfg == nil {
+		kubeletCfg = &v1alpha1.KubeletConfiguration{}
+	}
 	cfg := &NodeConfig{
 		KubeletConfig: &ACKKubeletConfig{
-			MaxPods: kubeletCfg.MaxPods,
+			ClusterDNS:                  kubeletCfg.ClusterDNS,
+			MaxPods:                     kubeletCfg.MaxPods,
+			PodsPerCore:                 kubeletCfg.PodsPerCore,
+			SystemReserved:              kubeletCfg.SystemReserved,
+			KubeReserved:                kubeletCfg.KubeReserved,
+			EvictionHard:                kubeletCfg.EvictionHard,
+			EvictionSoft:                kubeletCfg.EvictionSoft,
+			EvictionMaxPodGracePeriod:   kubeletCfg.EvictionMaxPodGracePeriod,
+			ImageGCHighThresholdPercent: kubeletCfg.ImageGCHighThresholdPercent,
+			ImageGCLowThresholdPercent:  kubeletCfg.ImageGCLowThresholdPercent,
+			CPUCFSQuota:                 kubeletCfg.CPUCFSQuota,
 		},
 	}
+	// Kubelet expects the grace periods as duration strings, e.g. "1m30s"
+	if len(kubeletCfg.EvictionSoftGracePeriod) != 0 {
+		cfg.KubeletConfig.EvictionSoftGracePeriod = lo.MapValues(kubeletCfg.EvictionSoftGracePeriod, func(d metav1.Duration, _ string) string {
+			return d.Duration.String()
+		})
+	}
 
 	data, err := json.Marshal(cfg)
 	if err != nil {
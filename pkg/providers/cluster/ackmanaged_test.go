@@ -17,10 +17,13 @@ limitations under the License.
 package cluster
 
 import (
+	"encoding/base64"
 	"testing"
+	"time"
 
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
 )
@@ -32,3 +35,81 @@ func Test_convertNodeClassKubeletConfigToACKNodeConfig(t *testing.T) {
 	d := convertNodeClassKubeletConfigToACKNodeConfig(kubeletCfg)
 	assert.Equal(t, "eyJrdWJlbGV0X2NvbmZpZyI6eyJtYXhQb2RzIjoxMTB9fQ==", d)
 }
+
+func Test_convertNodeClassKubeletConfigToACKNodeConfigFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		kubeletCfg *v1alpha1.KubeletConfiguration
+		expected   string
+	}{
+		{
+			name:       "nil kubelet configuration",
+			kubeletCfg: nil,
+			expected:   `{"kubelet_config":{}}`,
+		},
+		{
+			name:       "empty kubelet configuration",
+			kubeletCfg: &v1alpha1.KubeletConfiguration{},
+			expected:   `{"kubelet_config":{}}`,
+		},
+		{
+			name:       "clusterDNS",
+			kubeletCfg: &v1alpha1.KubeletConfiguration{ClusterDNS: []string{"10.0.0.10", "169.254.20.10"}},
+			expected:   `{"kubelet_config":{"clusterDNS":["10.0.0.10","169.254.20.10"]}}`,
+		},
+		{
+			name:       "podsPerCore",
+			kubeletCfg: &v1alpha1.KubeletConfiguration{PodsPerCore: tea.Int32(10)},
+			expected:   `{"kubelet_config":{"podsPerCore":10}}`,
+		},
+		{
+			name:       "systemReserved",
+			kubeletCfg: &v1alpha1.KubeletConfiguration{SystemReserved: map[string]string{"cpu": "100m", "memory": "512Mi"}},
+			expected:   `{"kubelet_config":{"systemReserved":{"cpu":"100m","memory":"512Mi"}}}`,
+		},
+		{
+			name:       "kubeReserved",
+			kubeletCfg: &v1alpha1.KubeletConfiguration{KubeReserved: map[string]string{"cpu": "200m", "ephemeral-storage": "1Gi"}},
+			expected:   `{"kubelet_config":{"kubeReserved":{"cpu":"200m","ephemeral-storage":"1Gi"}}}`,
+		},
+		{
+			name:       "evictionHard",
+			kubeletCfg: &v1alpha1.KubeletConfiguration{EvictionHard: map[string]string{"memory.available": "5%"}},
+			expected:   `{"kubelet_config":{"evictionHard":{"memory.available":"5%"}}}`,
+		},
+		{
+			name: "evictionSoft and evictionSoftGracePeriod",
+			kubeletCfg: &v1alpha1.KubeletConfiguration{
+				EvictionSoft:            map[string]string{"nodefs.available": "10%"},
+				EvictionSoftGracePeriod: map[string]metav1.Duration{"nodefs.available": {Duration: 90 * time.Second}},
+			},
+			expected: `{"kubelet_config":{"evictionSoft":{"nodefs.available":"10%"},"evictionSoftGracePeriod":{"nodefs.available":"1m30s"}}}`,
+		},
+		{
+			name:       "evictionMaxPodGracePeriod",
+			kubeletCfg: &v1alpha1.KubeletConfiguration{EvictionMaxPodGracePeriod: tea.Int32(60)},
+			expected:   `{"kubelet_config":{"evictionMaxPodGracePeriod":60}}`,
+		},
+		{
+			name: "imageGC thresholds",
+			kubeletCfg: &v1alpha1.KubeletConfiguration{
+				ImageGCHighThresholdPercent: tea.Int32(85),
+				ImageGCLowThresholdPercent:  tea.Int32(80),
+			},
+			expected: `{"kubelet_config":{"imageGCHighThresholdPercent":85,"imageGCLowThresholdPercent":80}}`,
+		},
+		{
+			name:       "cpuCFSQuota",
+			kubeletCfg: &v1alpha1.KubeletConfiguration{CPUCFSQuota: tea.Bool(false)},
+			expected:   `{"kubelet_config":{"cpuCFSQuota":false}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := base64.StdEncoding.DecodeString(convertNodeClassKubeletConfigToACKNodeConfig(tt.kubeletCfg))
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
@@ -34,8 +34,22 @@ func NewCustom() *Custom {
 	return &Custom{}
 }
 
-func (c *Custom) UserData(ctx context.Context, labels map[string]string, taints []corev1.Taint, configuration *v1alpha1.KubeletConfiguration, userData *string, formatDataDisk bool) (string, error) {
-	return base64.StdEncoding.EncodeToString([]byte(lo.FromPtr(userData))), nil
+func (c *Custom) UserData(ctx context.Context, labels map[string]string, taints []corev1.Taint, configuration *v1alpha1.KubeletConfiguration, userData *string, formatDataDisk bool, instanceStorePolicy *string, ipFamilies []corev1.IPFamily) (string, error) {
+	scripts := lo.Compact([]*string{instanceStoreScript(instanceStorePolicy), nodeIPScript(ipFamilies)})
+	if len(scripts) == 0 {
+		return base64.StdEncoding.EncodeToString([]byte(lo.FromPtr(userData))), nil
+	}
+
+	cloudInit := NewCloudInit()
+	for _, script := range scripts {
+		if err := cloudInit.Merge(script); err != nil {
+			return "", err
+		}
+	}
+	if err := cloudInit.Merge(userData); err != nil {
+		return "", err
+	}
+	return cloudInit.Script()
 }
 
 func (c *Custom) GetClusterCNI(ctx context.Context) (string, error) {
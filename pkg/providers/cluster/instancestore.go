@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"github.com/samber/lo"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+)
+
+// instanceStoreRAID0Script formats the local disks as a RAID0 array, or as a single disk when there is only one of
+// them, and mounts it for containerd and kubelet. The cloud disks are listed by their serial number in the instance
+// metadata, the remaining unused disks are the local disks.
+const instanceStoreRAID0Script = `#!/bin/bash
+set -o errexit -o nounset -o pipefail
+
+mount_point=/mnt/k8s-disks
+if mountpoint -q "${mount_point}"; then
+  exit 0
+fi
+
+cloud_disks=$(curl -sf http://100.100.100.200/latest/meta-data/disks/ || true)
+local_disks=()
+for disk in $(lsblk --nodeps --noheadings --output NAME,TYPE | awk '$2 == "disk" {print $1}'); do
+  serial=$(lsblk --nodeps --noheadings --output SERIAL "/dev/${disk}" | tr -d '[:space:]')
+  if [ -n "${serial}" ] && echo "${cloud_disks}" | grep -q "^${serial}/\?$"; then
+    continue
+  fi
+  # Skip the disks which are partitioned, formatted or mounted
+  if [ "$(lsblk --noheadings --output NAME "/dev/${disk}" | wc -l)" -gt 1 ] ||
+    [ -n "$(lsblk --nodeps --noheadings --output FSTYPE,MOUNTPOINT "/dev/${disk}" | tr -d '[:space:]')" ]; then
+    continue
+  fi
+  local_disks+=("/dev/${disk}")
+done
+
+if [ "${#local_disks[@]}" -eq 0 ]; then
+  exit 0
+elif [ "${#local_disks[@]}" -eq 1 ]; then
+  device="${local_disks[0]}"
+else
+  device=/dev/md/kubernetes
+  mdadm --create "${device}" --level=0 --raid-devices="${#local_disks[@]}" --force --run "${local_disks[@]}"
+  mdadm --detail --scan >> /etc/mdadm.conf
+fi
+
+mkfs.ext4 -F "${device}"
+mkdir -p "${mount_point}"
+echo "UUID=$(blkid -s UUID -o value "${device}") ${mount_point} ext4 defaults,nofail 0 2" >> /etc/fstab
+mount "${mount_point}"
+
+for dir in containerd kubelet; do
+  mkdir -p "${mount_point}/${dir}" "/var/lib/${dir}"
+  echo "${mount_point}/${dir} /var/lib/${dir} none bind,nofail 0 0" >> /etc/fstab
+  mount "/var/lib/${dir}"
+done
+`
+
+// instanceStoreScript returns the script preparing the local disks of the instance for the instance store policy,
+// nil is returned when the local disks are left alone
+func instanceStoreScript(instanceStorePolicy *string) *string {
+	if lo.FromPtr(instanceStorePolicy) != v1alpha1.InstanceStorePolicyRAID0 {
+		return nil
+	}
+	return lo.ToPtr(instanceStoreRAID0Script)
+}
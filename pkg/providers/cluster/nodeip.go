@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// dualStackNodeIPScript makes kubelet register both the IPv4 and the IPv6 address of the primary network interface
+// as the node IPs. The addresses are read from the instance metadata and passed with the extra arguments of kubelet.
+const dualStackNodeIPScript = `#!/bin/bash
+set -o errexit -o nounset -o pipefail
+
+metadata=http://100.100.100.200/latest/meta-data
+mac=$(curl -sf "${metadata}/mac")
+ipv4=$(curl -sf "${metadata}/private-ipv4")
+ipv6=$(curl -sf "${metadata}/network/interfaces/macs/${mac}/ipv6s" | tr -d '[]" ' | cut -d, -f1)
+if [ -z "${ipv6}" ]; then
+  echo "no IPv6 address is assigned to ${mac}, registering an IPv4 node" >&2
+  exit 0
+fi
+
+mkdir -p /etc/sysconfig
+touch /etc/sysconfig/kubelet
+if grep -q '^KUBELET_EXTRA_ARGS=' /etc/sysconfig/kubelet; then
+  sed -i -E "s|^KUBELET_EXTRA_ARGS=\"?([^\"]*)\"?$|KUBELET_EXTRA_ARGS=\"\1 --node-ip=${ipv4},${ipv6}\"|" /etc/sysconfig/kubelet
+else
+  echo "KUBELET_EXTRA_ARGS=\"--node-ip=${ipv4},${ipv6}\"" >> /etc/sysconfig/kubelet
+fi
+`
+
+// nodeIPScript returns the script configuring the node IPs of kubelet for the IP families, nil is returned for the
+// IPv4 only nodes which keep the default node IP
+func nodeIPScript(ipFamilies []corev1.IPFamily) *string {
+	if !lo.Contains(ipFamilies, corev1.IPv6Protocol) {
+		return nil
+	}
+	return lo.ToPtr(dualStackNodeIPScript)
+}
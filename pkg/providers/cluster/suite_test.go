@@ -27,6 +27,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
 	. "sigs.k8s.io/karpenter/pkg/utils/testing"
 )
 
@@ -317,3 +318,38 @@ echo 'world'
 		})
 	})
 })
+
+var _ = Describe("Custom", func() {
+	It("should keep the userdata when the instance store policy is unset", func() {
+		userData, err := NewCustom().UserData(ctx, nil, nil, nil, lo.ToPtr("echo 'hello'"), false, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		result, err := base64.StdEncoding.DecodeString(userData)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(result)).To(Equal("echo 'hello'"))
+	})
+
+	It("should prepare the local disks before the userdata with the RAID0 instance store policy", func() {
+		userData, err := NewCustom().UserData(ctx, nil, nil, nil, lo.ToPtr("echo 'hello'"), false, lo.ToPtr("RAID0"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		result, err := base64.StdEncoding.DecodeString(userData)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(result)).To(ContainSubstring("mdadm --create"))
+		Expect(strings.Index(string(result), "mdadm --create")).To(BeNumerically("<", strings.Index(string(result), "echo 'hello'")))
+	})
+
+	It("should configure the dual-stack node IPs before the userdata with the IPv6 family", func() {
+		userData, err := NewCustom().UserData(ctx, nil, nil, nil, lo.ToPtr("echo 'hello'"), false, nil,
+			[]corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol})
+		Expect(err).NotTo(HaveOccurred())
+		result, err := base64.StdEncoding.DecodeString(userData)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(result)).To(ContainSubstring("--node-ip=${ipv4},${ipv6}"))
+		Expect(strings.Index(string(result), "--node-ip")).To(BeNumerically("<", strings.Index(string(result), "echo 'hello'")))
+
+		userData, err = NewCustom().UserData(ctx, nil, nil, nil, lo.ToPtr("echo 'hello'"), false, nil, []corev1.IPFamily{corev1.IPv4Protocol})
+		Expect(err).NotTo(HaveOccurred())
+		result, err = base64.StdEncoding.DecodeString(userData)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(result)).To(Equal("echo 'hello'"))
+	})
+})
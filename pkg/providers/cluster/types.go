@@ -20,10 +20,10 @@ import (
 	"context"
 	"net/http"
 
-	ackclient "github.com/alibabacloud-go/cs-20151215/v5/client"
 	"github.com/patrickmn/go-cache"
 	corev1 "k8s.io/api/core/v1"
 
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
 	alicache "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/cache"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
@@ -53,14 +53,14 @@ type Image struct {
 // Provider can be implemented to generate userdata
 type Provider interface {
 	ClusterType() string
-	UserData(context.Context, map[string]string, []corev1.Taint, *v1alpha1.KubeletConfiguration, *string, bool) (string, error)
+	UserData(context.Context, map[string]string, []corev1.Taint, *v1alpha1.KubeletConfiguration, *string, bool, *string, []corev1.IPFamily) (string, error)
 	GetClusterCNI(context.Context) (string, error)
 	LivenessProbe(*http.Request) error
 	GetSupportedImages(string) ([]Image, error)
 	FeatureFlags() FeatureFlags
 }
 
-func NewClusterProvider(ctx context.Context, ackClient *ackclient.Client, region string) Provider {
+func NewClusterProvider(ctx context.Context, ackClient sdk.ACKAPI, region string) Provider {
 	clusterID := options.FromContext(ctx).ClusterID
 	if options.FromContext(ctx).ClusterType == ackManagedClusterType {
 		return NewACKManaged(clusterID, region, ackClient, cache.New(alicache.ClusterAttachScriptTTL, alicache.DefaultCleanupInterval))
@@ -0,0 +1,245 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dedicatedhost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/mitchellh/hashstructure/v2"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
+)
+
+const (
+	// statusAvailable is the status of the dedicated hosts instances can be launched onto
+	statusAvailable = "Available"
+	maxResults      = 100
+	pageSize        = 50
+)
+
+type Provider interface {
+	List(context.Context, *v1alpha1.ECSNodeClass) ([]v1alpha1.DedicatedHost, error)
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	region string
+	ecsapi sdk.ECSAPI
+	cache  *cache.Cache
+	cm     *pretty.ChangeMonitor
+}
+
+func NewDefaultProvider(region string, ecsapi sdk.ECSAPI, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		region: region,
+		ecsapi: ecsapi,
+		cm:     pretty.NewChangeMonitor(),
+		cache:  cache,
+	}
+}
+
+// List returns the available dedicated hosts the instances of the ECSNodeClass can be launched onto, sorted by ID.
+// Without a dedicated host nor a dedicated host cluster selected, they are the hosts which accept automatic deployment.
+func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) ([]v1alpha1.DedicatedHost, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	placement := nodeClass.Spec.Placement
+	if !placement.OnDedicatedHosts() {
+		return nil, nil
+	}
+	hash, err := hashstructure.Hash(placement, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	if err != nil {
+		return nil, err
+	}
+	if hosts, ok := p.cache.Get(fmt.Sprint(hash)); ok {
+		return deepCopy(hosts.([]v1alpha1.DedicatedHost)), nil
+	}
+
+	var hosts []*ecs.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost
+	switch {
+	case placement.DedicatedHostID != "":
+		ids, _ := json.Marshal([]string{placement.DedicatedHostID})
+		hosts, err = p.describeDedicatedHosts(&ecs.DescribeDedicatedHostsRequest{DedicatedHostIds: tea.String(string(ids))})
+	case len(placement.DedicatedHostClusterSelectorTerms) != 0:
+		var clusterIDs []string
+		if clusterIDs, err = p.dedicatedHostClusterIDs(placement.DedicatedHostClusterSelectorTerms); err != nil {
+			return nil, err
+		}
+		for _, clusterID := range clusterIDs {
+			clusterHosts, err := p.describeDedicatedHosts(&ecs.DescribeDedicatedHostsRequest{DedicatedHostClusterId: tea.String(clusterID)})
+			if err != nil {
+				return nil, fmt.Errorf("describing dedicated hosts of %s, %w", clusterID, err)
+			}
+			hosts = append(hosts, clusterHosts...)
+		}
+	default:
+		hosts, err = p.describeDedicatedHosts(&ecs.DescribeDedicatedHostsRequest{})
+		hosts = lo.Filter(hosts, func(host *ecs.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost, _ int) bool {
+			return tea.StringValue(host.AutoPlacement) == "on"
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("describing dedicated hosts, %w", err)
+	}
+
+	dedicatedHosts := lo.FilterMap(lo.UniqBy(hosts, func(host *ecs.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost) string {
+		return tea.StringValue(host.DedicatedHostId)
+	}), func(host *ecs.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost, _ int) (v1alpha1.DedicatedHost, bool) {
+		return toDedicatedHost(host), tea.StringValue(host.Status) == statusAvailable
+	})
+	sort.Slice(dedicatedHosts, func(i, j int) bool { return dedicatedHosts[i].ID < dedicatedHosts[j].ID })
+
+	if p.cm.HasChanged(fmt.Sprintf("dedicated-hosts/%s", nodeClass.Name), lo.Map(dedicatedHosts, func(host v1alpha1.DedicatedHost, _ int) string {
+		return host.ID
+	})) {
+		log.FromContext(ctx).
+			WithValues("dedicated-hosts", lo.Map(dedicatedHosts, func(host v1alpha1.DedicatedHost, _ int) string { return host.ID })).
+			V(1).Info("discovered dedicated hosts")
+	}
+	p.cache.SetDefault(fmt.Sprint(hash), dedicatedHosts)
+	return deepCopy(dedicatedHosts), nil
+}
+
+// Fits returns true when an instance of the instance type with the vCPUs and the memory in GiB can still be launched
+// onto the dedicated host
+func Fits(host v1alpha1.DedicatedHost, instanceType string, vCPUs int32, memoryGiB float64) bool {
+	return lo.Contains(host.InstanceTypes, instanceType) && host.AvailableVCPUs >= vCPUs &&
+		host.AvailableMemory.AsApproximateFloat64() >= memoryGiB*1024*1024*1024
+}
+
+// Zones returns the zones of the dedicated hosts the instance type can still be launched onto
+func Zones(hosts []v1alpha1.DedicatedHost, instanceType string, vCPUs int32, memoryGiB float64) sets.Set[string] {
+	return sets.New(lo.FilterMap(hosts, func(host v1alpha1.DedicatedHost, _ int) (string, bool) {
+		return host.ZoneID, Fits(host, instanceType, vCPUs, memoryGiB)
+	})...)
+}
+
+// dedicatedHostClusterIDs returns the IDs of the dedicated host clusters selected by the terms
+func (p *DefaultProvider) dedicatedHostClusterIDs(terms []v1alpha1.DedicatedHostClusterSelectorTerm) ([]string, error) {
+	ids := sets.New[string]()
+	for _, term := range terms {
+		if term.ID != "" {
+			ids.Insert(term.ID)
+			continue
+		}
+		request := &ecs.DescribeDedicatedHostClustersRequest{}
+		for k, v := range term.Tags {
+			tag := &ecs.DescribeDedicatedHostClustersRequestTag{Key: tea.String(k)}
+			if v != "*" {
+				tag.Value = tea.String(v)
+			}
+			request.Tag = append(request.Tag, tag)
+		}
+		clusters, err := p.describeDedicatedHostClusters(request)
+		if err != nil {
+			return nil, fmt.Errorf("describing dedicated host clusters, %w", err)
+		}
+		for _, cluster := range clusters {
+			ids.Insert(tea.StringValue(cluster.DedicatedHostClusterId))
+		}
+	}
+	return sets.List(ids), nil
+}
+
+func (p *DefaultProvider) describeDedicatedHosts(request *ecs.DescribeDedicatedHostsRequest) ([]*ecs.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost, error) {
+	runtime := &util.RuntimeOptions{}
+	request.RegionId = tea.String(p.region)
+	request.MaxResults = tea.Int32(maxResults)
+
+	var hosts []*ecs.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost
+	for {
+		output, err := p.ecsapi.DescribeDedicatedHostsWithOptions(request, runtime)
+		if err != nil {
+			return nil, err
+		} else if output == nil || output.Body == nil {
+			return nil, fmt.Errorf("unexpected null value was returned")
+		} else if output.Body.DedicatedHosts == nil {
+			return nil, alierrors.WithRequestID(tea.StringValue(output.Body.RequestId), fmt.Errorf("unexpected null value was returned"))
+		}
+
+		hosts = append(hosts, output.Body.DedicatedHosts.DedicatedHost...)
+		if output.Body.NextToken == nil || *output.Body.NextToken == "" {
+			break
+		}
+		request.NextToken = output.Body.NextToken
+	}
+	return hosts, nil
+}
+
+func (p *DefaultProvider) describeDedicatedHostClusters(request *ecs.DescribeDedicatedHostClustersRequest) (
+	[]*ecs.DescribeDedicatedHostClustersResponseBodyDedicatedHostClustersDedicatedHostCluster, error) {
+	runtime := &util.RuntimeOptions{}
+	request.RegionId = tea.String(p.region)
+	request.PageSize = tea.Int32(pageSize)
+
+	var clusters []*ecs.DescribeDedicatedHostClustersResponseBodyDedicatedHostClustersDedicatedHostCluster
+	for pageNumber := int32(1); pageNumber < 100; pageNumber++ {
+		request.PageNumber = tea.Int32(pageNumber)
+		output, err := p.ecsapi.DescribeDedicatedHostClustersWithOptions(request, runtime)
+		if err != nil {
+			return nil, err
+		} else if output == nil || output.Body == nil {
+			return nil, fmt.Errorf("unexpected null value was returned")
+		} else if output.Body.TotalCount == nil || output.Body.DedicatedHostClusters == nil {
+			return nil, alierrors.WithRequestID(tea.StringValue(output.Body.RequestId), fmt.Errorf("unexpected null value was returned"))
+		}
+
+		clusters = append(clusters, output.Body.DedicatedHostClusters.DedicatedHostCluster...)
+		if *output.Body.TotalCount < pageNumber*pageSize || len(output.Body.DedicatedHostClusters.DedicatedHostCluster) < pageSize {
+			break
+		}
+	}
+	return clusters, nil
+}
+
+func toDedicatedHost(host *ecs.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost) v1alpha1.DedicatedHost {
+	dedicatedHost := v1alpha1.DedicatedHost{
+		ID:        tea.StringValue(host.DedicatedHostId),
+		ClusterID: tea.StringValue(host.DedicatedHostClusterId),
+		ZoneID:    tea.StringValue(host.ZoneId),
+	}
+	if host.SupportedInstanceTypesList != nil {
+		dedicatedHost.InstanceTypes = tea.StringSliceValue(host.SupportedInstanceTypesList.SupportedInstanceTypesList)
+		sort.Strings(dedicatedHost.InstanceTypes)
+	}
+	if host.Capacity != nil {
+		dedicatedHost.AvailableVCPUs = tea.Int32Value(host.Capacity.AvailableVcpus)
+		// The available memory is reported in GiB
+		dedicatedHost.AvailableMemory = *resource.NewQuantity(int64(tea.Float32Value(host.Capacity.AvailableMemory)*1024)*1024*1024, resource.BinarySI)
+	}
+	return dedicatedHost
+}
+
+func deepCopy(hosts []v1alpha1.DedicatedHost) []v1alpha1.DedicatedHost {
+	return lo.Map(hosts, func(host v1alpha1.DedicatedHost, _ int) v1alpha1.DedicatedHost { return *host.DeepCopy() })
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dedicatedhost
+
+import (
+	"context"
+	"testing"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+)
+
+func newTestDedicatedHost(id, clusterID, status, autoPlacement string) *ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost {
+	return &ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost{
+		DedicatedHostId:        tea.String(id),
+		DedicatedHostClusterId: tea.String(clusterID),
+		ZoneId:                 tea.String("cn-hangzhou-i"),
+		Status:                 tea.String(status),
+		AutoPlacement:          tea.String(autoPlacement),
+		SupportedInstanceTypesList: &ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHostSupportedInstanceTypesList{
+			SupportedInstanceTypesList: tea.StringSlice([]string{"ecs.g7.xlarge", "ecs.g7.large"}),
+		},
+		Capacity: &ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHostCapacity{
+			AvailableVcpus:  tea.Int32(8),
+			AvailableMemory: tea.Float32(32),
+		},
+	}
+}
+
+func newTestDedicatedHostCluster(id string, tags map[string]string) *ecsclient.DescribeDedicatedHostClustersResponseBodyDedicatedHostClustersDedicatedHostCluster {
+	cluster := &ecsclient.DescribeDedicatedHostClustersResponseBodyDedicatedHostClustersDedicatedHostCluster{
+		DedicatedHostClusterId: tea.String(id),
+		ZoneId:                 tea.String("cn-hangzhou-i"),
+		Tags:                   &ecsclient.DescribeDedicatedHostClustersResponseBodyDedicatedHostClustersDedicatedHostClusterTags{},
+	}
+	for k, v := range tags {
+		cluster.Tags.Tag = append(cluster.Tags.Tag, &ecsclient.DescribeDedicatedHostClustersResponseBodyDedicatedHostClustersDedicatedHostClusterTagsTag{
+			TagKey: tea.String(k), TagValue: tea.String(v),
+		})
+	}
+	return cluster
+}
+
+func TestDefaultProvider_List(t *testing.T) {
+	ecsAPI := fake.NewECSAPI(nil)
+	ecsAPI.AddDedicatedHosts(
+		newTestDedicatedHost("dh-b", "dc-a", statusAvailable, "on"),
+		newTestDedicatedHost("dh-a", "dc-a", statusAvailable, "off"),
+		newTestDedicatedHost("dh-c", "dc-b", statusAvailable, "on"),
+		newTestDedicatedHost("dh-d", "dc-b", "UnderAssessment", "on"),
+	)
+	ecsAPI.AddDedicatedHostClusters(
+		newTestDedicatedHostCluster("dc-a", map[string]string{"team": "a"}),
+		newTestDedicatedHostCluster("dc-b", map[string]string{"team": "b"}),
+	)
+
+	tests := []struct {
+		name      string
+		placement *v1alpha1.Placement
+		want      []string
+	}{
+		{
+			name: "no placement",
+		},
+		{
+			name:      "not on dedicated hosts",
+			placement: &v1alpha1.Placement{HPCClusterID: "hpc-a"},
+		},
+		{
+			name:      "id",
+			placement: &v1alpha1.Placement{DedicatedHostID: "dh-a"},
+			want:      []string{"dh-a"},
+		},
+		{
+			name:      "unavailable id",
+			placement: &v1alpha1.Placement{DedicatedHostID: "dh-d"},
+		},
+		{
+			name:      "cluster id",
+			placement: &v1alpha1.Placement{DedicatedHostClusterSelectorTerms: []v1alpha1.DedicatedHostClusterSelectorTerm{{ID: "dc-a"}}},
+			want:      []string{"dh-a", "dh-b"},
+		},
+		{
+			name:      "cluster tags",
+			placement: &v1alpha1.Placement{DedicatedHostClusterSelectorTerms: []v1alpha1.DedicatedHostClusterSelectorTerm{{Tags: map[string]string{"team": "b"}}}},
+			want:      []string{"dh-c"},
+		},
+		{
+			name:      "wildcard cluster tag",
+			placement: &v1alpha1.Placement{DedicatedHostClusterSelectorTerms: []v1alpha1.DedicatedHostClusterSelectorTerm{{Tags: map[string]string{"team": "*"}}}},
+			want:      []string{"dh-a", "dh-b", "dh-c"},
+		},
+		{
+			name:      "automatic placement",
+			placement: &v1alpha1.Placement{Tenancy: v1alpha1.TenancyHost},
+			want:      []string{"dh-b", "dh-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewDefaultProvider(fake.DefaultRegion, ecsAPI, cache.New(cache.NoExpiration, cache.NoExpiration))
+			nodeClass := &v1alpha1.ECSNodeClass{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+				Spec:       v1alpha1.ECSNodeClassSpec{Placement: tt.placement},
+			}
+
+			hosts, err := provider.List(context.Background(), nodeClass)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, lo.Map(hosts, func(host v1alpha1.DedicatedHost, _ int) string { return host.ID }))
+		})
+	}
+}
+
+func TestToDedicatedHost(t *testing.T) {
+	host := toDedicatedHost(newTestDedicatedHost("dh-a", "dc-a", statusAvailable, "on"))
+	assert.Equal(t, "dc-a", host.ClusterID)
+	assert.Equal(t, []string{"ecs.g7.large", "ecs.g7.xlarge"}, host.InstanceTypes)
+	assert.Equal(t, int32(8), host.AvailableVCPUs)
+	assert.True(t, host.AvailableMemory.Equal(resource.MustParse("32Gi")))
+
+	assert.True(t, Fits(host, "ecs.g7.xlarge", 4, 16))
+	assert.False(t, Fits(host, "ecs.g7.xlarge", 16, 16))
+	assert.False(t, Fits(host, "ecs.g7.xlarge", 4, 64))
+	assert.False(t, Fits(host, "ecs.c7.xlarge", 4, 8))
+	assert.Equal(t, []string{"cn-hangzhou-i"}, Zones([]v1alpha1.DedicatedHost{host}, "ecs.g7.large", 2, 8).UnsortedList())
+	assert.Empty(t, Zones([]v1alpha1.DedicatedHost{host}, "ecs.g7.large", 16, 8))
+}
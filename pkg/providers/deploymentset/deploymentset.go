@@ -0,0 +1,306 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploymentset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/mitchellh/hashstructure/v2"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
+)
+
+const (
+	// resourceType is the type of the deployment sets in the tag APIs
+	resourceType = "deploymentset"
+	// maxNameLength is the longest name a deployment set accepts
+	maxNameLength = 128
+	pageSize      = 50
+)
+
+// strategies maps the strategies of the deployment set selector to the ECS ones
+var strategies = map[string]string{
+	v1alpha1.DeploymentSetStrategyAvailability: "Availability",
+	v1alpha1.DeploymentSetStrategyLowLatency:   "LowLatency",
+}
+
+type Provider interface {
+	Get(context.Context, *v1alpha1.ECSNodeClass) (*v1alpha1.DeploymentSet, error)
+	Delete(context.Context, *v1alpha1.ECSNodeClass) error
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	region string
+	ecsapi sdk.ECSAPI
+	cache  *cache.Cache
+	cm     *pretty.ChangeMonitor
+}
+
+func NewDefaultProvider(region string, ecsapi sdk.ECSAPI, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		region: region,
+		ecsapi: ecsapi,
+		cm:     pretty.NewChangeMonitor(),
+		cache:  cache,
+	}
+}
+
+// Get returns the deployment set selected by the ECSNodeClass, the deployment set of the ECSNodeClass is created
+// when the selector sets a strategy. nil is returned when the selected deployment set doesn't exist.
+func (p *DefaultProvider) Get(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (*v1alpha1.DeploymentSet, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	selector := nodeClass.Spec.DeploymentSetSelector
+	if selector == nil {
+		return nil, nil
+	}
+	hash, err := hashstructure.Hash(selector, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	if err != nil {
+		return nil, err
+	}
+	// The created deployment sets are named after the ECSNodeClass
+	key := fmt.Sprintf("%s-%d", nodeClass.Name, hash)
+	if deploymentSet, ok := p.cache.Get(key); ok {
+		return deploymentSet.(*v1alpha1.DeploymentSet).DeepCopy(), nil
+	}
+
+	var deploymentSet *v1alpha1.DeploymentSet
+	switch {
+	case selector.ID != "":
+		deploymentSet, err = p.get(selector.ID)
+	case len(selector.Tags) != 0:
+		deploymentSet, err = p.getByTags(selector.Tags)
+	default:
+		deploymentSet, err = p.getOrCreate(ctx, nodeClass)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deploymentSet == nil {
+		return nil, nil
+	}
+
+	if p.cm.HasChanged(fmt.Sprintf("deployment-set/%s", nodeClass.Name), deploymentSet.ID) {
+		log.FromContext(ctx).
+			WithValues("deployment-set", deploymentSet.ID, "strategy", deploymentSet.Strategy).
+			V(1).Info("discovered deployment set")
+	}
+	p.cache.SetDefault(key, deploymentSet)
+	return deploymentSet.DeepCopy(), nil
+}
+
+// Delete deletes the deployment sets created for the ECSNodeClass, it fails while instances are still placed into them
+func (p *DefaultProvider) Delete(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) error {
+	p.Lock()
+	defer p.Unlock()
+
+	var ids []string
+	name := deploymentSetName(ctx, nodeClass)
+	if err := p.describeDeploymentSets(&ecs.DescribeDeploymentSetsRequest{DeploymentSetName: tea.String(name)},
+		func(ds *ecs.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet) {
+			// The names are matched fuzzily
+			if tea.StringValue(ds.DeploymentSetName) == name {
+				ids = append(ids, tea.StringValue(ds.DeploymentSetId))
+			}
+		}); err != nil {
+		return fmt.Errorf("describing deployment sets, %w", err)
+	}
+
+	runtime := &util.RuntimeOptions{}
+	for _, id := range ids {
+		if _, err := p.ecsapi.DeleteDeploymentSetWithOptions(&ecs.DeleteDeploymentSetRequest{
+			RegionId:        tea.String(p.region),
+			DeploymentSetId: tea.String(id),
+		}, runtime); err != nil {
+			return fmt.Errorf("deleting deployment set %s, %w", id, err)
+		}
+		log.FromContext(ctx).WithValues("deployment-set", id).Info("deleted deployment set")
+	}
+	p.cache.Flush()
+	return nil
+}
+
+func (p *DefaultProvider) get(id string) (*v1alpha1.DeploymentSet, error) {
+	var deploymentSet *v1alpha1.DeploymentSet
+	if err := p.describeDeploymentSets(&ecs.DescribeDeploymentSetsRequest{DeploymentSetIds: deploymentSetIDs(id)},
+		func(ds *ecs.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet) {
+			if tea.StringValue(ds.DeploymentSetId) == id {
+				deploymentSet = toDeploymentSet(ds)
+			}
+		}); err != nil {
+		return nil, fmt.Errorf("describing deployment set %s, %w", id, err)
+	}
+	return deploymentSet, nil
+}
+
+// getByTags returns the deployment set with the smallest ID among the ones with the tags, the describe API doesn't
+// filter by tags
+func (p *DefaultProvider) getByTags(tags map[string]string) (*v1alpha1.DeploymentSet, error) {
+	request := &ecs.ListTagResourcesRequest{
+		RegionId:     tea.String(p.region),
+		ResourceType: tea.String(resourceType),
+	}
+	for k, v := range tags {
+		tag := &ecs.ListTagResourcesRequestTag{Key: tea.String(k)}
+		if v != "*" {
+			tag.Value = tea.String(v)
+		}
+		request.Tag = append(request.Tag, tag)
+	}
+
+	var ids []string
+	runtime := &util.RuntimeOptions{}
+	for {
+		output, err := p.ecsapi.ListTagResourcesWithOptions(request, runtime)
+		if err != nil {
+			return nil, fmt.Errorf("listing tagged deployment sets, %w", err)
+		} else if output == nil || output.Body == nil {
+			return nil, fmt.Errorf("unexpected null value was returned")
+		} else if output.Body.TagResources == nil {
+			return nil, alierrors.WithRequestID(tea.StringValue(output.Body.RequestId), fmt.Errorf("unexpected null value was returned"))
+		}
+
+		for _, tagResource := range output.Body.TagResources.TagResource {
+			ids = append(ids, tea.StringValue(tagResource.ResourceId))
+		}
+		if output.Body.NextToken == nil || *output.Body.NextToken == "" {
+			break
+		}
+		request.NextToken = output.Body.NextToken
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return p.get(lo.Min(ids))
+}
+
+// getOrCreate returns the deployment set of the ECSNodeClass with the strategy of the selector, it is created when it
+// doesn't exist yet
+func (p *DefaultProvider) getOrCreate(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (*v1alpha1.DeploymentSet, error) {
+	name := deploymentSetName(ctx, nodeClass)
+	strategy := strategies[nodeClass.Spec.DeploymentSetSelector.Strategy]
+
+	var deploymentSets []*v1alpha1.DeploymentSet
+	if err := p.describeDeploymentSets(&ecs.DescribeDeploymentSetsRequest{
+		DeploymentSetName: tea.String(name),
+		Strategy:          tea.String(strategy),
+	}, func(ds *ecs.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet) {
+		// The names are matched fuzzily
+		if tea.StringValue(ds.DeploymentSetName) == name {
+			deploymentSets = append(deploymentSets, toDeploymentSet(ds))
+		}
+	}); err != nil {
+		return nil, fmt.Errorf("describing deployment sets, %w", err)
+	}
+	if len(deploymentSets) != 0 {
+		return lo.MinBy(deploymentSets, func(a, b *v1alpha1.DeploymentSet) bool { return a.ID < b.ID }), nil
+	}
+
+	output, err := p.ecsapi.CreateDeploymentSetWithOptions(&ecs.CreateDeploymentSetRequest{
+		RegionId:          tea.String(p.region),
+		DeploymentSetName: tea.String(name),
+		Description:       tea.String(fmt.Sprintf("Created by Karpenter for the ECSNodeClass %s", nodeClass.Name)),
+		Strategy:          tea.String(strategy),
+	}, &util.RuntimeOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("creating deployment set, %w", err)
+	} else if output == nil || output.Body == nil {
+		return nil, fmt.Errorf("unexpected null value was returned")
+	} else if output.Body.DeploymentSetId == nil {
+		return nil, alierrors.WithRequestID(tea.StringValue(output.Body.RequestId), fmt.Errorf("unexpected null value was returned"))
+	}
+	log.FromContext(ctx).WithValues("deployment-set", tea.StringValue(output.Body.DeploymentSetId), "strategy", strategy).Info("created deployment set")
+	return &v1alpha1.DeploymentSet{
+		ID:       tea.StringValue(output.Body.DeploymentSetId),
+		Name:     name,
+		Strategy: strategy,
+	}, nil
+}
+
+func (p *DefaultProvider) describeDeploymentSets(request *ecs.DescribeDeploymentSetsRequest,
+	process func(*ecs.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet)) error {
+	runtime := &util.RuntimeOptions{}
+	request.RegionId = tea.String(p.region)
+	request.PageSize = tea.Int32(pageSize)
+	for pageNumber := int32(1); pageNumber < 100; pageNumber++ {
+		request.PageNumber = tea.Int32(pageNumber)
+		output, err := p.ecsapi.DescribeDeploymentSetsWithOptions(request, runtime)
+		if err != nil {
+			return err
+		} else if output == nil || output.Body == nil {
+			return fmt.Errorf("unexpected null value was returned")
+		} else if output.Body.TotalCount == nil || output.Body.DeploymentSets == nil {
+			return alierrors.WithRequestID(tea.StringValue(output.Body.RequestId), fmt.Errorf("unexpected null value was returned"))
+		}
+
+		for _, ds := range output.Body.DeploymentSets.DeploymentSet {
+			process(ds)
+		}
+		if *output.Body.TotalCount < pageNumber*pageSize || len(output.Body.DeploymentSets.DeploymentSet) < pageSize {
+			break
+		}
+	}
+	return nil
+}
+
+func toDeploymentSet(ds *ecs.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet) *v1alpha1.DeploymentSet {
+	deploymentSet := &v1alpha1.DeploymentSet{
+		ID:       tea.StringValue(ds.DeploymentSetId),
+		Name:     tea.StringValue(ds.DeploymentSetName),
+		Strategy: tea.StringValue(ds.Strategy),
+	}
+	if ds.Capacities != nil {
+		deploymentSet.Capacities = lo.Map(ds.Capacities.Capacity, func(c *ecs.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSetCapacitiesCapacity, _ int) v1alpha1.DeploymentSetCapacity {
+			return v1alpha1.DeploymentSetCapacity{
+				ZoneID:                 tea.StringValue(c.ZoneId),
+				AvailableInstanceCount: tea.Int32Value(c.AvailableAmount),
+			}
+		})
+		sort.Slice(deploymentSet.Capacities, func(i, j int) bool {
+			return deploymentSet.Capacities[i].ZoneID < deploymentSet.Capacities[j].ZoneID
+		})
+	}
+	return deploymentSet
+}
+
+// deploymentSetName is the name of the deployment set created for the ECSNodeClass, it is unique per cluster
+func deploymentSetName(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) string {
+	name := fmt.Sprintf("karpenter-%s-%s", options.FromContext(ctx).ClusterID, nodeClass.Name)
+	return name[:min(len(name), maxNameLength)]
+}
+
+// deploymentSetIDs returns the deployment set ID as the JSON array the describe API accepts
+func deploymentSetIDs(id string) *string {
+	ids, _ := json.Marshal([]string{id})
+	return tea.String(string(ids))
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploymentset
+
+import (
+	"context"
+	"testing"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
+)
+
+const testClusterID = "c-test"
+
+func newTestDeploymentSet(id string, availableAmounts map[string]int32) *ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet {
+	ds := &ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet{
+		DeploymentSetId:   tea.String(id),
+		DeploymentSetName: tea.String(id),
+		Strategy:          tea.String("Availability"),
+		Capacities:        &ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSetCapacities{},
+	}
+	for zoneID, availableAmount := range availableAmounts {
+		ds.Capacities.Capacity = append(ds.Capacities.Capacity, &ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSetCapacitiesCapacity{
+			ZoneId: tea.String(zoneID), AvailableAmount: tea.Int32(availableAmount), UsedAmount: tea.Int32(20 - availableAmount),
+		})
+	}
+	return ds
+}
+
+func newTestNodeClass(selector *v1alpha1.DeploymentSetSelector) *v1alpha1.ECSNodeClass {
+	return &v1alpha1.ECSNodeClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       v1alpha1.ECSNodeClassSpec{DeploymentSetSelector: selector},
+	}
+}
+
+func TestDefaultProvider_Get(t *testing.T) {
+	ctx := options.ToContext(context.Background(), &options.Options{ClusterID: testClusterID})
+	ecsAPI := fake.NewECSAPI(nil)
+	ecsAPI.AddDeploymentSet(newTestDeploymentSet("ds-b", map[string]int32{"cn-hangzhou-j": 0, "cn-hangzhou-i": 20}), map[string]string{"team": "a"})
+	ecsAPI.AddDeploymentSet(newTestDeploymentSet("ds-a", map[string]int32{"cn-hangzhou-i": 5}), map[string]string{"team": "a"})
+	ecsAPI.AddDeploymentSet(newTestDeploymentSet("ds-c", nil), map[string]string{"team": "c"})
+	dsA := &v1alpha1.DeploymentSet{ID: "ds-a", Name: "ds-a", Strategy: "Availability",
+		Capacities: []v1alpha1.DeploymentSetCapacity{{ZoneID: "cn-hangzhou-i", AvailableInstanceCount: 5}}}
+	dsB := &v1alpha1.DeploymentSet{ID: "ds-b", Name: "ds-b", Strategy: "Availability",
+		Capacities: []v1alpha1.DeploymentSetCapacity{{ZoneID: "cn-hangzhou-i", AvailableInstanceCount: 20}, {ZoneID: "cn-hangzhou-j"}}}
+
+	tests := []struct {
+		name     string
+		selector *v1alpha1.DeploymentSetSelector
+		want     *v1alpha1.DeploymentSet
+	}{
+		{
+			name: "no selector",
+		},
+		{
+			name:     "id",
+			selector: &v1alpha1.DeploymentSetSelector{ID: "ds-b"},
+			want:     dsB,
+		},
+		{
+			name:     "missing id",
+			selector: &v1alpha1.DeploymentSetSelector{ID: "ds-missing"},
+		},
+		{
+			name:     "tags match the smallest id",
+			selector: &v1alpha1.DeploymentSetSelector{Tags: map[string]string{"team": "a"}},
+			want:     dsA,
+		},
+		{
+			name:     "wildcard tag",
+			selector: &v1alpha1.DeploymentSetSelector{Tags: map[string]string{"team": "*"}},
+			want:     dsA,
+		},
+		{
+			name:     "missing tags",
+			selector: &v1alpha1.DeploymentSetSelector{Tags: map[string]string{"team": "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewDefaultProvider(fake.DefaultRegion, ecsAPI, cache.New(cache.NoExpiration, cache.NoExpiration))
+
+			deploymentSet, err := provider.Get(ctx, newTestNodeClass(tt.selector))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, deploymentSet)
+		})
+	}
+}
+
+func TestDefaultProvider_GetCreatesDeploymentSet(t *testing.T) {
+	ctx := options.ToContext(context.Background(), &options.Options{ClusterID: testClusterID})
+	ecsAPI := fake.NewECSAPI(nil)
+	provider := NewDefaultProvider(fake.DefaultRegion, ecsAPI, cache.New(cache.NoExpiration, cache.NoExpiration))
+	nodeClass := newTestNodeClass(&v1alpha1.DeploymentSetSelector{Strategy: v1alpha1.DeploymentSetStrategyLowLatency})
+
+	created, err := provider.Get(ctx, nodeClass)
+	require.NoError(t, err)
+	require.NotNil(t, created)
+	assert.Equal(t, "karpenter-c-test-default", created.Name)
+	assert.Equal(t, "LowLatency", created.Strategy)
+	require.Len(t, ecsAPI.DeploymentSets(), 1)
+
+	// The deployment set is reused instead of being created again
+	provider = NewDefaultProvider(fake.DefaultRegion, ecsAPI, cache.New(cache.NoExpiration, cache.NoExpiration))
+	got, err := provider.Get(ctx, nodeClass)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	assert.Len(t, ecsAPI.DeploymentSets(), 1)
+
+	// Changing the strategy creates another deployment set, both are deleted with the ECSNodeClass
+	nodeClass.Spec.DeploymentSetSelector.Strategy = v1alpha1.DeploymentSetStrategyAvailability
+	got, err = provider.Get(ctx, nodeClass)
+	require.NoError(t, err)
+	assert.NotEqual(t, created.ID, got.ID)
+	assert.Equal(t, "Availability", got.Strategy)
+	ecsAPI.AddDeploymentSet(newTestDeploymentSet("ds-other", nil), nil)
+	require.Len(t, ecsAPI.DeploymentSets(), 3)
+
+	require.NoError(t, provider.Delete(ctx, nodeClass))
+	remaining := ecsAPI.DeploymentSets()
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "ds-other", tea.StringValue(remaining[0].DeploymentSetId))
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package imagefamily
 
 import (
-	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/cluster"
 	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/cluster"
 )
 
 type ContainerOS struct {
@@ -27,10 +30,10 @@ type ContainerOS struct {
 
 func (c *ContainerOS) GetImages(supportedImages []cluster.Image, kubernetesVersion, imageVersion string) (Images, error) {
 	var ret Images
-	for _, im := range supportedImages {
-		if !strings.HasPrefix(im.ImageName, "ContainerOS") {
-			continue
-		}
+	supportedImages = lo.Filter(supportedImages, func(im cluster.Image, _ int) bool {
+		return strings.HasPrefix(im.ImageName, "ContainerOS")
+	})
+	for _, im := range filterImagesByVersion(supportedImages, imageVersion) {
 		if image, err := alibabaCloudLinuxResolveImages(im); err == nil {
 			ret = append(ret, image)
 		}
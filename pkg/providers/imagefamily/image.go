@@ -19,6 +19,8 @@ package imagefamily
 import (
 	"context"
 	"fmt"
+	"path"
+	"strings"
 	"sync"
 
 	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
@@ -29,18 +31,24 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/cluster"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/version"
 )
 
+const (
+	describeImagesPageSize int32 = 100
+	imageOwnerSelf               = "self"
+)
+
 type Provider interface {
 	List(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass) (Images, error)
 }
 
 type DefaultProvider struct {
 	region    string
-	ecsClient *ecs.Client
+	ecsClient sdk.ECSAPI
 
 	sync.Mutex
 	cache *cache.Cache
@@ -49,7 +57,7 @@ type DefaultProvider struct {
 	versionProvider version.Provider
 }
 
-func NewDefaultProvider(region string, ecsClient *ecs.Client, clusterProvider cluster.Provider,
+func NewDefaultProvider(region string, ecsClient sdk.ECSAPI, clusterProvider cluster.Provider,
 	versionProvider version.Provider, cache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
 		region:    region,
@@ -109,11 +117,16 @@ func (p *DefaultProvider) getImages(ctx context.Context, nodeClass *v1alpha1.ECS
 			if err != nil {
 				return nil, err
 			}
-		} else {
+		} else if selectorTerm.ID != "" {
 			ims, err = p.getImagesByID(selectorTerm.ID)
 			if err != nil {
 				return nil, err
 			}
+		} else {
+			ims, err = p.getImagesBySelectorTerm(selectorTerm)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		for _, im := range ims {
@@ -165,3 +178,72 @@ func (p *DefaultProvider) getImagesByID(id string) (Images, error) {
 
 	return images, nil
 }
+
+// getImagesBySelectorTerm returns the newest image of each architecture that matches the tags, name and owner of the term
+func (p *DefaultProvider) getImagesBySelectorTerm(term v1alpha1.ImageSelectorTerm) (Images, error) {
+	req := &ecs.DescribeImagesRequest{
+		RegionId: tea.String(p.region),
+		PageSize: tea.Int32(describeImagesPageSize),
+	}
+	owner := term.Owner
+	if term.Name != "" && owner == "" {
+		// Never pick up a public or shared image only because its name matches
+		owner = imageOwnerSelf
+	}
+	if owner != "" {
+		req.ImageOwnerAlias = tea.String(owner)
+	}
+	// Wildcards are matched after listing the images
+	if term.Name != "" && !strings.ContainsAny(term.Name, "*?[") {
+		req.ImageName = tea.String(term.Name)
+	}
+	for k, v := range term.Tags {
+		tag := &ecs.DescribeImagesRequestTag{Key: tea.String(k)}
+		if v != "*" {
+			tag.Value = tea.String(v)
+		}
+		req.Tag = append(req.Tag, tag)
+	}
+
+	newest := map[string]*ecs.DescribeImagesResponseBodyImagesImage{}
+	for pageNumber := int32(1); ; pageNumber++ {
+		req.PageNumber = tea.Int32(pageNumber)
+		resp, err := p.ecsClient.DescribeImages(req)
+		if err != nil {
+			return nil, fmt.Errorf("describing images %+v, %w", term, err)
+		}
+		if resp == nil || resp.Body == nil || resp.Body.Images == nil {
+			return nil, fmt.Errorf("unexpected null value was returned")
+		}
+
+		for _, image := range resp.Body.Images.Image {
+			if term.Name != "" {
+				if matched, _ := path.Match(term.Name, tea.StringValue(image.ImageName)); !matched {
+					continue
+				}
+			}
+			arch, ok := v1alpha1.AlibabaCloudToKubeArchitectures[tea.StringValue(image.Architecture)]
+			if !ok {
+				continue
+			}
+			// CreationTime is in the UTC ISO8601 format, so it can be compared lexically
+			if current, ok := newest[arch]; !ok || tea.StringValue(image.CreationTime) > tea.StringValue(current.CreationTime) {
+				newest[arch] = image
+			}
+		}
+
+		if len(resp.Body.Images.Image) == 0 || pageNumber*describeImagesPageSize >= tea.Int32Value(resp.Body.TotalCount) {
+			break
+		}
+	}
+
+	images := Images{}
+	for arch, image := range newest {
+		images = append(images, Image{
+			Name:         tea.StringValue(image.ImageName),
+			ImageID:      tea.StringValue(image.ImageId),
+			Requirements: scheduling.NewRequirements(scheduling.NewRequirement(corev1.LabelArchStable, corev1.NodeSelectorOpIn, arch)),
+		})
+	}
+	return images, nil
+}
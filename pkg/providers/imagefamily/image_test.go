@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+)
+
+func newTestImage(id, name, owner, arch string, created time.Time) *ecsclient.DescribeImagesResponseBodyImagesImage {
+	return &ecsclient.DescribeImagesResponseBodyImagesImage{
+		ImageId:         tea.String(id),
+		ImageName:       tea.String(name),
+		ImageOwnerAlias: tea.String(owner),
+		Architecture:    tea.String(arch),
+		CreationTime:    tea.String(created.UTC().Format("2006-01-02T15:04:05Z")),
+	}
+}
+
+// imageIDsByArchitecture returns the IDs of the images keyed by the architecture they are offered for
+func imageIDsByArchitecture(images Images) map[string]string {
+	return lo.SliceToMap(images, func(image Image) (string, string) {
+		return image.Requirements.Get(corev1.LabelArchStable).Any(), image.ImageID
+	})
+}
+
+func TestDefaultProvider_getImagesBySelectorTerm(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	ecsAPI := fake.NewECSAPI(nil)
+	ecsAPI.AddImages(
+		newTestImage("m-amd64-old", "ack-node-v1", "self", "x86_64", day),
+		newTestImage("m-amd64-new", "ack-node-v2", "self", "x86_64", day.AddDate(0, 0, 1)),
+		newTestImage("m-arm64", "ack-node-v1", "self", "arm64", day),
+		newTestImage("m-i386", "ack-node-v3", "self", "i386", day.AddDate(0, 0, 2)),
+		newTestImage("m-other", "other-node", "self", "x86_64", day.AddDate(0, 0, 3)),
+		newTestImage("m-system", "ack-node-v9", "system", "x86_64", day.AddDate(0, 0, 4)),
+	)
+	provider := NewDefaultProvider(fake.DefaultRegion, ecsAPI, nil, nil, cache.New(cache.NoExpiration, cache.NoExpiration))
+
+	tests := []struct {
+		name string
+		term v1alpha1.ImageSelectorTerm
+		want map[string]string
+	}{
+		{
+			name: "name wildcard only picks up the own images",
+			term: v1alpha1.ImageSelectorTerm{Name: "ack-node-*"},
+			want: map[string]string{"amd64": "m-amd64-new", "arm64": "m-arm64"},
+		},
+		{
+			name: "exact name",
+			term: v1alpha1.ImageSelectorTerm{Name: "ack-node-v1"},
+			want: map[string]string{"amd64": "m-amd64-old", "arm64": "m-arm64"},
+		},
+		{
+			name: "single character wildcard",
+			term: v1alpha1.ImageSelectorTerm{Name: "ack-node-v?"},
+			want: map[string]string{"amd64": "m-amd64-new", "arm64": "m-arm64"},
+		},
+		{
+			name: "owner overrides the own images",
+			term: v1alpha1.ImageSelectorTerm{Name: "ack-node-*", Owner: "system"},
+			want: map[string]string{"amd64": "m-system"},
+		},
+		{
+			name: "no match",
+			term: v1alpha1.ImageSelectorTerm{Name: "ack-node-v1?"},
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			images, err := provider.getImagesBySelectorTerm(tt.term)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, imageIDsByArchitecture(images))
+		})
+	}
+}
+
+func TestDefaultProvider_getImagesBySelectorTermPagination(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	ecsAPI := fake.NewECSAPI(nil)
+	// The newest image is on the last page
+	for i := range 2*int(describeImagesPageSize) + 1 {
+		ecsAPI.AddImages(newTestImage(fmt.Sprintf("m-%03d", i), fmt.Sprintf("ack-node-%03d", i), "self", "x86_64",
+			day.Add(time.Duration(i)*time.Hour)))
+	}
+	provider := NewDefaultProvider(fake.DefaultRegion, ecsAPI, nil, nil, cache.New(cache.NoExpiration, cache.NoExpiration))
+
+	images, err := provider.getImagesBySelectorTerm(v1alpha1.ImageSelectorTerm{Name: "ack-node-*"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"amd64": "m-200"}, imageIDsByArchitecture(images))
+	assert.Equal(t, "ack-node-200", images[0].Name)
+}
+
+func TestDefaultProvider_getImagesBySelectorTermError(t *testing.T) {
+	ecsAPI := fake.NewECSAPI(nil)
+	ecsAPI.DescribeImagesError.Set(fake.NewThrottlingError())
+	provider := NewDefaultProvider(fake.DefaultRegion, ecsAPI, nil, nil, cache.New(cache.NoExpiration, cache.NoExpiration))
+
+	_, err := provider.getImagesBySelectorTerm(v1alpha1.ImageSelectorTerm{Name: "ack-node-*"})
+	assert.Error(t, err)
+}
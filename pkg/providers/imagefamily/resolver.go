@@ -32,6 +32,7 @@ import (
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/utils/resources"
 
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
 )
@@ -42,12 +43,21 @@ var DefaultSystemDisk = v1alpha1.SystemDisk{
 	VolumeSize: resources.Quantity("20Gi"),
 }
 
+// essdPerformanceLevelMinGiBSizes are the smallest ESSDs offering the performance levels, the lower levels start at
+// the smallest system disk
+var essdPerformanceLevelMinGiBSizes = map[string]int32{
+	"PL2": 461,
+	"PL3": 1261,
+}
+
 // Options for ImageFamily
 type Options struct {
 }
 
 type InstanceTypeAvailableSystemDisk struct {
 	availableSystemDisk sets.Set[string]
+	// sizeRanges are the smallest and the largest system disk sizes in GiB of the categories, when they are reported
+	sizeRanges map[string][2]int32
 	// todo: verify availability zone
 	// availableZone sets.Set[string]
 }
@@ -55,6 +65,7 @@ type InstanceTypeAvailableSystemDisk struct {
 func newInstanceTypeAvailableSystemDisk() *InstanceTypeAvailableSystemDisk {
 	return &InstanceTypeAvailableSystemDisk{
 		availableSystemDisk: sets.Set[string]{},
+		sizeRanges:          map[string][2]int32{},
 	}
 }
 
@@ -62,11 +73,22 @@ func (s *InstanceTypeAvailableSystemDisk) AddAvailableSystemDisk(systemDisks ...
 	s.availableSystemDisk.Insert(systemDisks...)
 }
 
-func (s *InstanceTypeAvailableSystemDisk) Compatible(systemDisks []string) bool {
+func (s *InstanceTypeAvailableSystemDisk) addSizeRange(systemDisk string, minSize, maxSize *int32) {
+	if minSize != nil && maxSize != nil {
+		s.sizeRanges[systemDisk] = [2]int32{*minSize, *maxSize}
+	}
+}
+
+// Compatible returns true when one of the system disks is available with the size in GiB
+func (s *InstanceTypeAvailableSystemDisk) Compatible(systemDisks []string, size int32) bool {
 	for sdi := range systemDisks {
-		if s.availableSystemDisk.Has(systemDisks[sdi]) {
-			return true
+		if !s.availableSystemDisk.Has(systemDisks[sdi]) {
+			continue
 		}
+		if sizeRange, ok := s.sizeRanges[systemDisks[sdi]]; ok && (size < sizeRange[0] || size > sizeRange[1]) {
+			continue
+		}
+		return true
 	}
 
 	return false
@@ -80,12 +102,12 @@ type Resolver interface {
 type DefaultResolver struct {
 	sync.Mutex
 	region string
-	ecsapi *ecs.Client
+	ecsapi sdk.ECSAPI
 	cache  *cache.Cache
 }
 
 // NewDefaultResolver constructs a new launch template DefaultResolver
-func NewDefaultResolver(region string, ecsapi *ecs.Client, cache *cache.Cache) *DefaultResolver {
+func NewDefaultResolver(region string, ecsapi sdk.ECSAPI, cache *cache.Cache) *DefaultResolver {
 	return &DefaultResolver{
 		region: region,
 		ecsapi: ecsapi,
@@ -116,12 +138,23 @@ func (r *DefaultResolver) FilterInstanceTypesBySystemDisk(ctx context.Context, n
 	if nodeClass.Spec.SystemDisk == nil || nodeClass.Spec.SystemDisk.Categories == nil {
 		return instanceTypes
 	}
-	expectDiskCategories := nodeClass.Spec.SystemDisk.Categories
+	// Only the categories offering the performance settings of the system disk are launched
+	expectDiskCategories := nodeClass.Spec.SystemDisk.GetCategories()
+	size := nodeClass.Spec.SystemDisk.GetGiBSize()
+	if size == 0 {
+		size = DefaultSystemDisk.GetGiBSize()
+	}
+	// The higher performance levels are only offered by the larger ESSDs
+	if pl := nodeClass.Spec.SystemDisk.GetPerformanceLevel(); pl != nil && size < essdPerformanceLevelMinGiBSizes[*pl] {
+		log.FromContext(ctx).Error(fmt.Errorf("system disk of %d GiB is smaller than the %d GiB of performance level %s",
+			size, essdPerformanceLevelMinGiBSizes[*pl], *pl), "filter instance types by system disk", "nodeClass", nodeClass.Name)
+		return nil
+	}
 	errs := make([]error, len(instanceTypes))
 	workqueue.ParallelizeUntil(ctx, 50, len(instanceTypes), func(i int) {
 		instanceType := instanceTypes[i]
 		if availableSystemDisk, ok := r.cache.Get(instanceType.Name); ok {
-			if availableSystemDisk.(*InstanceTypeAvailableSystemDisk).Compatible(expectDiskCategories) {
+			if availableSystemDisk.(*InstanceTypeAvailableSystemDisk).Compatible(expectDiskCategories, size) {
 				resultMutex.Lock()
 				result = append(result, instanceTypes[i])
 				resultMutex.Unlock()
@@ -138,12 +171,13 @@ func (r *DefaultResolver) FilterInstanceTypesBySystemDisk(ctx context.Context, n
 			if tea.StringValue(resource.Status) == "Available" &&
 				tea.StringValue(resource.Value) != "" {
 				availableSystemDisk.AddAvailableSystemDisk(tea.StringValue(resource.Value))
+				availableSystemDisk.addSizeRange(tea.StringValue(resource.Value), resource.Min, resource.Max)
 			}
 		}); err != nil {
 			errs[i] = err
 			return
 		}
-		if availableSystemDisk.Compatible(expectDiskCategories) {
+		if availableSystemDisk.Compatible(expectDiskCategories, size) {
 			resultMutex.Lock()
 			result = append(result, instanceTypes[i])
 			resultMutex.Unlock()
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+)
+
+func TestDefaultResolver_FilterInstanceTypesBySystemDisk(t *testing.T) {
+	ecsAPI := fake.NewECSAPI(nil)
+	ecsAPI.AddOfferings(fake.CapacityPool{InstanceType: "ecs.g7.large", ZoneID: "cn-beijing-a", CapacityType: karpv1.CapacityTypeOnDemand})
+	ecsAPI.SetSystemDiskCategories("cloud_efficiency", "cloud_essd")
+	instanceTypes := []*cloudprovider.InstanceType{{Name: "ecs.g7.large"}}
+
+	tests := []struct {
+		name       string
+		systemDisk *v1alpha1.SystemDisk
+		want       []string
+	}{
+		{
+			name:       "no system disk",
+			systemDisk: nil,
+			want:       []string{"ecs.g7.large"},
+		},
+		{
+			name:       "compatible category",
+			systemDisk: &v1alpha1.SystemDisk{Categories: []string{"cloud_auto", "cloud_essd"}},
+			want:       []string{"ecs.g7.large"},
+		},
+		{
+			name:       "incompatible category",
+			systemDisk: &v1alpha1.SystemDisk{Categories: []string{"cloud_auto"}},
+		},
+		{
+			name: "performance level offered by the ESSD",
+			systemDisk: &v1alpha1.SystemDisk{
+				Categories:       []string{"cloud_efficiency", "cloud_essd"},
+				VolumeSize:       lo.ToPtr(resource.MustParse("500Gi")),
+				PerformanceLevel: tea.String("PL2"),
+			},
+			want: []string{"ecs.g7.large"},
+		},
+		{
+			name: "performance level not offered by the system disk size",
+			systemDisk: &v1alpha1.SystemDisk{
+				Categories:       []string{"cloud_essd"},
+				VolumeSize:       lo.ToPtr(resource.MustParse("500Gi")),
+				PerformanceLevel: tea.String("PL3"),
+			},
+		},
+		{
+			name: "performance level not offered by the compatible categories",
+			systemDisk: &v1alpha1.SystemDisk{
+				Categories:       []string{"cloud_efficiency", "cloud_auto"},
+				PerformanceLevel: tea.String("PL3"),
+			},
+		},
+		{
+			name: "provisioned IOPS not offered by the compatible categories",
+			systemDisk: &v1alpha1.SystemDisk{
+				Categories:      []string{"cloud_essd", "cloud_auto"},
+				ProvisionedIOPS: lo.ToPtr[int64](5000),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := NewDefaultResolver(fake.DefaultRegion, ecsAPI, cache.New(cache.NoExpiration, cache.NoExpiration))
+			nodeClass := &v1alpha1.ECSNodeClass{Spec: v1alpha1.ECSNodeClassSpec{SystemDisk: tt.systemDisk}}
+
+			result := resolver.FilterInstanceTypesBySystemDisk(context.Background(), nodeClass, instanceTypes)
+			assert.ElementsMatch(t, tt.want, lo.Map(result, func(instanceType *cloudprovider.InstanceType, _ int) string {
+				return instanceType.Name
+			}))
+		})
+	}
+}
+
+func TestInstanceTypeAvailableSystemDisk_Compatible(t *testing.T) {
+	availableSystemDisk := newInstanceTypeAvailableSystemDisk()
+	availableSystemDisk.AddAvailableSystemDisk("cloud_efficiency", "cloud_essd")
+	availableSystemDisk.addSizeRange("cloud_efficiency", tea.Int32(20), tea.Int32(500))
+
+	assert.True(t, availableSystemDisk.Compatible([]string{"cloud_efficiency"}, 40))
+	assert.False(t, availableSystemDisk.Compatible([]string{"cloud_efficiency"}, 1000))
+	// The sizes of the categories without a reported range aren't checked
+	assert.True(t, availableSystemDisk.Compatible([]string{"cloud_efficiency", "cloud_essd"}, 1000))
+	assert.False(t, availableSystemDisk.Compatible([]string{"cloud_auto"}, 40))
+}
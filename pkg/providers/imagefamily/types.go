@@ -17,8 +17,18 @@ limitations under the License.
 package imagefamily
 
 import (
-	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/cluster"
+	"regexp"
+
+	"github.com/samber/lo"
 	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/cluster"
+)
+
+var (
+	// The release date is embedded at the end of the ACK image ID, e.g. aliyun_3_arm64_20G_alibase_20240819.vhd
+	imageReleaseVersionRegex = regexp.MustCompile(`_(\d{8})(\.vhd)?$`)
 )
 
 type Image struct {
@@ -33,3 +43,32 @@ type Images []Image
 type ImageFamily interface {
 	GetImages(supportedImages []cluster.Image, kubernetesVersion, imageVersion string) (Images, error)
 }
+
+// imageReleaseVersion returns the release version of an ACK image, or an empty string if it is unknown
+func imageReleaseVersion(im cluster.Image) string {
+	matches := imageReleaseVersionRegex.FindStringSubmatch(im.ImageID)
+	if len(matches) < 2 {
+		return ""
+	}
+	return matches[1]
+}
+
+// filterImagesByVersion returns the images of the given release version, the latest version
+// selects the newest release of each architecture
+func filterImagesByVersion(images []cluster.Image, imageVersion string) []cluster.Image {
+	if imageVersion != v1alpha1.AliasVersionLatest {
+		return lo.Filter(images, func(im cluster.Image, _ int) bool {
+			return imageReleaseVersion(im) == imageVersion
+		})
+	}
+
+	newest := map[string]string{}
+	for _, im := range images {
+		if version := imageReleaseVersion(im); version > newest[im.Architecture] {
+			newest[im.Architecture] = version
+		}
+	}
+	return lo.Filter(images, func(im cluster.Image, _ int) bool {
+		return imageReleaseVersion(im) == newest[im.Architecture]
+	})
+}
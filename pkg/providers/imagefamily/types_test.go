@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagefamily
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/cluster"
+)
+
+func Test_filterImagesByVersion(t *testing.T) {
+	images := []cluster.Image{
+		{ImageID: "aliyun_3_x64_20G_alibase_20240528.vhd", Architecture: "x86_64"},
+		{ImageID: "aliyun_3_x64_20G_alibase_20240819.vhd", Architecture: "x86_64"},
+		{ImageID: "aliyun_3_arm64_20G_alibase_20240528.vhd", Architecture: "arm64"},
+		{ImageID: "lifsea_3_x64_10G_containerd_1_6_28_alibase_20240819.vhd", Architecture: "x86_64"},
+		{ImageID: "lifsea_3_arm64_10G_containerd_1_6_28_alibase_20240705", Architecture: "arm64"},
+	}
+
+	tests := []struct {
+		name         string
+		imageVersion string
+		want         []string
+	}{
+		{
+			name:         "latest picks the newest release of each architecture",
+			imageVersion: v1alpha1.AliasVersionLatest,
+			want: []string{
+				"aliyun_3_x64_20G_alibase_20240819.vhd",
+				"lifsea_3_x64_10G_containerd_1_6_28_alibase_20240819.vhd",
+				"lifsea_3_arm64_10G_containerd_1_6_28_alibase_20240705",
+			},
+		},
+		{
+			name:         "pinned version",
+			imageVersion: "20240528",
+			want:         []string{"aliyun_3_x64_20G_alibase_20240528.vhd", "aliyun_3_arm64_20G_alibase_20240528.vhd"},
+		},
+		{
+			name:         "pinned version without the .vhd suffix",
+			imageVersion: "20240705",
+			want:         []string{"lifsea_3_arm64_10G_containerd_1_6_28_alibase_20240705"},
+		},
+		{
+			name:         "pinned version which isn't released",
+			imageVersion: "20240101",
+			want:         []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, lo.Map(filterImagesByVersion(images, tt.imageVersion), func(im cluster.Image, _ int) string {
+				return im.ImageID
+			}))
+		})
+	}
+}
+
+func Test_imageReleaseVersion(t *testing.T) {
+	tests := []struct {
+		imageID string
+		want    string
+	}{
+		{imageID: "aliyun_3_arm64_20G_alibase_20240819.vhd", want: "20240819"},
+		{imageID: "lifsea_3_x64_10G_containerd_1_6_28_alibase_20240705", want: "20240705"},
+		{imageID: "aliyun_3_x64_20G_alibase_2024081.vhd", want: ""},
+		{imageID: "m-bp1234567890abcdef", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.imageID, func(t *testing.T) {
+			assert.Equal(t, tt.want, imageReleaseVersion(cluster.Image{ImageID: tt.imageID}))
+		})
+	}
+}
@@ -19,12 +19,16 @@ package instance
 import (
 	"context"
 	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
@@ -32,8 +36,6 @@ import (
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
-	"go.uber.org/multierr"
-	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
@@ -41,8 +43,10 @@ import (
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 	"sigs.k8s.io/karpenter/pkg/utils/resources"
 
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/batcher"
 	kcache "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/cache"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/cluster"
@@ -57,6 +61,16 @@ const (
 	maxInstanceTypes                       = 20
 	instanceCacheExpiration                = 15 * time.Second
 	defaultDataDiskSize              int32 = 20
+
+	// tagQueryLimit is the amount of resources DescribeInstances returns at most when filtering by tags
+	tagQueryLimit = 1000
+	// maxDescribeInstancesResults is both the page size and the amount of instance IDs a DescribeInstances call accepts
+	maxDescribeInstancesResults = 100
+	// defaultInternetMaxBandwidthOut is the outbound bandwidth in Mbit/s of the public IP addresses which don't set it
+	defaultInternetMaxBandwidthOut int32 = 5
+	// reservedCapacityUsedUpTTL keeps the reserved offering unavailable until the capacity reservations are resolved
+	// into the status again
+	reservedCapacityUsedUpTTL = time.Minute
 )
 
 type Provider interface {
@@ -68,7 +82,7 @@ type Provider interface {
 }
 
 type DefaultProvider struct {
-	ecsClient            *ecsclient.Client
+	ecsClient            sdk.ECSAPI
 	region               string
 	instanceCache        *cache.Cache
 	unavailableOfferings *kcache.UnavailableOfferings
@@ -76,10 +90,21 @@ type DefaultProvider struct {
 	imageFamilyResolver imagefamily.Resolver
 	vSwitchProvider     vswitch.Provider
 	clusterProvider     cluster.Provider
-	createLimiter       *rate.Limiter
+	ecsBatcher          *batcher.ECSAPI
+
+	muInflightReservations sync.Mutex
+	// inflightReservations tracks the instances launched into the private pools since their available instance
+	// counts were resolved into the status, keyed by private pool, instance type and zone
+	inflightReservations map[string]inflightReservation
+}
+
+type inflightReservation struct {
+	// availableInstanceCount is the count of the status the launches are deducted from
+	availableInstanceCount int32
+	launched               int32
 }
 
-func NewDefaultProvider(ctx context.Context, region string, ecsClient *ecsclient.Client, unavailableOfferings *kcache.UnavailableOfferings,
+func NewDefaultProvider(ctx context.Context, region string, ecsClient sdk.ECSAPI, unavailableOfferings *kcache.UnavailableOfferings,
 	imageFamilyResolver imagefamily.Resolver, vSwitchProvider vswitch.Provider,
 	clusterProvider cluster.Provider,
 ) *DefaultProvider {
@@ -88,10 +113,11 @@ func NewDefaultProvider(ctx context.Context, region string, ecsClient *ecsclient
 		region:               region,
 		instanceCache:        cache.New(instanceCacheExpiration, instanceCacheExpiration),
 		unavailableOfferings: unavailableOfferings,
-		createLimiter:        rate.NewLimiter(rate.Limit(1), options.FromContext(ctx).APGCreationQPS),
+		ecsBatcher:           batcher.ECS(ctx, ecsClient),
 		imageFamilyResolver:  imageFamilyResolver,
 		vSwitchProvider:      vSwitchProvider,
 		clusterProvider:      clusterProvider,
+		inflightReservations: map[string]inflightReservation{},
 	}
 
 	return p
@@ -100,11 +126,6 @@ func NewDefaultProvider(ctx context.Context, region string, ecsClient *ecsclient
 func (p *DefaultProvider) Create(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, nodeClaim *karpv1.NodeClaim,
 	instanceTypes []*cloudprovider.InstanceType,
 ) (*Instance, error) {
-	// Wait for rate limiter
-	if err := p.createLimiter.Wait(ctx); err != nil {
-		log.FromContext(ctx).Error(err, "rate limit exceeded")
-		return nil, fmt.Errorf("rate limit exceeded: %w", err)
-	}
 	schedulingRequirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
 	// Only filter the instances if there are no minValues in the requirement.
 	if !schedulingRequirements.HasMinValues() {
@@ -144,37 +165,80 @@ func (p *DefaultProvider) Get(ctx context.Context, id string) (*Instance, error)
 }
 
 func (p *DefaultProvider) list(ctx context.Context) ([]*Instance, error) {
-	var instances []*Instance
-
-	describeInstancesRequest := &ecsclient.DescribeInstancesRequest{
+	clusterTagKey := fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterID)
+	instances, totalCount, err := p.describeInstances(&ecsclient.DescribeInstancesRequest{
 		Tag: []*ecsclient.DescribeInstancesRequestTag{
 			// TODO: add karpenter.xxx.xxx tags
 			{
-				Key:   tea.String(fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterID)),
+				Key:   tea.String(clusterTagKey),
 				Value: tea.String("owned"),
 			},
 		},
-		RegionId: tea.String(p.region),
+		RegionId:   tea.String(p.region),
+		MaxResults: tea.Int32(maxDescribeInstancesResults),
+	}, tagQueryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	/* Refer https://api.aliyun.com/api/Ecs/2014-05-26/DescribeInstances
+	If you use one tag to filter resources, the number of resources queried under that tag cannot exceed 1000;
+	if you use multiple tags to filter resources, the number of resources queried with multiple tags bound at the
+	same time cannot exceed 1000. If the number of resources exceeds 1000, use the ListTagResources interface to query.
+	*/
+	if totalCount < tagQueryLimit {
+		InstancesListed.Set(float64(len(instances)), map[string]string{discoveryLabel: discoveryDescribeInstances})
+		InstancesListed.Delete(map[string]string{discoveryLabel: discoveryListTagResources})
+		return instances, nil
+	}
+
+	// The tag filter stopped after the first page, the instances are described by their IDs instead
+	instanceIDs, err := p.listTaggedInstanceIDs(clusterTagKey, "owned")
+	if err != nil {
+		return nil, fmt.Errorf("listing tagged instances, %w", err)
 	}
+	for _, ids := range lo.Chunk(instanceIDs, maxDescribeInstancesResults) {
+		instanceIDsJSON, err := json.Marshal(ids)
+		if err != nil {
+			return nil, err
+		}
+		chunk, _, err := p.describeInstances(&ecsclient.DescribeInstancesRequest{
+			InstanceIds: tea.String(string(instanceIDsJSON)),
+			RegionId:    tea.String(p.region),
+			MaxResults:  tea.Int32(maxDescribeInstancesResults),
+		}, 0)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, chunk...)
+	}
+	InstancesListed.Set(float64(len(instances)), map[string]string{discoveryLabel: discoveryListTagResources})
+	InstancesListed.Delete(map[string]string{discoveryLabel: discoveryDescribeInstances})
+
+	return instances, nil
+}
 
+// describeInstances returns all the pages of the instances and the total count of the matching instances, it stops
+// after the first page when the total count reaches totalCountLimit, 0 means no limit
+func (p *DefaultProvider) describeInstances(describeInstancesRequest *ecsclient.DescribeInstancesRequest, totalCountLimit int32) ([]*Instance, int32, error) {
+	var instances []*Instance
+	var totalCount int32
 	runtime := &util.RuntimeOptions{}
 
 	for {
-		// TODO: limit 1000
-		/* Refer https://api.aliyun.com/api/Ecs/2014-05-26/DescribeInstances
-		If you use one tag to filter resources, the number of resources queried under that tag cannot exceed 1000;
-		if you use multiple tags to filter resources, the number of resources queried with multiple tags bound at the
-		same time cannot exceed 1000. If the number of resources exceeds 1000, use the ListTagResources interface to query.
-		*/
 		resp, err := p.ecsClient.DescribeInstancesWithOptions(describeInstancesRequest, runtime)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		if resp == nil || resp.Body == nil || resp.Body.Instances == nil || len(resp.Body.Instances.Instance) == 0 {
 			break
 		}
 
+		totalCount = tea.Int32Value(resp.Body.TotalCount)
+		if totalCountLimit > 0 && totalCount >= totalCountLimit {
+			return nil, totalCount, nil
+		}
 		describeInstancesRequest.NextToken = resp.Body.NextToken
 		for i := range resp.Body.Instances.Instance {
 			instances = append(instances, NewInstance(resp.Body.Instances.Instance[i]))
@@ -185,7 +249,44 @@ func (p *DefaultProvider) list(ctx context.Context) ([]*Instance, error) {
 		}
 	}
 
-	return instances, nil
+	// The pages may overlap when the instances change while listing
+	return lo.UniqBy(instances, func(instance *Instance) string { return instance.ID }), totalCount, nil
+}
+
+// listTaggedInstanceIDs returns the IDs of the instances with the tag, ListTagResources returns an entry per tag
+// of each instance, so the IDs are deduplicated
+func (p *DefaultProvider) listTaggedInstanceIDs(key, value string) ([]string, error) {
+	listTagResourcesRequest := &ecsclient.ListTagResourcesRequest{
+		RegionId:     tea.String(p.region),
+		ResourceType: tea.String("instance"),
+		Tag: []*ecsclient.ListTagResourcesRequestTag{
+			{Key: tea.String(key), Value: tea.String(value)},
+		},
+	}
+
+	var instanceIDs []string
+	runtime := &util.RuntimeOptions{}
+	for {
+		resp, err := p.ecsClient.ListTagResourcesWithOptions(listTagResourcesRequest, runtime)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp == nil || resp.Body == nil || resp.Body.TagResources == nil {
+			break
+		}
+
+		for _, tagResource := range resp.Body.TagResources.TagResource {
+			instanceIDs = append(instanceIDs, tea.StringValue(tagResource.ResourceId))
+		}
+
+		if resp.Body.NextToken == nil || *resp.Body.NextToken == "" {
+			break
+		}
+		listTagResourcesRequest.NextToken = resp.Body.NextToken
+	}
+
+	return lo.Uniq(instanceIDs), nil
 }
 
 func (p *DefaultProvider) List(ctx context.Context) ([]*Instance, error) {
@@ -199,33 +300,22 @@ func (p *DefaultProvider) List(ctx context.Context) ([]*Instance, error) {
 }
 
 func (p *DefaultProvider) Delete(ctx context.Context, id string) error {
-	instance, err := p.Get(ctx, id)
-	if err != nil {
-		return fmt.Errorf("deleting instance, %w", err)
-	}
-
-	// For follow state, the API will return an error, let's return NotSupportedError
-	if instance.Status == InstanceStatusStarting || instance.Status == InstanceStatusPending {
-		return NewInstanceStateOperationNotSupportedError(id)
-	}
-
-	deleteInstanceRequest := &ecsclient.DeleteInstanceRequest{
-		InstanceId:            tea.String(id),
+	deleteInstancesRequest := &ecsclient.DeleteInstancesRequest{
+		RegionId:              tea.String(p.region),
+		InstanceId:            []*string{tea.String(id)},
 		Force:                 tea.Bool(true),
 		TerminateSubscription: tea.Bool(true),
 	}
 
-	runtime := &util.RuntimeOptions{}
-	if _, err := p.ecsClient.DeleteInstanceWithOptions(deleteInstanceRequest, runtime); err != nil {
+	if _, err := p.ecsBatcher.DeleteInstances(ctx, deleteInstancesRequest); err != nil {
 		if alierrors.IsNotFound(err) {
+			p.instanceCache.Delete(id)
 			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("instance already terminated"))
 		}
 
-		if _, e := p.Get(ctx, id); e != nil {
-			if cloudprovider.IsNodeClaimNotFoundError(e) {
-				return e
-			}
-			err = multierr.Append(err, e)
+		// For the instances which are still being created, the API will return an error, let's return NotSupportedError
+		if alierrors.IsIncorrectInstanceStatus(err) {
+			return NewInstanceStateOperationNotSupportedError(id)
 		}
 
 		return fmt.Errorf("terminating instance id: %s, %w", id, err)
@@ -321,9 +411,10 @@ func (p *DefaultProvider) isMixedCapacityLaunch(nodeClaim *karpv1.NodeClaim, ins
 				if requirements.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) != nil {
 					continue
 				}
-				if offering.Requirements.Get(karpv1.CapacityTypeLabelKey).Any() == karpv1.CapacityTypeSpot {
+				switch offering.Requirements.Get(karpv1.CapacityTypeLabelKey).Any() {
+				case karpv1.CapacityTypeSpot:
 					hasSpotOfferings = true
-				} else {
+				case karpv1.CapacityTypeOnDemand:
 					hasODOffering = true
 				}
 			}
@@ -382,48 +473,239 @@ func (p *DefaultProvider) launchInstance(ctx context.Context, nodeClass *v1alpha
 
 	createAutoProvisioningGroupRequest, err := p.getProvisioningGroup(ctx, nodeClass, nodeClaim, instanceTypes, zonalVSwitchs, capacityType, tags)
 	if err != nil {
+		p.vSwitchProvider.UpdateInflightIPs(nil, instanceTypes, lo.Values(zonalVSwitchs), capacityType)
 		return nil, nil, fmt.Errorf("getting provisioning group, %w", err)
 	}
 
-	runtime := &util.RuntimeOptions{}
-	resp, err := p.ecsClient.CreateAutoProvisioningGroupWithOptions(createAutoProvisioningGroupRequest, runtime)
+	// The auto provisioning groups can't set the protection period of the spot instances either
+	spotDuration := capacityType == karpv1.CapacityTypeSpot && nodeClass.Spec.SpotOptions != nil && nodeClass.Spec.SpotOptions.Duration != nil
+	var resp *ecsclient.CreateAutoProvisioningGroupResponse
+	if nodeClass.Spec.Placement != nil || nodeClass.Spec.IPv6AddressCount != nil || spotDuration {
+		resp, err = p.runInstances(ctx, nodeClass, createAutoProvisioningGroupRequest, zonalVSwitchs)
+	} else if resp, err = p.ecsBatcher.CreateAutoProvisioningGroup(ctx, createAutoProvisioningGroupRequest); err != nil {
+		err = fmt.Errorf("creating auto provisioning group, %w", err)
+	}
+	// Give back the IPs predicted for the vSwitches which didn't receive the instance
+	p.vSwitchProvider.UpdateInflightIPs(resp, instanceTypes, lo.Values(zonalVSwitchs), capacityType)
 	if err != nil {
-		return nil, nil, fmt.Errorf("creating auto provisioning group, %w", err)
+		if aliErr, ok := alierrors.AsError(err); ok {
+			return nil, nil, launchError(err, aliErr)
+		}
+		return nil, nil, err
 	}
 
-	p.updateUnavailableOfferingsCache(ctx, resp, capacityType)
+	p.updateUnavailableOfferingsCache(ctx, resp, capacityType, zonalVSwitchs)
 
 	if err := createAutoProvisioningGroupResponseHandler(resp); err != nil {
 		return nil, nil, err
 	}
 
-	return resp.Body.LaunchResults.LaunchResult[0], createAutoProvisioningGroupRequest, nil
+	launchResult := resp.Body.LaunchResults.LaunchResult[0]
+	if capacityType == v1alpha1.CapacityTypeReserved {
+		p.trackReservedLaunch(ctx, nodeClass, tea.StringValue(launchResult.InstanceType), tea.StringValue(launchResult.ZoneId))
+	}
+	return launchResult, createAutoProvisioningGroupRequest, nil
 }
 
-const (
-	ErrCodeNoInstanceStock        = "NoInstanceStock"
-	ErrCodeOperationDeniedNoStock = "OperationDenied.NoStock"
-)
+// runInstances launches the instance with RunInstances, trying the launch template configs in order, since the auto
+// provisioning groups can't place the instances onto dedicated hosts or into HPC clusters, assign IPv6 addresses nor
+// set the protection period of the spot instances.
+// The outcome is returned as the response of an auto provisioning group, so it is handled the same way.
+func (p *DefaultProvider) runInstances(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, request *ecsclient.CreateAutoProvisioningGroupRequest,
+	zonalVSwitchs map[string]*vswitch.VSwitch) (*ecsclient.CreateAutoProvisioningGroupResponse, error) {
+	var failed []*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult
+	for _, config := range request.LaunchTemplateConfig {
+		zoneID, _ := lo.FindKeyBy(zonalVSwitchs, func(_ string, vSwitch *vswitch.VSwitch) bool { return vSwitch.ID == tea.StringValue(config.VSwitchId) })
+		runInstancesRequest, ok := p.toRunInstancesRequest(nodeClass, request, config, zoneID)
+		if !ok {
+			continue
+		}
+		launchResult := &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{
+			InstanceType: config.InstanceType,
+			SpotStrategy: tea.String(lo.Ternary(runInstancesRequest.SpotStrategy != nil, tea.StringValue(runInstancesRequest.SpotStrategy), "NoSpot")),
+			ZoneId:       tea.String(zoneID),
+		}
 
-func (p *DefaultProvider) updateUnavailableOfferingsCache(ctx context.Context, resp *ecsclient.CreateAutoProvisioningGroupResponse, capacityType string) {
+		output, err := p.ecsClient.RunInstancesWithOptions(runInstancesRequest, &util.RuntimeOptions{})
+		if err != nil {
+			// The other instance types and zones may still have capacity
+			aliErr, ok := alierrors.AsError(err)
+			if !ok || (aliErr.Category != alierrors.CategoryInsufficientCapacity && aliErr.Category != alierrors.CategoryIPExhausted) {
+				return nil, fmt.Errorf("running instances, %w", err)
+			}
+			log.FromContext(ctx).WithValues("instance-type", tea.StringValue(config.InstanceType), "zone", zoneID).V(1).
+				Info("failed to run instance, trying the next instance type", "error", aliErr.Error())
+			launchResult.ErrorCode = tea.String(aliErr.Code)
+			launchResult.ErrorMsg = tea.String(aliErr.Message)
+			failed = append(failed, launchResult)
+			continue
+		} else if output == nil || output.Body == nil {
+			return nil, fmt.Errorf("unexpected null value was returned")
+		} else if output.Body.InstanceIdSets == nil || len(output.Body.InstanceIdSets.InstanceIdSet) == 0 {
+			return nil, alierrors.WithRequestID(tea.StringValue(output.Body.RequestId), fmt.Errorf("unexpected null value was returned"))
+		}
+
+		launchResult.Amount = tea.Int32(1)
+		launchResult.InstanceIds = &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResultInstanceIds{
+			InstanceId: output.Body.InstanceIdSets.InstanceIdSet,
+		}
+		return runInstancesResponse(tea.StringValue(output.Body.RequestId), append([]*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{launchResult}, failed...)), nil
+	}
+	if len(failed) == 0 {
+		return nil, cloudprovider.NewInsufficientCapacityError(errors.New("none of the instance types can be placed as the ECSNodeClass requires"))
+	}
+	return runInstancesResponse("", failed), nil
+}
+
+func runInstancesResponse(requestID string, launchResults []*ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult) *ecsclient.CreateAutoProvisioningGroupResponse {
+	return &ecsclient.CreateAutoProvisioningGroupResponse{
+		StatusCode: tea.Int32(http.StatusOK),
+		Body: &ecsclient.CreateAutoProvisioningGroupResponseBody{
+			RequestId:     tea.String(requestID),
+			LaunchResults: &ecsclient.CreateAutoProvisioningGroupResponseBodyLaunchResults{LaunchResult: launchResults},
+		},
+	}
+}
+
+// toRunInstancesRequest converts the launch template config of the auto provisioning group into a RunInstances
+// request placing the instance as the ECSNodeClass requires, false is returned when the instance type can't be placed
+// in the zone. RunInstances only accepts a single disk category, the first one of the disks is used.
+func (p *DefaultProvider) toRunInstancesRequest(nodeClass *v1alpha1.ECSNodeClass, request *ecsclient.CreateAutoProvisioningGroupRequest,
+	config *ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig, zoneID string) (*ecsclient.RunInstancesRequest, bool) {
+	launchConfiguration := request.LaunchConfiguration
+	runInstancesRequest := &ecsclient.RunInstancesRequest{
+		RegionId:                request.RegionId,
+		InstanceType:            config.InstanceType,
+		VSwitchId:               config.VSwitchId,
+		InstanceChargeType:      tea.String("PostPaid"),
+		Amount:                  tea.Int32(1),
+		MinAmount:               tea.Int32(1),
+		ImageId:                 lo.Ternary(config.ImageId != nil, config.ImageId, launchConfiguration.ImageId),
+		UserData:                launchConfiguration.UserData,
+		ResourceGroupId:         launchConfiguration.ResourceGroupId,
+		SecurityGroupIds:        launchConfiguration.SecurityGroupIds,
+		KeyPairName:             launchConfiguration.KeyPairName,
+		Password:                launchConfiguration.Password,
+		PasswordInherit:         launchConfiguration.PasswordInherit,
+		RamRoleName:             launchConfiguration.RamRoleName,
+		DeploymentSetId:         launchConfiguration.DeploymentSetId,
+		InternetChargeType:      launchConfiguration.InternetChargeType,
+		InternetMaxBandwidthOut: launchConfiguration.InternetMaxBandwidthOut,
+		InternetMaxBandwidthIn:  launchConfiguration.InternetMaxBandwidthIn,
+		SystemDisk: &ecsclient.RunInstancesRequestSystemDisk{
+			Size:             tea.String(strconv.Itoa(int(tea.Int32Value(launchConfiguration.SystemDiskSize)))),
+			PerformanceLevel: launchConfiguration.SystemDiskPerformanceLevel,
+		},
+		Tag: lo.Map(launchConfiguration.Tag, func(tag *ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationTag, _ int) *ecsclient.RunInstancesRequestTag {
+			return &ecsclient.RunInstancesRequestTag{Key: tag.Key, Value: tag.Value}
+		}),
+	}
+	if len(request.SystemDiskConfig) != 0 {
+		runInstancesRequest.SystemDisk.Category = request.SystemDiskConfig[0].DiskCategory
+	}
+	if systemDisk := launchConfiguration.SystemDisk; systemDisk != nil {
+		runInstancesRequest.SystemDisk.Encrypted = systemDisk.Encrypted
+		runInstancesRequest.SystemDisk.KMSKeyId = systemDisk.KMSKeyId
+		runInstancesRequest.SystemDisk.ProvisionedIops = systemDisk.ProvisionedIops
+		runInstancesRequest.SystemDisk.BurstingEnabled = systemDisk.BurstingEnabled
+	}
+	for _, dataDisk := range launchConfiguration.DataDisk {
+		disk := &ecsclient.RunInstancesRequestDataDisk{
+			Category:           dataDisk.Category,
+			Size:               dataDisk.Size,
+			Device:             dataDisk.Device,
+			PerformanceLevel:   dataDisk.PerformanceLevel,
+			ProvisionedIops:    dataDisk.ProvisionedIops,
+			BurstingEnabled:    dataDisk.BurstingEnabled,
+			KMSKeyId:           dataDisk.KmsKeyId,
+			SnapshotId:         dataDisk.SnapshotId,
+			DeleteWithInstance: dataDisk.DeleteWithInstance,
+		}
+		if dataDisk.Encrypted != nil {
+			disk.Encrypted = tea.String(strconv.FormatBool(*dataDisk.Encrypted))
+		}
+		runInstancesRequest.DataDisk = append(runInstancesRequest.DataDisk, disk)
+	}
+
+	if tea.StringValue(request.SpotTargetCapacity) == "1" {
+		runInstancesRequest.SpotStrategy = tea.String("SpotAsPriceGo")
+		if config.MaxPrice != nil {
+			runInstancesRequest.SpotStrategy = tea.String("SpotWithPriceLimit")
+			runInstancesRequest.SpotPriceLimit = tea.Float32(float32(*config.MaxPrice))
+		}
+		// RunInstances capitalizes the interruption behaviors
+		if request.SpotInstanceInterruptionBehavior != nil {
+			runInstancesRequest.SpotInterruptionBehavior = tea.String(lo.Capitalize(*request.SpotInstanceInterruptionBehavior))
+		}
+		if nodeClass.Spec.SpotOptions != nil {
+			runInstancesRequest.SpotDuration = nodeClass.Spec.SpotOptions.Duration
+		}
+	}
+	if request.ResourcePoolOptions != nil && tea.StringValue(request.ResourcePoolOptions.Strategy) == "PrivatePoolOnly" {
+		privatePoolIDs := p.reservedPrivatePoolIDs(nodeClass, tea.StringValue(config.InstanceType), zoneID)
+		if len(privatePoolIDs) == 0 {
+			return nil, false
+		}
+		runInstancesRequest.PrivatePoolOptions = &ecsclient.RunInstancesRequestPrivatePoolOptions{
+			MatchCriteria: tea.String("Target"),
+			Id:            tea.String(privatePoolIDs[0]),
+		}
+	}
+
+	runInstancesRequest.Ipv6AddressCount = nodeClass.Spec.IPv6AddressCount
+	placement := nodeClass.Spec.Placement
+	if placement != nil && placement.HPCClusterID != "" {
+		runInstancesRequest.HpcClusterId = tea.String(placement.HPCClusterID)
+	}
+	if !placement.OnDedicatedHosts() {
+		return runInstancesRequest, true
+	}
+	hosts := lo.Filter(nodeClass.Status.DedicatedHosts, func(host v1alpha1.DedicatedHost, _ int) bool {
+		return host.ZoneID == zoneID && lo.Contains(host.InstanceTypes, tea.StringValue(config.InstanceType))
+	})
+	if len(hosts) == 0 {
+		return nil, false
+	}
+	runInstancesRequest.Tenancy = tea.String(v1alpha1.TenancyHost)
+	if placement.Affinity != "" {
+		runInstancesRequest.Affinity = tea.String(placement.Affinity)
+	}
+	switch {
+	case placement.DedicatedHostID != "":
+		runInstancesRequest.DedicatedHostId = tea.String(placement.DedicatedHostID)
+	case len(placement.DedicatedHostClusterSelectorTerms) != 0:
+		// ECS picks the host within the cluster, the cluster of the host with the most vCPUs left is preferred
+		host := lo.MaxBy(hosts, func(a, b v1alpha1.DedicatedHost) bool { return a.AvailableVCPUs > b.AvailableVCPUs })
+		runInstancesRequest.SchedulerOptions = &ecsclient.RunInstancesRequestSchedulerOptions{DedicatedHostClusterId: tea.String(host.ClusterID)}
+	}
+	return runInstancesRequest, true
+}
+
+// updateUnavailableOfferingsCache marks the offerings out of capacity as unavailable and stops launching into the
+// vSwitches out of IPs
+func (p *DefaultProvider) updateUnavailableOfferingsCache(ctx context.Context, resp *ecsclient.CreateAutoProvisioningGroupResponse, capacityType string,
+	zonalVSwitchs map[string]*vswitch.VSwitch) {
 	if resp == nil || resp.Body == nil || resp.Body.LaunchResults == nil || len(resp.Body.LaunchResults.LaunchResult) == 0 {
 		return
 	}
 
-	for lri := range resp.Body.LaunchResults.LaunchResult {
-		if resp.Body.LaunchResults.LaunchResult[lri] == nil {
+	for _, launchResult := range resp.Body.LaunchResults.LaunchResult {
+		if launchResult == nil {
 			continue
 		}
 
-		if (tea.StringValue(resp.Body.LaunchResults.LaunchResult[lri].ErrorCode) == ErrCodeNoInstanceStock || tea.StringValue(resp.Body.LaunchResults.LaunchResult[lri].ErrorCode) == ErrCodeOperationDeniedNoStock) &&
-			tea.StringValue(resp.Body.LaunchResults.LaunchResult[lri].InstanceType) != "" &&
-			tea.StringValue(resp.Body.LaunchResults.LaunchResult[lri].ZoneId) != "" {
-			p.unavailableOfferings.MarkUnavailable(
-				ctx,
-				tea.StringValue(resp.Body.LaunchResults.LaunchResult[lri].ErrorMsg),
-				tea.StringValue(resp.Body.LaunchResults.LaunchResult[lri].InstanceType),
-				tea.StringValue(resp.Body.LaunchResults.LaunchResult[lri].ZoneId),
-				capacityType)
+		instanceType := tea.StringValue(launchResult.InstanceType)
+		zoneID := tea.StringValue(launchResult.ZoneId)
+		switch alierrors.Classify(tea.StringValue(launchResult.ErrorCode)) {
+		case alierrors.CategoryInsufficientCapacity:
+			if instanceType != "" && zoneID != "" {
+				p.unavailableOfferings.MarkUnavailable(ctx, tea.StringValue(launchResult.ErrorMsg), instanceType, zoneID, capacityType)
+			}
+		case alierrors.CategoryIPExhausted:
+			if vSwitch, ok := zonalVSwitchs[zoneID]; ok {
+				log.FromContext(ctx).WithValues("vSwitch", vSwitch.ID, "zone", zoneID).Info("vSwitch ran out of IPs, excluding it from launch")
+				p.vSwitchProvider.MarkIPsExhausted(vSwitch.ID)
+			}
 		}
 	}
 }
@@ -444,33 +726,46 @@ func createAutoProvisioningGroupResponseHandler(resp *ecsclient.CreateAutoProvis
 	}
 
 	launchResult := launchResults[0]
-
-	if tea.StringValue(launchResult.ErrorCode) == ErrCodeNoInstanceStock || tea.StringValue(launchResult.ErrorCode) == ErrCodeOperationDeniedNoStock {
-		return cloudprovider.NewInsufficientCapacityError(alierrors.WithRequestID(tea.StringValue(resp.Body.RequestId),
-			fmt.Errorf("failed to launch instance: errorCode=%s, errorMessage=%s",
-				tea.StringValue(launchResult.ErrorCode), tea.StringValue(launchResult.ErrorMsg))))
-	}
-
 	if launchResult.InstanceIds == nil || len(launchResult.InstanceIds.InstanceId) == 0 {
-		return alierrors.WithRequestID(tea.StringValue(resp.Body.RequestId),
-			fmt.Errorf("failed to launch instance: errorCode=%s, errorMessage=%s",
-				tea.StringValue(launchResult.ErrorCode), tea.StringValue(launchResult.ErrorMsg)))
+		aliErr := alierrors.New(tea.StringValue(launchResult.ErrorCode), tea.StringValue(launchResult.ErrorMsg))
+		return launchError(alierrors.WithRequestID(tea.StringValue(resp.Body.RequestId),
+			fmt.Errorf("failed to launch instance: %w", aliErr)), aliErr)
 	}
 
 	return nil
 }
 
-// getCapacityType selects spot if both constraints are flexible and there is an
+// launchError wraps err into the error Karpenter acts on according to the category of the Alibaba Cloud error
+func launchError(err error, aliErr *alierrors.Error) error {
+	switch aliErr.Category {
+	case alierrors.CategoryInsufficientCapacity, alierrors.CategoryIPExhausted:
+		return alierrors.Attach(cloudprovider.NewInsufficientCapacityError(err), aliErr)
+	case alierrors.CategoryNodeClassMisconfigured:
+		return alierrors.Attach(cloudprovider.NewNodeClassNotReadyError(err), aliErr)
+	case alierrors.CategoryFatal:
+		return alierrors.Attach(cloudprovider.NewCreateError(err, string(aliErr.Category), aliErr.Error()), aliErr)
+	default:
+		// Throttled and unknown errors are retried by the next launch
+		return err
+	}
+}
+
+// getCapacityType selects reserved if it is allowed and there is an available offering, the reserved capacity is
+// already paid for. Otherwise it selects spot if both constraints are flexible and there is an
 // available offering. The Alibaba Cloud Provider defaults to [ on-demand ], so spot
 // must be explicitly included in capacity type requirements.
 func (p *DefaultProvider) getCapacityType(nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) string {
 	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
-	if requirements.Get(karpv1.CapacityTypeLabelKey).Has(karpv1.CapacityTypeSpot) {
-		requirements[karpv1.CapacityTypeLabelKey] = scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, karpv1.CapacityTypeSpot)
+	capacityTypes := requirements.Get(karpv1.CapacityTypeLabelKey)
+	for _, capacityType := range []string{v1alpha1.CapacityTypeReserved, karpv1.CapacityTypeSpot} {
+		if !capacityTypes.Has(capacityType) {
+			continue
+		}
+		requirements[karpv1.CapacityTypeLabelKey] = scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, capacityType)
 		for _, instanceType := range instanceTypes {
 			for _, offering := range instanceType.Offerings.Available() {
 				if requirements.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) == nil {
-					return karpv1.CapacityTypeSpot
+					return capacityType
 				}
 			}
 		}
@@ -506,10 +801,27 @@ func (p *DefaultProvider) getProvisioningGroup(ctx context.Context, nodeClass *v
 		return nil, errors.New("no instance types match the system disk requirements")
 	}
 
-	mappedImages := mapToInstanceTypes(instanceTypes, nodeClass.Status.Images)
-
 	requirements[karpv1.CapacityTypeLabelKey] = scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, capacityType)
+
+	// Every launch template config carries the image of its instance type, so the instance types of different
+	// architectures are offered in the same request, the instance types without a compatible image are dropped
+	imageIDs := mapToInstanceTypes(instanceTypes, nodeClass.Status.Images)
+	instanceTypes = lo.Filter(instanceTypes, func(instanceType *cloudprovider.InstanceType, _ int) bool {
+		_, ok := imageIDs[instanceType.Name]
+		return ok
+	})
+	if len(instanceTypes) == 0 {
+		return nil, errors.New("matching image not found")
+	}
+
+	prioritized := capacityType == karpv1.CapacityTypeOnDemand &&
+		nodeClass.Spec.PayAsYouGoAllocationStrategy == v1alpha1.PayAsYouGoAllocationStrategyPrioritized
+	if prioritized {
+		instanceTypes = cloudprovider.InstanceTypes(instanceTypes).OrderByPrice(requirements)
+	}
+
 	var launchTemplateConfigs []*ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig
+	var privatePoolIDs []string
 	for _, instanceType := range instanceTypes {
 		if len(launchTemplateConfigs) > maxInstanceTypes-1 {
 			break
@@ -523,13 +835,35 @@ func (p *DefaultProvider) getProvisioningGroup(ctx context.Context, nodeClass *v
 		launchTemplateConfig := &ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig{
 			InstanceType:     tea.String(instanceType.Name),
 			VSwitchId:        &vSwitchID,
+			ImageId:          tea.String(imageIDs[instanceType.Name]),
 			WeightedCapacity: tea.Float64(1),
 		}
+		// The priority follows the price ordering of Karpenter, 0 is the highest priority
+		if prioritized {
+			launchTemplateConfig.Priority = tea.Int32(int32(len(launchTemplateConfigs)))
+		}
+		if capacityType == karpv1.CapacityTypeSpot {
+			if maxPrice, ok := nodeClass.Spec.SpotOptions.GetMaxPrice(onDemandPrice(instanceType)); ok {
+				launchTemplateConfig.MaxPrice = tea.Float64(maxPrice)
+			}
+		}
+		if capacityType == v1alpha1.CapacityTypeReserved {
+			zoneID, _ := lo.FindKeyBy(zonalVSwitchs, func(_ string, vSwitch *vswitch.VSwitch) bool { return vSwitch.ID == vSwitchID })
+			ids := p.reservedPrivatePoolIDs(nodeClass, instanceType.Name, zoneID)
+			// The private pools may have been used up by the launches since the status was resolved
+			if len(ids) == 0 {
+				continue
+			}
+			privatePoolIDs = append(privatePoolIDs, ids...)
+		}
 
 		launchTemplateConfigs = append(launchTemplateConfigs, launchTemplateConfig)
 	}
 
 	if len(launchTemplateConfigs) == 0 {
+		if capacityType == v1alpha1.CapacityTypeReserved {
+			return nil, cloudprovider.NewInsufficientCapacityError(errors.New("the private pools have no capacity left for the instance types"))
+		}
 		return nil, errors.New("no capacity offerings are currently available given the constraints")
 	}
 
@@ -559,61 +893,77 @@ func (p *DefaultProvider) getProvisioningGroup(ctx context.Context, nodeClass *v
 		systemDisk.VolumeSize = imagefamily.DefaultSystemDisk.VolumeSize
 	}
 
-	imageID, ok := mappedImages[instanceTypes[0].Name]
-	if !ok {
-		return nil, errors.New("matching image not found")
-	}
-
 	// Convert bytes to GiB
 	createAutoProvisioningGroupRequest := &ecsclient.CreateAutoProvisioningGroupRequest{
 		RegionId:                        tea.String(p.region),
 		TotalTargetCapacity:             tea.String("1"),
-		SpotAllocationStrategy:          tea.String("lowest-price"),
-		PayAsYouGoAllocationStrategy:    tea.String("lowest-price"),
+		SpotAllocationStrategy:          tea.String(spotAllocationStrategy(nodeClass)),
+		PayAsYouGoAllocationStrategy:    tea.String(payAsYouGoAllocationStrategy(nodeClass)),
 		LaunchTemplateConfig:            launchTemplateConfigs,
 		ExcessCapacityTerminationPolicy: tea.String("termination"),
 		AutoProvisioningGroupType:       tea.String("instant"),
 		LaunchConfiguration: &ecsclient.CreateAutoProvisioningGroupRequestLaunchConfiguration{
-			// TODO: we should set image id for each instance types after alibabacloud supports
-			ImageId:          tea.String(imageID),
-			UserData:         tea.String(userData),
-			ResourceGroupId:  tea.String(nodeClass.Spec.ResourceGroupID),
-			SecurityGroupIds: securityGroupIDs,
-			SystemDiskSize:   tea.Int32(systemDisk.GetGiBSize()),
-			Tag:              reqTags,
-			KeyPairName:      tea.String(nodeClass.Spec.KeyPairName),
-			Password:         tea.String(nodeClass.Spec.Password),
-			PasswordInherit:  tea.Bool(nodeClass.Spec.PasswordInherit),
+			// The launch configuration requires an image, the launch template configs override it
+			ImageId:                    launchTemplateConfigs[0].ImageId,
+			UserData:                   tea.String(userData),
+			ResourceGroupId:            tea.String(nodeClass.Spec.ResourceGroupID),
+			SecurityGroupIds:           securityGroupIDs,
+			SystemDiskSize:             tea.Int32(systemDisk.GetGiBSize()),
+			SystemDiskPerformanceLevel: systemDisk.GetPerformanceLevel(),
+			SystemDisk:                 launchSystemDisk(systemDisk),
+			Tag:                        reqTags,
+			KeyPairName:                tea.String(nodeClass.Spec.KeyPairName),
+			Password:                   tea.String(nodeClass.Spec.Password),
+			PasswordInherit:            tea.Bool(nodeClass.Spec.PasswordInherit),
 		},
 		// Add this tag to auto-provisioning-group, alibabacloud will monitor the requests and enhance the stability
 		Tag: []*ecsclient.CreateAutoProvisioningGroupRequestTag{
 			{Key: tea.String(apis.Group + "/autoprovisiongroup"), Value: tea.String("true")},
 		},
 
-		SystemDiskConfig: lo.Map(systemDisk.Categories, func(category string, _ int) *ecsclient.CreateAutoProvisioningGroupRequestSystemDiskConfig {
+		SystemDiskConfig: lo.Map(systemDisk.GetCategories(), func(category string, _ int) *ecsclient.CreateAutoProvisioningGroupRequestSystemDiskConfig {
 			return &ecsclient.CreateAutoProvisioningGroupRequestSystemDiskConfig{
 				DiskCategory: &category,
 			}
 		}),
 	}
 
-	if nodeClass.Spec.DataDisks != nil && nodeClass.Spec.DataDisksCategories != nil {
-		createAutoProvisioningGroupRequest.LaunchConfiguration.DataDisk = lo.Map(nodeClass.Spec.DataDisks, func(dataDisk v1alpha1.DataDisk, index int) *ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationDataDisk {
-			return &ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationDataDisk{
-				Size:   tea.Int32(max(defaultDataDiskSize, dataDisk.GetGiBSize())),
-				Device: dataDisk.Device,
-			}
-		})
-		createAutoProvisioningGroupRequest.DataDiskConfig = lo.Map(nodeClass.Spec.DataDisksCategories, func(category string, index int) *ecsclient.CreateAutoProvisioningGroupRequestDataDiskConfig {
-			return &ecsclient.CreateAutoProvisioningGroupRequestDataDiskConfig{
-				DiskCategory: &category,
-			}
-		})
+	if nodeClass.Spec.RAMRole != "" {
+		createAutoProvisioningGroupRequest.LaunchConfiguration.RamRoleName = tea.String(nodeClass.Spec.RAMRole)
+	}
+
+	if nodeClass.Spec.DeploymentSetSelector != nil {
+		if nodeClass.Status.DeploymentSet == nil {
+			return nil, errors.New("deployment set is not resolved yet")
+		}
+		createAutoProvisioningGroupRequest.LaunchConfiguration.DeploymentSetId = tea.String(nodeClass.Status.DeploymentSet.ID)
+	}
+
+	// A public IP address is assigned when the outbound bandwidth isn't zero
+	if lo.FromPtr(nodeClass.Spec.AssociatePublicIPAddress) {
+		createAutoProvisioningGroupRequest.LaunchConfiguration.InternetChargeType = tea.String(lo.FromPtrOr(nodeClass.Spec.InternetChargeType, v1alpha1.InternetChargeTypePayByTraffic))
+		createAutoProvisioningGroupRequest.LaunchConfiguration.InternetMaxBandwidthOut = tea.Int32(lo.FromPtrOr(nodeClass.Spec.InternetMaxBandwidthOut, defaultInternetMaxBandwidthOut))
+		createAutoProvisioningGroupRequest.LaunchConfiguration.InternetMaxBandwidthIn = nodeClass.Spec.InternetMaxBandwidthIn
+	}
+
+	if len(nodeClass.Spec.DataDisks) != 0 {
+		createAutoProvisioningGroupRequest.LaunchConfiguration.DataDisk, createAutoProvisioningGroupRequest.DataDiskConfig = dataDisks(nodeClass)
+	}
+
+	// The reserved instances are pay-as-you-go instances launched into the private pools
+	if capacityType == v1alpha1.CapacityTypeReserved {
+		createAutoProvisioningGroupRequest.ResourcePoolOptions = &ecsclient.CreateAutoProvisioningGroupRequestResourcePoolOptions{
+			Strategy:       tea.String("PrivatePoolOnly"),
+			PrivatePoolIds: tea.StringSlice(lo.Uniq(privatePoolIDs)),
+		}
 	}
 
 	if capacityType == karpv1.CapacityTypeSpot {
 		createAutoProvisioningGroupRequest.SpotTargetCapacity = tea.String("1")
 		createAutoProvisioningGroupRequest.PayAsYouGoTargetCapacity = tea.String("0")
+		if spotOptions := nodeClass.Spec.SpotOptions; spotOptions != nil && spotOptions.InterruptionBehavior != "" {
+			createAutoProvisioningGroupRequest.SpotInstanceInterruptionBehavior = tea.String(spotOptions.InterruptionBehavior)
+		}
 	} else {
 		createAutoProvisioningGroupRequest.SpotTargetCapacity = tea.String("0")
 		createAutoProvisioningGroupRequest.PayAsYouGoTargetCapacity = tea.String("1")
@@ -622,6 +972,158 @@ func (p *DefaultProvider) getProvisioningGroup(ctx context.Context, nodeClass *v
 	return createAutoProvisioningGroupRequest, nil
 }
 
+// reservedPrivatePoolIDs returns the IDs of the private pools of the ECSNodeClass with capacity left for the instance
+// type in the zone
+func (p *DefaultProvider) reservedPrivatePoolIDs(nodeClass *v1alpha1.ECSNodeClass, instanceType, zoneID string) []string {
+	p.muInflightReservations.Lock()
+	defer p.muInflightReservations.Unlock()
+
+	return lo.FilterMap(nodeClass.Status.CapacityReservations, func(cr v1alpha1.CapacityReservation, _ int) (string, bool) {
+		return cr.ID, cr.InstanceType == instanceType && cr.ZoneID == zoneID && p.availableInstanceCount(cr) > 0
+	})
+}
+
+// trackReservedLaunch deducts the launched instance from the private pools of the instance type in the zone, so the
+// launches don't exceed the reserved capacity before it is resolved into the status again. The pool the instance
+// went into isn't returned, it is deducted from the first pool with capacity left, the order the pools are offered in.
+func (p *DefaultProvider) trackReservedLaunch(ctx context.Context, nodeClass *v1alpha1.ECSNodeClass, instanceType, zoneID string) {
+	p.muInflightReservations.Lock()
+	defer p.muInflightReservations.Unlock()
+
+	capacityReservations := lo.Filter(nodeClass.Status.CapacityReservations, func(cr v1alpha1.CapacityReservation, _ int) bool {
+		return cr.InstanceType == instanceType && cr.ZoneID == zoneID && p.availableInstanceCount(cr) > 0
+	})
+	if len(capacityReservations) == 0 {
+		return
+	}
+	cr := capacityReservations[0]
+	key := inflightReservationKey(cr)
+	inflight, ok := p.inflightReservations[key]
+	if !ok || inflight.availableInstanceCount != cr.AvailableInstanceCount {
+		inflight = inflightReservation{availableInstanceCount: cr.AvailableInstanceCount}
+	}
+	inflight.launched++
+	p.inflightReservations[key] = inflight
+
+	// The reserved offering is left out of scheduling once the private pools are used up
+	if len(capacityReservations) == 1 && p.availableInstanceCount(cr) <= 0 {
+		p.unavailableOfferings.MarkUnavailableWithTTL(ctx, "ReservedCapacityUsedUp", instanceType, zoneID,
+			v1alpha1.CapacityTypeReserved, reservedCapacityUsedUpTTL)
+	}
+}
+
+// availableInstanceCount returns the available instance count of the status minus the instances launched since it was
+// resolved, the launches are forgotten once the status count changes
+func (p *DefaultProvider) availableInstanceCount(cr v1alpha1.CapacityReservation) int32 {
+	inflight, ok := p.inflightReservations[inflightReservationKey(cr)]
+	if !ok || inflight.availableInstanceCount != cr.AvailableInstanceCount {
+		return cr.AvailableInstanceCount
+	}
+	return cr.AvailableInstanceCount - inflight.launched
+}
+
+func inflightReservationKey(cr v1alpha1.CapacityReservation) string {
+	return fmt.Sprintf("%s/%s/%s", cr.ID, cr.InstanceType, cr.ZoneID)
+}
+
+// launchSystemDisk maps the encryption and the provisioned performance of the system disk to the launch
+// configuration, nil is returned when none of them is set
+func launchSystemDisk(systemDisk *v1alpha1.SystemDisk) *ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationSystemDisk {
+	if systemDisk.Encrypted == nil && systemDisk.KMSKeyID == nil && systemDisk.ProvisionedIOPS == nil && systemDisk.BurstingEnabled == nil {
+		return nil
+	}
+	launchSystemDisk := &ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationSystemDisk{
+		KMSKeyId:        systemDisk.KMSKeyID,
+		ProvisionedIops: systemDisk.ProvisionedIOPS,
+		BurstingEnabled: systemDisk.BurstingEnabled,
+	}
+	if systemDisk.Encrypted != nil {
+		launchSystemDisk.Encrypted = tea.String(strconv.FormatBool(*systemDisk.Encrypted))
+	}
+	if systemDisk.KMSKeyID != nil {
+		launchSystemDisk.Encrypted = tea.String("true")
+	}
+	return launchSystemDisk
+}
+
+// dataDisks maps the data disks of the ECSNodeClass to the launch configuration. Each data disk is created with its
+// first category, the auto provisioning group only accepts a single list of fallback categories for all the data disks,
+// so it is only sent when every data disk has the same categories. Otherwise a data disk could fall back to the
+// category of another one.
+func dataDisks(nodeClass *v1alpha1.ECSNodeClass) ([]*ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationDataDisk,
+	[]*ecsclient.CreateAutoProvisioningGroupRequestDataDiskConfig) {
+	categories := lo.Map(nodeClass.Spec.DataDisks, func(dataDisk v1alpha1.DataDisk, _ int) []string {
+		if len(dataDisk.Categories) == 0 {
+			return nodeClass.Spec.DataDisksCategories
+		}
+		return dataDisk.Categories
+	})
+	disks := lo.Map(nodeClass.Spec.DataDisks, func(dataDisk v1alpha1.DataDisk, i int) *ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationDataDisk {
+		diskCategories := categories[i]
+
+		disk := &ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationDataDisk{
+			Size:               tea.Int32(max(defaultDataDiskSize, dataDisk.GetGiBSize())),
+			Device:             dataDisk.Device,
+			PerformanceLevel:   dataDisk.PerformanceLevel,
+			ProvisionedIops:    dataDisk.ProvisionedIOPS,
+			BurstingEnabled:    dataDisk.BurstingEnabled,
+			Encrypted:          dataDisk.Encrypted,
+			KmsKeyId:           dataDisk.KMSKeyID,
+			SnapshotId:         dataDisk.SnapshotID,
+			DeleteWithInstance: dataDisk.DeleteWithInstance,
+		}
+		if len(diskCategories) != 0 {
+			disk.Category = tea.String(diskCategories[0])
+		}
+		if dataDisk.KMSKeyID != nil {
+			disk.Encrypted = tea.Bool(true)
+		}
+		return disk
+	})
+
+	if len(categories) == 0 || !lo.EveryBy(categories, func(diskCategories []string) bool { return slices.Equal(diskCategories, categories[0]) }) {
+		return disks, nil
+	}
+	return disks, lo.Map(lo.Uniq(categories[0]), func(category string, _ int) *ecsclient.CreateAutoProvisioningGroupRequestDataDiskConfig {
+		return &ecsclient.CreateAutoProvisioningGroupRequestDataDiskConfig{
+			DiskCategory: tea.String(category),
+		}
+	})
+}
+
+// spotAllocationStrategy maps the spot allocation strategy of the ECSNodeClass to the one of the auto provisioning group
+func spotAllocationStrategy(nodeClass *v1alpha1.ECSNodeClass) string {
+	switch nodeClass.Spec.SpotAllocationStrategy {
+	case v1alpha1.SpotAllocationStrategyDiversified:
+		return "diversified"
+	case v1alpha1.SpotAllocationStrategyPriceCapacityOptimized:
+		// The launch template configs only contain the cheapest instance types Karpenter truncated to, the auto
+		// provisioning group picks the one with the most capacity among them
+		return "capacity-optimized"
+	default:
+		return "lowest-price"
+	}
+}
+
+// payAsYouGoAllocationStrategy maps the pay-as-you-go allocation strategy of the ECSNodeClass to the one of the
+// auto provisioning group
+func payAsYouGoAllocationStrategy(nodeClass *v1alpha1.ECSNodeClass) string {
+	if nodeClass.Spec.PayAsYouGoAllocationStrategy == v1alpha1.PayAsYouGoAllocationStrategyPrioritized {
+		return "prioritized"
+	}
+	return "lowest-price"
+}
+
+// onDemandPrice returns the on-demand price of the instance type, 0 is returned when it has no on-demand offering
+func onDemandPrice(instanceType *cloudprovider.InstanceType) float64 {
+	for _, offering := range instanceType.Offerings {
+		if offering.Requirements.Get(karpv1.CapacityTypeLabelKey).Any() == karpv1.CapacityTypeOnDemand {
+			return offering.Price
+		}
+	}
+	return 0
+}
+
 func (p *DefaultProvider) checkODFallback(nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) error {
 	// only evaluate for on-demand fallback if the capacity type for the request is OD and both OD and spot are allowed in requirements
 	if p.getCapacityType(nodeClaim, instanceTypes) != karpv1.CapacityTypeOnDemand ||
@@ -642,6 +1144,19 @@ func (p *DefaultProvider) getVSwitchID(instanceType *cloudprovider.InstanceType,
 	cheapestVSwitchID := ""
 	cheapestPrice := math.MaxFloat64
 
+	offerings := instanceType.Offerings
+	// The reserved capacity is only left in the zones of the available reserved offerings, the other zones are
+	// dropped before any zone is selected
+	if capacityType == v1alpha1.CapacityTypeReserved {
+		offerings = offerings.Available()
+		zonalVSwitchs = lo.PickBy(zonalVSwitchs, func(zoneID string, _ *vswitch.VSwitch) bool {
+			return lo.ContainsBy(offerings, func(offering cloudprovider.Offering) bool {
+				return offering.Requirements.Get(corev1.LabelTopologyZone).Has(zoneID) &&
+					reqs.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) == nil
+			})
+		})
+	}
+
 	if capacityType == karpv1.CapacityTypeOnDemand || vSwitchSelectionPolicy == v1alpha1.VSwitchSelectionPolicyBalanced {
 		// For on-demand, randomly select a zone's vswitch
 		zoneIDs := lo.Keys(zonalVSwitchs)
@@ -652,18 +1167,18 @@ func (p *DefaultProvider) getVSwitchID(instanceType *cloudprovider.InstanceType,
 	}
 
 	// For different AZ, the spot price may differ. So we need to get the cheapest vSwitch in the zone
-	for i := range instanceType.Offerings {
-		if reqs.Compatible(instanceType.Offerings[i].Requirements, scheduling.AllowUndefinedWellKnownLabels) != nil {
+	for i := range offerings {
+		if reqs.Compatible(offerings[i].Requirements, scheduling.AllowUndefinedWellKnownLabels) != nil {
 			continue
 		}
 
-		vswitch, ok := zonalVSwitchs[instanceType.Offerings[i].Requirements.Get(corev1.LabelTopologyZone).Any()]
+		vswitch, ok := zonalVSwitchs[offerings[i].Requirements.Get(corev1.LabelTopologyZone).Any()]
 		if !ok {
 			continue
 		}
-		if instanceType.Offerings[i].Price < cheapestPrice {
+		if offerings[i].Price < cheapestPrice {
 			cheapestVSwitchID = vswitch.ID
-			cheapestPrice = instanceType.Offerings[i].Price
+			cheapestPrice = offerings[i].Price
 		}
 	}
 
@@ -688,7 +1203,12 @@ func (p *DefaultProvider) buildUserData(ctx context.Context, capacityType string
 	}) {
 		taints = append(taints, karpv1.UnregisteredNoExecuteTaint)
 	}
-	return p.clusterProvider.UserData(ctx, labels, taints, kubeletCfg, nodeClass.Spec.UserData, nodeClass.Spec.FormatDataDisk)
+	ipFamilies := []corev1.IPFamily{corev1.IPv4Protocol}
+	if nodeClass.Spec.IPv6AddressCount != nil {
+		ipFamilies = append(ipFamilies, corev1.IPv6Protocol)
+	}
+	return p.clusterProvider.UserData(ctx, labels, taints, kubeletCfg, nodeClass.Spec.UserData, nodeClass.Spec.FormatDataDisk,
+		nodeClass.Spec.InstanceStorePolicy, ipFamilies)
 }
 
 func resolveKubeletConfiguration(nodeClass *v1alpha1.ECSNodeClass) *v1alpha1.KubeletConfiguration {
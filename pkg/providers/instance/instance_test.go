@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+)
+
+func newTestInstanceType(name, arch string, price float64) *cloudprovider.InstanceType {
+	return &cloudprovider.InstanceType{
+		Name: name,
+		Requirements: scheduling.NewRequirements(
+			scheduling.NewRequirement(corev1.LabelInstanceTypeStable, corev1.NodeSelectorOpIn, name),
+			scheduling.NewRequirement(corev1.LabelArchStable, corev1.NodeSelectorOpIn, arch),
+		),
+		Offerings: cloudprovider.Offerings{
+			{
+				Requirements: scheduling.NewRequirements(
+					scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, karpv1.CapacityTypeOnDemand),
+					scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, "cn-hangzhou-i"),
+				),
+				Price:     price,
+				Available: true,
+			},
+		},
+	}
+}
+
+func newTestImage(id, arch string) v1alpha1.Image {
+	return v1alpha1.Image{
+		ID: id,
+		Requirements: []corev1.NodeSelectorRequirement{
+			{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{arch}},
+		},
+	}
+}
+
+func Test_mapToInstanceTypes(t *testing.T) {
+	images := []v1alpha1.Image{
+		newTestImage("amd64-image", karpv1.ArchitectureAmd64),
+		newTestImage("arm64-image", karpv1.ArchitectureArm64),
+	}
+
+	tests := []struct {
+		name          string
+		instanceTypes []*cloudprovider.InstanceType
+		images        []v1alpha1.Image
+		expected      map[string]string
+	}{
+		{
+			name: "both architectures",
+			instanceTypes: []*cloudprovider.InstanceType{
+				newTestInstanceType("ecs.g8y.large", karpv1.ArchitectureArm64, 0.1),
+				newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 0.2),
+			},
+			images:   images,
+			expected: map[string]string{"ecs.g8y.large": "arm64-image", "ecs.g7.large": "amd64-image"},
+		},
+		{
+			name: "arm64 instance types are dropped without an arm64 image",
+			instanceTypes: []*cloudprovider.InstanceType{
+				newTestInstanceType("ecs.g8y.large", karpv1.ArchitectureArm64, 0.1),
+				newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 0.2),
+			},
+			images:   images[:1],
+			expected: map[string]string{"ecs.g7.large": "amd64-image"},
+		},
+		{
+			name: "no compatible image",
+			instanceTypes: []*cloudprovider.InstanceType{
+				newTestInstanceType("ecs.g8y.large", karpv1.ArchitectureArm64, 0.1),
+			},
+			images:   images[:1],
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, mapToInstanceTypes(tt.instanceTypes, tt.images))
+		})
+	}
+}
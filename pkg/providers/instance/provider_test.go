@@ -0,0 +1,956 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	kcache "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/cache"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/cluster"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/imagefamily"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/vswitch"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
+)
+
+const testClusterID = "c-test"
+
+type testEnv struct {
+	ctx                  context.Context
+	vpcAPI               *fake.VPCAPI
+	ecsAPI               *fake.ECSAPI
+	unavailableOfferings *kcache.UnavailableOfferings
+	provider             *DefaultProvider
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	ctx := options.ToContext(context.Background(), &options.Options{ClusterID: testClusterID, APGCreationQPS: 100})
+	vpcAPI := fake.NewVPCAPI()
+	vpcAPI.AddVSwitches(fake.VSwitch{ID: "vsw-test", ZoneID: "cn-hangzhou-i", AvailableIPAddressCount: 100})
+	ecsAPI := fake.NewECSAPI(vpcAPI)
+	unavailableOfferings := kcache.NewUnavailableOfferings()
+
+	return &testEnv{
+		ctx:                  ctx,
+		vpcAPI:               vpcAPI,
+		ecsAPI:               ecsAPI,
+		unavailableOfferings: unavailableOfferings,
+		provider: NewDefaultProvider(ctx, fake.DefaultRegion, ecsAPI, unavailableOfferings,
+			imagefamily.NewDefaultResolver(fake.DefaultRegion, ecsAPI, cache.New(cache.NoExpiration, cache.NoExpiration)),
+			vswitch.NewDefaultProvider(fake.DefaultRegion, vpcAPI, cache.New(cache.NoExpiration, cache.NoExpiration),
+				cache.New(cache.NoExpiration, cache.NoExpiration)),
+			cluster.NewCustom()),
+	}
+}
+
+func newTestNodeClass() *v1alpha1.ECSNodeClass {
+	return &v1alpha1.ECSNodeClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Status: v1alpha1.ECSNodeClassStatus{
+			VSwitches:      []v1alpha1.VSwitch{{ID: "vsw-test", ZoneID: "cn-hangzhou-i"}},
+			SecurityGroups: []v1alpha1.SecurityGroup{{ID: "sg-test"}},
+			Images:         []v1alpha1.Image{newTestImage("amd64-image", karpv1.ArchitectureAmd64)},
+		},
+	}
+}
+
+func newTestNodeClaim() *karpv1.NodeClaim {
+	return &karpv1.NodeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "default-abcde",
+			Labels: map[string]string{karpv1.NodePoolLabelKey: "default"},
+		},
+		Spec: karpv1.NodeClaimSpec{
+			Requirements: []karpv1.NodeSelectorRequirementWithMinValues{{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      karpv1.CapacityTypeLabelKey,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{karpv1.CapacityTypeOnDemand},
+				},
+			}},
+		},
+	}
+}
+
+func TestDefaultProvider_Create(t *testing.T) {
+	env := newTestEnv(t)
+	instanceTypes := []*cloudprovider.InstanceType{
+		newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1),
+		newTestInstanceType("ecs.g7.xlarge", karpv1.ArchitectureAmd64, 2),
+	}
+
+	instance, err := env.provider.Create(env.ctx, newTestNodeClass(), newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+	assert.Equal(t, "ecs.g7.large", instance.Type)
+	assert.Equal(t, "cn-hangzhou-i", instance.Zone)
+	assert.Equal(t, karpv1.CapacityTypeOnDemand, instance.CapacityType)
+	assert.Equal(t, "amd64-image", instance.ImageID)
+
+	launched, ok := env.ecsAPI.Instance(instance.ID)
+	require.True(t, ok)
+	assert.Equal(t, "vsw-test", tea.StringValue(launched.VpcAttributes.VSwitchId))
+	vSwitch, _ := env.vpcAPI.VSwitch("vsw-test")
+	assert.Equal(t, int64(99), vSwitch.AvailableIPAddressCount)
+
+	requests := env.ecsAPI.CreateAutoProvisioningGroupRequests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "1", tea.StringValue(requests[0].PayAsYouGoTargetCapacity))
+	assert.Equal(t, "0", tea.StringValue(requests[0].SpotTargetCapacity))
+}
+
+func TestDefaultProvider_CreateInsufficientCapacity(t *testing.T) {
+	env := newTestEnv(t)
+	instanceTypes := []*cloudprovider.InstanceType{
+		newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1),
+		newTestInstanceType("ecs.g7.xlarge", karpv1.ArchitectureAmd64, 2),
+	}
+	env.ecsAPI.AddInsufficientCapacityPools(fake.CapacityPool{
+		InstanceType: "ecs.g7.large", ZoneID: "cn-hangzhou-i", CapacityType: karpv1.CapacityTypeOnDemand,
+	})
+
+	instance, err := env.provider.Create(env.ctx, newTestNodeClass(), newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+	assert.Equal(t, "ecs.g7.xlarge", instance.Type)
+	assert.True(t, env.unavailableOfferings.IsUnavailable("ecs.g7.large", "cn-hangzhou-i", karpv1.CapacityTypeOnDemand))
+
+	env.ecsAPI.AddInsufficientCapacityPools(fake.CapacityPool{
+		InstanceType: "ecs.g7.xlarge", ZoneID: "cn-hangzhou-i", CapacityType: karpv1.CapacityTypeOnDemand,
+	})
+	_, err = env.provider.Create(env.ctx, newTestNodeClass(), newTestNodeClaim(), instanceTypes)
+	assert.True(t, cloudprovider.IsInsufficientCapacityError(err))
+}
+
+func TestDefaultProvider_ListGetDelete(t *testing.T) {
+	env := newTestEnv(t)
+	instanceTypes := []*cloudprovider.InstanceType{newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1)}
+
+	created, err := env.provider.Create(env.ctx, newTestNodeClass(), newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+
+	instances, err := env.provider.List(env.ctx)
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, created.ID, instances[0].ID)
+	assert.Equal(t, "owned", instances[0].Tags["kubernetes.io/cluster/"+testClusterID])
+
+	instance, err := env.provider.Get(env.ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, InstanceStatusRunning, instance.Status)
+
+	require.NoError(t, env.provider.Delete(env.ctx, created.ID))
+	_, ok := env.ecsAPI.Instance(created.ID)
+	assert.False(t, ok)
+
+	_, err = env.provider.Get(env.ctx, created.ID)
+	assert.True(t, cloudprovider.IsNodeClaimNotFoundError(err))
+
+	_, err = env.provider.Get(env.ctx, "i-unknown")
+	assert.True(t, cloudprovider.IsNodeClaimNotFoundError(err))
+}
+
+func TestDefaultProvider_Delete(t *testing.T) {
+	env := newTestEnv(t)
+	instanceTypes := []*cloudprovider.InstanceType{newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1)}
+
+	created, err := env.provider.Create(env.ctx, newTestNodeClass(), newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+	env.ecsAPI.SetInstanceStatus(created.ID, InstanceStatusPending)
+	assert.True(t, IsInstanceStateOperationNotSupportedError(env.provider.Delete(env.ctx, created.ID)))
+
+	env.ecsAPI.SetInstanceStatus(created.ID, InstanceStatusRunning)
+	require.NoError(t, env.provider.Delete(env.ctx, created.ID))
+	assert.True(t, cloudprovider.IsNodeClaimNotFoundError(env.provider.Delete(env.ctx, created.ID)))
+
+	env.ecsAPI.DeleteInstancesError.Set(fake.NewThrottlingError())
+	err = env.provider.Delete(env.ctx, "i-unknown")
+	assert.Error(t, err)
+	assert.False(t, cloudprovider.IsNodeClaimNotFoundError(err))
+}
+
+func TestDefaultProvider_DeleteBatchWithMissingInstance(t *testing.T) {
+	env := newTestEnv(t)
+	instanceIDs := []string{"i-1", "i-2", "i-3"}
+	for _, id := range instanceIDs {
+		env.ecsAPI.AddInstances(newTestECSInstance(id, nil))
+	}
+
+	// The deletions are batched, the missing instance fails the batch but not the deletion of the other instances
+	ids := append(instanceIDs, "i-missing")
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = env.provider.Delete(env.ctx, ids[i])
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs[:len(instanceIDs)] {
+		assert.NoError(t, err)
+	}
+	assert.True(t, cloudprovider.IsNodeClaimNotFoundError(errs[len(instanceIDs)]))
+	assert.Empty(t, env.ecsAPI.Instances())
+	requests := env.ecsAPI.DeleteInstancesRequests()
+	require.NotEmpty(t, requests)
+	assert.ElementsMatch(t, ids, tea.StringSliceValue(requests[0].InstanceId))
+}
+
+func newTestECSInstance(id string, tags map[string]string) *ecsclient.DescribeInstancesResponseBodyInstancesInstance {
+	return &ecsclient.DescribeInstancesResponseBodyInstancesInstance{
+		InstanceId:   tea.String(id),
+		InstanceType: tea.String("ecs.g7.large"),
+		ImageId:      tea.String("amd64-image"),
+		RegionId:     tea.String(fake.DefaultRegion),
+		ZoneId:       tea.String("cn-hangzhou-i"),
+		Status:       tea.String(InstanceStatusRunning),
+		SpotStrategy: tea.String("NoSpot"),
+		CreationTime: tea.String("2024-01-01T00:00Z"),
+		Tags: &ecsclient.DescribeInstancesResponseBodyInstancesInstanceTags{
+			Tag: lo.MapToSlice(tags, func(k, v string) *ecsclient.DescribeInstancesResponseBodyInstancesInstanceTagsTag {
+				return &ecsclient.DescribeInstancesResponseBodyInstancesInstanceTagsTag{TagKey: tea.String(k), TagValue: tea.String(v)}
+			}),
+		},
+	}
+}
+
+func instancesListedMetric(t *testing.T, discovery string) (float64, bool) {
+	t.Helper()
+	families, err := crmetrics.Registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "karpenter_cloudprovider_instances_listed" {
+			continue
+		}
+		for _, metric := range family.Metric {
+			if lo.ContainsBy(metric.Label, func(l *dto.LabelPair) bool { return l.GetName() == discoveryLabel && l.GetValue() == discovery }) {
+				return metric.GetGauge().GetValue(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestDefaultProvider_ListBeyondTagQueryLimit(t *testing.T) {
+	env := newTestEnv(t)
+	clusterTags := map[string]string{"kubernetes.io/cluster/" + testClusterID: "owned", karpv1.NodePoolLabelKey: "default"}
+
+	env.ecsAPI.AddInstances(newTestECSInstance("i-unmanaged", map[string]string{"kubernetes.io/cluster/other": "owned"}))
+	for i := range fake.MaxTagQueryResults - 1 {
+		env.ecsAPI.AddInstances(newTestECSInstance(fmt.Sprintf("i-%05d", i), clusterTags))
+	}
+	instances, err := env.provider.List(env.ctx)
+	require.NoError(t, err)
+	assert.Len(t, instances, fake.MaxTagQueryResults-1)
+	listed, ok := instancesListedMetric(t, discoveryDescribeInstances)
+	assert.True(t, ok)
+	assert.Equal(t, float64(fake.MaxTagQueryResults-1), listed)
+
+	for i := fake.MaxTagQueryResults - 1; i < 1234; i++ {
+		env.ecsAPI.AddInstances(newTestECSInstance(fmt.Sprintf("i-%05d", i), clusterTags))
+	}
+	instances, err = env.provider.List(env.ctx)
+	require.NoError(t, err)
+	assert.Len(t, instances, 1234)
+	assert.Len(t, lo.UniqBy(instances, func(instance *Instance) string { return instance.ID }), 1234)
+	assert.False(t, lo.ContainsBy(instances, func(instance *Instance) bool { return instance.ID == "i-unmanaged" }))
+	listed, ok = instancesListedMetric(t, discoveryListTagResources)
+	assert.True(t, ok)
+	assert.Equal(t, float64(1234), listed)
+	_, ok = instancesListedMetric(t, discoveryDescribeInstances)
+	assert.False(t, ok)
+
+	instance, err := env.provider.Get(env.ctx, "i-01233")
+	require.NoError(t, err)
+	assert.Equal(t, "default", instance.Tags[karpv1.NodePoolLabelKey])
+}
+
+func TestDefaultProvider_ListBeyondTagQueryLimitDescribesByID(t *testing.T) {
+	env := newTestEnv(t)
+	clusterTags := map[string]string{"kubernetes.io/cluster/" + testClusterID: "owned"}
+	for i := range 1234 {
+		env.ecsAPI.AddInstances(newTestECSInstance(fmt.Sprintf("i-%05d", i), clusterTags))
+	}
+
+	instances, err := env.provider.List(env.ctx)
+	require.NoError(t, err)
+	assert.Len(t, instances, 1234)
+	// Only the first page is described by the tag, the other instances are only described by their IDs
+	requests := env.ecsAPI.DescribeInstancesRequests()
+	byTag := lo.Filter(requests, func(request *ecsclient.DescribeInstancesRequest, _ int) bool { return len(request.Tag) != 0 })
+	require.Len(t, byTag, 1)
+	assert.Nil(t, byTag[0].NextToken)
+	assert.Len(t, requests, 1+13)
+}
+
+func TestDefaultProvider_CreateClassifiesLaunchErrors(t *testing.T) {
+	instanceTypes := []*cloudprovider.InstanceType{newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1)}
+
+	t.Run("ip exhausted", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.vpcAPI.Reset()
+		env.vpcAPI.AddVSwitches(fake.VSwitch{ID: "vsw-test", ZoneID: "cn-hangzhou-i"})
+
+		_, err := env.provider.Create(env.ctx, newTestNodeClass(), newTestNodeClaim(), instanceTypes)
+		assert.True(t, cloudprovider.IsInsufficientCapacityError(err))
+		assert.Equal(t, alierrors.CategoryIPExhausted, alierrors.CategoryOf(err))
+	})
+
+	tests := []struct {
+		name     string
+		err      error
+		category alierrors.Category
+		assert   func(error) bool
+	}{
+		{
+			name:     "misconfigured security group",
+			err:      fake.NewNotFoundError("InvalidSecurityGroupId.NotFound", "The specified security group does not exist."),
+			category: alierrors.CategoryNodeClassMisconfigured,
+			assert:   cloudprovider.IsNodeClassNotReadyError,
+		},
+		{
+			name: "not enough balance",
+			err: &tea.SDKError{Code: tea.String(alierrors.ErrCodeNotEnoughBalance), Message: tea.String("Your account does not have enough balance."),
+				StatusCode: tea.Int(http.StatusForbidden)},
+			category: alierrors.CategoryFatal,
+			assert:   isCreateError,
+		},
+		{
+			name:     "throttling",
+			err:      fake.NewThrottlingError(),
+			category: alierrors.CategoryThrottling,
+			assert: func(err error) bool {
+				return !cloudprovider.IsInsufficientCapacityError(err) && !cloudprovider.IsNodeClassNotReadyError(err) && !isCreateError(err)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.ecsAPI.CreateAutoProvisioningGroupError.Set(tt.err)
+
+			_, err := env.provider.Create(env.ctx, newTestNodeClass(), newTestNodeClaim(), instanceTypes)
+			require.Error(t, err)
+			assert.True(t, tt.assert(err))
+			assert.Equal(t, tt.category, alierrors.CategoryOf(err))
+		})
+	}
+}
+
+func isCreateError(err error) bool {
+	var createErr *cloudprovider.CreateError
+	return errors.As(err, &createErr)
+}
+
+// newTestSpotInstanceType returns an instance type whose spot price is a tenth of its on-demand price
+func newTestSpotInstanceType(name string, price float64) *cloudprovider.InstanceType {
+	instanceType := newTestInstanceType(name, karpv1.ArchitectureAmd64, price)
+	instanceType.Offerings = append(instanceType.Offerings, cloudprovider.Offering{
+		Requirements: scheduling.NewRequirements(
+			scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, karpv1.CapacityTypeSpot),
+			scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, "cn-hangzhou-i"),
+		),
+		Price:     price / 10,
+		Available: true,
+	})
+	return instanceType
+}
+
+func TestDefaultProvider_getProvisioningGroupAllocationStrategy(t *testing.T) {
+	tests := []struct {
+		name                         string
+		spotAllocationStrategy       string
+		payAsYouGoAllocationStrategy string
+		capacityType                 string
+		wantSpot                     string
+		wantPayAsYouGo               string
+		wantPriorities               []*int32
+	}{
+		{
+			name:           "defaults",
+			capacityType:   karpv1.CapacityTypeOnDemand,
+			wantSpot:       "lowest-price",
+			wantPayAsYouGo: "lowest-price",
+			wantPriorities: []*int32{nil, nil},
+		},
+		{
+			name:                   "diversified spot",
+			spotAllocationStrategy: v1alpha1.SpotAllocationStrategyDiversified,
+			capacityType:           karpv1.CapacityTypeSpot,
+			wantSpot:               "diversified",
+			wantPayAsYouGo:         "lowest-price",
+			wantPriorities:         []*int32{nil, nil},
+		},
+		{
+			name:                   "price capacity optimized spot",
+			spotAllocationStrategy: v1alpha1.SpotAllocationStrategyPriceCapacityOptimized,
+			capacityType:           karpv1.CapacityTypeSpot,
+			wantSpot:               "capacity-optimized",
+			wantPayAsYouGo:         "lowest-price",
+			wantPriorities:         []*int32{nil, nil},
+		},
+		{
+			name:                         "prioritized pay-as-you-go",
+			payAsYouGoAllocationStrategy: v1alpha1.PayAsYouGoAllocationStrategyPrioritized,
+			capacityType:                 karpv1.CapacityTypeOnDemand,
+			wantSpot:                     "lowest-price",
+			wantPayAsYouGo:               "prioritized",
+			wantPriorities:               []*int32{tea.Int32(0), tea.Int32(1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			nodeClass := newTestNodeClass()
+			nodeClass.Spec.SpotAllocationStrategy = tt.spotAllocationStrategy
+			nodeClass.Spec.PayAsYouGoAllocationStrategy = tt.payAsYouGoAllocationStrategy
+			// The instance types aren't ordered by price
+			instanceTypes := []*cloudprovider.InstanceType{newTestSpotInstanceType("ecs.g7.xlarge", 2), newTestSpotInstanceType("ecs.g7.large", 1)}
+			zonalVSwitches := map[string]*vswitch.VSwitch{"cn-hangzhou-i": {ID: "vsw-test", ZoneID: "cn-hangzhou-i"}}
+
+			request, err := env.provider.getProvisioningGroup(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes, zonalVSwitches, tt.capacityType, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSpot, tea.StringValue(request.SpotAllocationStrategy))
+			assert.Equal(t, tt.wantPayAsYouGo, tea.StringValue(request.PayAsYouGoAllocationStrategy))
+			assert.Equal(t, tt.wantPriorities, lo.Map(request.LaunchTemplateConfig,
+				func(config *ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig, _ int) *int32 {
+					return config.Priority
+				}))
+			if tt.payAsYouGoAllocationStrategy == v1alpha1.PayAsYouGoAllocationStrategyPrioritized {
+				assert.Equal(t, "ecs.g7.large", tea.StringValue(request.LaunchTemplateConfig[0].InstanceType))
+			}
+		})
+	}
+}
+
+func TestDefaultProvider_getProvisioningGroupSpotOptions(t *testing.T) {
+	tests := []struct {
+		name                     string
+		spotOptions              *v1alpha1.SpotOptions
+		capacityType             string
+		wantMaxPrices            []*float64
+		wantInterruptionBehavior *string
+	}{
+		{
+			name:          "no spot options",
+			capacityType:  karpv1.CapacityTypeSpot,
+			wantMaxPrices: []*float64{nil, nil},
+		},
+		{
+			name: "absolute max price",
+			spotOptions: &v1alpha1.SpotOptions{
+				MaxPrice:             lo.ToPtr(resource.MustParse("0.5")),
+				InterruptionBehavior: v1alpha1.SpotInterruptionBehaviorStop,
+			},
+			capacityType:             karpv1.CapacityTypeSpot,
+			wantMaxPrices:            []*float64{tea.Float64(0.5), tea.Float64(0.5)},
+			wantInterruptionBehavior: tea.String("stop"),
+		},
+		{
+			name: "percentage of on-demand max price",
+			spotOptions: &v1alpha1.SpotOptions{
+				MaxPricePercentageOfOnDemand: lo.ToPtr[int32](50),
+				InterruptionBehavior:         v1alpha1.SpotInterruptionBehaviorTerminate,
+			},
+			capacityType:             karpv1.CapacityTypeSpot,
+			wantMaxPrices:            []*float64{tea.Float64(1), tea.Float64(0.5)},
+			wantInterruptionBehavior: tea.String("terminate"),
+		},
+		{
+			name: "on-demand",
+			spotOptions: &v1alpha1.SpotOptions{
+				MaxPrice:             lo.ToPtr(resource.MustParse("0.5")),
+				InterruptionBehavior: v1alpha1.SpotInterruptionBehaviorStop,
+			},
+			capacityType:  karpv1.CapacityTypeOnDemand,
+			wantMaxPrices: []*float64{nil, nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			nodeClass := newTestNodeClass()
+			nodeClass.Spec.SpotOptions = tt.spotOptions
+			instanceTypes := []*cloudprovider.InstanceType{newTestSpotInstanceType("ecs.g7.xlarge", 2), newTestSpotInstanceType("ecs.g7.large", 1)}
+			zonalVSwitches := map[string]*vswitch.VSwitch{"cn-hangzhou-i": {ID: "vsw-test", ZoneID: "cn-hangzhou-i"}}
+
+			request, err := env.provider.getProvisioningGroup(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes, zonalVSwitches, tt.capacityType, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMaxPrices, lo.Map(request.LaunchTemplateConfig,
+				func(config *ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig, _ int) *float64 {
+					return config.MaxPrice
+				}))
+			assert.Equal(t, tt.wantInterruptionBehavior, request.SpotInstanceInterruptionBehavior)
+		})
+	}
+}
+
+func TestDefaultProvider_getProvisioningGroupDataDisks(t *testing.T) {
+	env := newTestEnv(t)
+	nodeClass := newTestNodeClass()
+	nodeClass.Spec.DataDisksCategories = []string{"cloud_efficiency"}
+	nodeClass.Spec.DataDisks = []v1alpha1.DataDisk{
+		{
+			VolumeSize:         lo.ToPtr(resource.MustParse("100Gi")),
+			Device:             tea.String("/dev/xvdb"),
+			Categories:         []string{"cloud_essd", "cloud_ssd"},
+			PerformanceLevel:   tea.String("PL1"),
+			KMSKeyID:           tea.String("0e478b7a-4262-4802-b8cb-00d3fb40826X"),
+			SnapshotID:         tea.String("s-images"),
+			DeleteWithInstance: tea.Bool(false),
+		},
+		{
+			VolumeSize:      lo.ToPtr(resource.MustParse("40Gi")),
+			Categories:      []string{"cloud_auto"},
+			ProvisionedIOPS: lo.ToPtr[int64](5000),
+			BurstingEnabled: tea.Bool(true),
+		},
+		{
+			VolumeSize: lo.ToPtr(resource.MustParse("20Gi")),
+		},
+	}
+	instanceTypes := []*cloudprovider.InstanceType{newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1)}
+	zonalVSwitches := map[string]*vswitch.VSwitch{"cn-hangzhou-i": {ID: "vsw-test", ZoneID: "cn-hangzhou-i"}}
+
+	request, err := env.provider.getProvisioningGroup(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes, zonalVSwitches, karpv1.CapacityTypeOnDemand, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []*ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationDataDisk{
+		{
+			Size:               tea.Int32(100),
+			Device:             tea.String("/dev/xvdb"),
+			Category:           tea.String("cloud_essd"),
+			PerformanceLevel:   tea.String("PL1"),
+			Encrypted:          tea.Bool(true),
+			KmsKeyId:           tea.String("0e478b7a-4262-4802-b8cb-00d3fb40826X"),
+			SnapshotId:         tea.String("s-images"),
+			DeleteWithInstance: tea.Bool(false),
+		},
+		{
+			Size:            tea.Int32(40),
+			Category:        tea.String("cloud_auto"),
+			ProvisionedIops: tea.Int64(5000),
+			BurstingEnabled: tea.Bool(true),
+		},
+		{
+			Size:     tea.Int32(20),
+			Category: tea.String("cloud_efficiency"),
+		},
+	}, request.LaunchConfiguration.DataDisk)
+	// The data disks have different categories, none of them falls back to the categories of another one
+	assert.Empty(t, request.DataDiskConfig)
+
+	nodeClass.Spec.DataDisks[0].Categories = nil
+	nodeClass.Spec.DataDisks[1].Categories = nil
+	nodeClass.Spec.DataDisksCategories = []string{"cloud_essd", "cloud_ssd"}
+	request, err = env.provider.getProvisioningGroup(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes, zonalVSwitches, karpv1.CapacityTypeOnDemand, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cloud_essd", "cloud_essd", "cloud_essd"}, lo.Map(request.LaunchConfiguration.DataDisk,
+		func(disk *ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationDataDisk, _ int) string {
+			return tea.StringValue(disk.Category)
+		}))
+	assert.Equal(t, []string{"cloud_essd", "cloud_ssd"}, lo.Map(request.DataDiskConfig,
+		func(config *ecsclient.CreateAutoProvisioningGroupRequestDataDiskConfig, _ int) string {
+			return tea.StringValue(config.DiskCategory)
+		}))
+}
+
+func TestDefaultProvider_getProvisioningGroupSystemDisk(t *testing.T) {
+	env := newTestEnv(t)
+	env.ecsAPI.AddOfferings(fake.CapacityPool{InstanceType: "ecs.g7.large", ZoneID: "cn-hangzhou-i", CapacityType: karpv1.CapacityTypeOnDemand})
+	env.ecsAPI.SetSystemDiskCategories("cloud_efficiency", "cloud_essd")
+	nodeClass := newTestNodeClass()
+	nodeClass.Spec.SystemDisk = &v1alpha1.SystemDisk{
+		Categories:       []string{"cloud_efficiency", "cloud_essd", "cloud_auto"},
+		VolumeSize:       lo.ToPtr(resource.MustParse("500Gi")),
+		PerformanceLevel: tea.String("PL2"),
+		KMSKeyID:         tea.String("0e478b7a-4262-4802-b8cb-00d3fb40826X"),
+	}
+	instanceTypes := []*cloudprovider.InstanceType{newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1)}
+	zonalVSwitches := map[string]*vswitch.VSwitch{"cn-hangzhou-i": {ID: "vsw-test", ZoneID: "cn-hangzhou-i"}}
+
+	request, err := env.provider.getProvisioningGroup(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes, zonalVSwitches, karpv1.CapacityTypeOnDemand, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(500), tea.Int32Value(request.LaunchConfiguration.SystemDiskSize))
+	assert.Equal(t, "PL2", tea.StringValue(request.LaunchConfiguration.SystemDiskPerformanceLevel))
+	assert.Equal(t, &ecsclient.CreateAutoProvisioningGroupRequestLaunchConfigurationSystemDisk{
+		Encrypted: tea.String("true"),
+		KMSKeyId:  tea.String("0e478b7a-4262-4802-b8cb-00d3fb40826X"),
+	}, request.LaunchConfiguration.SystemDisk)
+	// PL2 is only offered by the ESSD
+	assert.Equal(t, []string{"cloud_essd"}, lo.Map(request.SystemDiskConfig,
+		func(config *ecsclient.CreateAutoProvisioningGroupRequestSystemDiskConfig, _ int) string {
+			return tea.StringValue(config.DiskCategory)
+		}))
+
+	// PL1 isn't applied when the system disk may be created with another category
+	nodeClass.Spec.SystemDisk.PerformanceLevel = tea.String("PL1")
+	request, err = env.provider.getProvisioningGroup(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes, zonalVSwitches, karpv1.CapacityTypeOnDemand, nil)
+	require.NoError(t, err)
+	assert.Nil(t, request.LaunchConfiguration.SystemDiskPerformanceLevel)
+	assert.Len(t, request.SystemDiskConfig, 3)
+}
+
+// newTestReservedInstanceType returns an instance type with a reserved offering priced near zero
+func newTestReservedInstanceType(name string, price float64) *cloudprovider.InstanceType {
+	instanceType := newTestInstanceType(name, karpv1.ArchitectureAmd64, price)
+	instanceType.Offerings = append(instanceType.Offerings, cloudprovider.Offering{
+		Requirements: scheduling.NewRequirements(
+			scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, v1alpha1.CapacityTypeReserved),
+			scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, "cn-hangzhou-i"),
+		),
+		Price:     price / 1000,
+		Available: true,
+	})
+	return instanceType
+}
+
+func TestDefaultProvider_CreateReserved(t *testing.T) {
+	env := newTestEnv(t)
+	env.ecsAPI.AddCapacityReservations(&ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItem{
+		PrivatePoolOptionsId: tea.String("crp-test"),
+		Status:               tea.String("Active"),
+		AllocatedResources: &ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItemAllocatedResources{
+			AllocatedResource: []*ecsclient.DescribeCapacityReservationsResponseBodyCapacityReservationSetCapacityReservationItemAllocatedResourcesAllocatedResource{
+				{InstanceType: tea.String("ecs.g7.large"), ZoneId: tea.String("cn-hangzhou-i"), TotalAmount: tea.Int32(1), AvailableAmount: tea.Int32(1)},
+			},
+		},
+	})
+	nodeClass := newTestNodeClass()
+	nodeClass.Status.CapacityReservations = []v1alpha1.CapacityReservation{{
+		ID:                     "crp-test",
+		Type:                   v1alpha1.CapacityReservationTypeCapacityReservation,
+		InstanceType:           "ecs.g7.large",
+		ZoneID:                 "cn-hangzhou-i",
+		AvailableInstanceCount: 1,
+	}}
+	nodeClaim := newTestNodeClaim()
+	nodeClaim.Spec.Requirements[0].Values = []string{v1alpha1.CapacityTypeReserved, karpv1.CapacityTypeOnDemand}
+	instanceTypes := []*cloudprovider.InstanceType{
+		newTestReservedInstanceType("ecs.g7.large", 1),
+		newTestInstanceType("ecs.g7.xlarge", karpv1.ArchitectureAmd64, 2),
+	}
+
+	instance, err := env.provider.Create(env.ctx, nodeClass, nodeClaim, instanceTypes)
+	require.NoError(t, err)
+	assert.Equal(t, "ecs.g7.large", instance.Type)
+	assert.Equal(t, v1alpha1.CapacityTypeReserved, instance.CapacityType)
+
+	requests := env.ecsAPI.CreateAutoProvisioningGroupRequests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "1", tea.StringValue(requests[0].PayAsYouGoTargetCapacity))
+	require.NotNil(t, requests[0].ResourcePoolOptions)
+	assert.Equal(t, "PrivatePoolOnly", tea.StringValue(requests[0].ResourcePoolOptions.Strategy))
+	assert.Equal(t, []string{"crp-test"}, tea.StringSliceValue(requests[0].ResourcePoolOptions.PrivatePoolIds))
+	// The on-demand instance type can't be launched into the private pool
+	assert.Len(t, requests[0].LaunchTemplateConfig, 1)
+
+	listed, err := env.provider.List(env.ctx)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, v1alpha1.CapacityTypeReserved, listed[0].CapacityType)
+
+	// The private pool is used up by the launch, before its available instance count is refreshed in the status
+	assert.True(t, env.unavailableOfferings.IsUnavailable("ecs.g7.large", "cn-hangzhou-i", v1alpha1.CapacityTypeReserved))
+	_, err = env.provider.Create(env.ctx, nodeClass, nodeClaim, instanceTypes)
+	assert.True(t, cloudprovider.IsInsufficientCapacityError(err))
+	assert.Len(t, env.ecsAPI.CreateAutoProvisioningGroupRequests(), 1)
+
+	// The launches are forgotten once the status is refreshed
+	nodeClass.Status.CapacityReservations[0].AvailableInstanceCount = 2
+	assert.Equal(t, []string{"crp-test"}, env.provider.reservedPrivatePoolIDs(nodeClass, "ecs.g7.large", "cn-hangzhou-i"))
+}
+
+func TestDefaultProvider_getVSwitchIDReservedBalanced(t *testing.T) {
+	env := newTestEnv(t)
+	zonalVSwitches := map[string]*vswitch.VSwitch{
+		"cn-hangzhou-i": {ID: "vsw-i", ZoneID: "cn-hangzhou-i"},
+		"cn-hangzhou-j": {ID: "vsw-j", ZoneID: "cn-hangzhou-j"},
+		"cn-hangzhou-k": {ID: "vsw-k", ZoneID: "cn-hangzhou-k"},
+	}
+	requirements := scheduling.NewRequirements(
+		scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, v1alpha1.CapacityTypeReserved))
+	instanceType := newTestReservedInstanceType("ecs.g7.large", 1)
+
+	// The balanced policy only picks among the zones with reserved capacity
+	for range 20 {
+		assert.Equal(t, "vsw-i", env.provider.getVSwitchID(instanceType, zonalVSwitches, requirements,
+			v1alpha1.CapacityTypeReserved, v1alpha1.VSwitchSelectionPolicyBalanced))
+	}
+	delete(zonalVSwitches, "cn-hangzhou-i")
+	assert.Empty(t, env.provider.getVSwitchID(instanceType, zonalVSwitches, requirements,
+		v1alpha1.CapacityTypeReserved, v1alpha1.VSwitchSelectionPolicyBalanced))
+}
+
+func TestDefaultProvider_CreateInDeploymentSet(t *testing.T) {
+	env := newTestEnv(t)
+	env.ecsAPI.AddDeploymentSet(&ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSet{
+		DeploymentSetId: tea.String("ds-test"),
+		Strategy:        tea.String("Availability"),
+		Capacities: &ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSetCapacities{
+			Capacity: []*ecsclient.DescribeDeploymentSetsResponseBodyDeploymentSetsDeploymentSetCapacitiesCapacity{
+				{ZoneId: tea.String("cn-hangzhou-i"), AvailableAmount: tea.Int32(1), UsedAmount: tea.Int32(19)},
+			},
+		},
+	}, nil)
+	nodeClass := newTestNodeClass()
+	nodeClass.Spec.DeploymentSetSelector = &v1alpha1.DeploymentSetSelector{ID: "ds-test"}
+	instanceTypes := []*cloudprovider.InstanceType{
+		newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1),
+	}
+
+	// The deployment set has to be resolved before launching into it
+	_, err := env.provider.Create(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes)
+	assert.Error(t, err)
+	assert.Empty(t, env.ecsAPI.CreateAutoProvisioningGroupRequests())
+
+	nodeClass.Status.DeploymentSet = &v1alpha1.DeploymentSet{ID: "ds-test", Strategy: "Availability",
+		Capacities: []v1alpha1.DeploymentSetCapacity{{ZoneID: "cn-hangzhou-i", AvailableInstanceCount: 1}}}
+	instance, err := env.provider.Create(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+	launched, ok := env.ecsAPI.Instance(instance.ID)
+	require.True(t, ok)
+	assert.Equal(t, "ds-test", tea.StringValue(launched.DeploymentSetId))
+	requests := env.ecsAPI.CreateAutoProvisioningGroupRequests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "ds-test", tea.StringValue(requests[0].LaunchConfiguration.DeploymentSetId))
+
+	// The deployment set is full in the zone
+	_, err = env.provider.Create(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes)
+	assert.True(t, cloudprovider.IsInsufficientCapacityError(err))
+	assert.True(t, env.unavailableOfferings.IsUnavailable("ecs.g7.large", "cn-hangzhou-i", karpv1.CapacityTypeOnDemand))
+}
+
+func TestDefaultProvider_CreateOnDedicatedHost(t *testing.T) {
+	env := newTestEnv(t)
+	env.ecsAPI.AddDedicatedHosts(&ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHost{
+		DedicatedHostId: tea.String("dh-test"),
+		ZoneId:          tea.String("cn-hangzhou-i"),
+		Status:          tea.String("Available"),
+		SupportedInstanceTypesList: &ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHostSupportedInstanceTypesList{
+			SupportedInstanceTypesList: tea.StringSlice([]string{"ecs.g7.xlarge"}),
+		},
+		Capacity: &ecsclient.DescribeDedicatedHostsResponseBodyDedicatedHostsDedicatedHostCapacity{
+			AvailableVcpus: tea.Int32(8), AvailableMemory: tea.Float32(32),
+		},
+	})
+	nodeClass := newTestNodeClass()
+	nodeClass.Spec.Placement = &v1alpha1.Placement{DedicatedHostID: "dh-test", HPCClusterID: "hpc-test"}
+	instanceTypes := []*cloudprovider.InstanceType{
+		newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1),
+		newTestInstanceType("ecs.g7.xlarge", karpv1.ArchitectureAmd64, 2),
+	}
+
+	// The dedicated hosts have to be resolved before launching onto them
+	_, err := env.provider.Create(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes)
+	assert.True(t, cloudprovider.IsInsufficientCapacityError(err))
+	assert.Empty(t, env.ecsAPI.RunInstancesRequests())
+
+	nodeClass.Status.DedicatedHosts = []v1alpha1.DedicatedHost{{
+		ID:             "dh-test",
+		ZoneID:         "cn-hangzhou-i",
+		InstanceTypes:  []string{"ecs.g7.xlarge"},
+		AvailableVCPUs: 8,
+	}}
+	instance, err := env.provider.Create(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+	assert.Equal(t, "ecs.g7.xlarge", instance.Type)
+	assert.Equal(t, karpv1.CapacityTypeOnDemand, instance.CapacityType)
+	launched, ok := env.ecsAPI.Instance(instance.ID)
+	require.True(t, ok)
+	require.NotNil(t, launched.DedicatedHostAttribute)
+	assert.Equal(t, "dh-test", tea.StringValue(launched.DedicatedHostAttribute.DedicatedHostId))
+	assert.Equal(t, "hpc-test", tea.StringValue(launched.HpcClusterId))
+
+	// The instance types which aren't supported by the dedicated host aren't tried
+	requests := env.ecsAPI.RunInstancesRequests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "ecs.g7.xlarge", tea.StringValue(requests[0].InstanceType))
+	assert.Equal(t, "dh-test", tea.StringValue(requests[0].DedicatedHostId))
+	assert.Equal(t, v1alpha1.TenancyHost, tea.StringValue(requests[0].Tenancy))
+	assert.Empty(t, env.ecsAPI.CreateAutoProvisioningGroupRequests())
+}
+
+func TestDefaultProvider_CreateHPCClusterMixedArchitectures(t *testing.T) {
+	env := newTestEnv(t)
+	nodeClass := newTestNodeClass()
+	nodeClass.Spec.Placement = &v1alpha1.Placement{HPCClusterID: "hpc-test"}
+	nodeClass.Status.Images = append(nodeClass.Status.Images, newTestImage("arm64-image", karpv1.ArchitectureArm64))
+	instanceTypes := []*cloudprovider.InstanceType{
+		newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1),
+		newTestInstanceType("ecs.g8y.large", karpv1.ArchitectureArm64, 2),
+	}
+	env.ecsAPI.AddInsufficientCapacityPools(fake.CapacityPool{
+		InstanceType: "ecs.g7.large", ZoneID: "cn-hangzhou-i", CapacityType: karpv1.CapacityTypeOnDemand,
+	})
+
+	// RunInstances launches every instance type with its own image
+	instance, err := env.provider.Create(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+	assert.Equal(t, "ecs.g8y.large", instance.Type)
+	assert.Equal(t, "arm64-image", instance.ImageID)
+	requests := env.ecsAPI.RunInstancesRequests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, []string{"amd64-image", "arm64-image"}, lo.Map(requests, func(request *ecsclient.RunInstancesRequest, _ int) string {
+		return tea.StringValue(request.ImageId)
+	}))
+	launched, ok := env.ecsAPI.Instance(instance.ID)
+	require.True(t, ok)
+	assert.Equal(t, "arm64-image", tea.StringValue(launched.ImageId))
+}
+
+func TestDefaultProvider_CreateWithPublicIPAddress(t *testing.T) {
+	env := newTestEnv(t)
+	nodeClass := newTestNodeClass()
+	nodeClass.Spec.AssociatePublicIPAddress = lo.ToPtr(true)
+	instanceTypes := []*cloudprovider.InstanceType{
+		newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1),
+	}
+
+	instance, err := env.provider.Create(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+	launched, ok := env.ecsAPI.Instance(instance.ID)
+	require.True(t, ok)
+	require.NotNil(t, launched.PublicIpAddress)
+	assert.Len(t, launched.PublicIpAddress.IpAddress, 1)
+	requests := env.ecsAPI.CreateAutoProvisioningGroupRequests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, v1alpha1.InternetChargeTypePayByTraffic, tea.StringValue(requests[0].LaunchConfiguration.InternetChargeType))
+	assert.Equal(t, defaultInternetMaxBandwidthOut, tea.Int32Value(requests[0].LaunchConfiguration.InternetMaxBandwidthOut))
+	assert.Nil(t, requests[0].LaunchConfiguration.InternetMaxBandwidthIn)
+
+	// The bandwidth is carried over to RunInstances
+	nodeClass.Spec.InternetChargeType = lo.ToPtr(v1alpha1.InternetChargeTypePayByBandwidth)
+	nodeClass.Spec.InternetMaxBandwidthOut = lo.ToPtr[int32](20)
+	nodeClass.Spec.InternetMaxBandwidthIn = lo.ToPtr[int32](15)
+	nodeClass.Spec.Placement = &v1alpha1.Placement{HPCClusterID: "hpc-test"}
+	_, err = env.provider.Create(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+	runInstancesRequests := env.ecsAPI.RunInstancesRequests()
+	require.Len(t, runInstancesRequests, 1)
+	assert.Equal(t, v1alpha1.InternetChargeTypePayByBandwidth, tea.StringValue(runInstancesRequests[0].InternetChargeType))
+	assert.Equal(t, int32(20), tea.Int32Value(runInstancesRequests[0].InternetMaxBandwidthOut))
+	assert.Equal(t, int32(15), tea.Int32Value(runInstancesRequests[0].InternetMaxBandwidthIn))
+
+	// Without the public IP address the bandwidth isn't requested
+	nodeClass = newTestNodeClass()
+	_, err = env.provider.Create(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+	requests = env.ecsAPI.CreateAutoProvisioningGroupRequests()
+	require.Len(t, requests, 2)
+	assert.Nil(t, requests[1].LaunchConfiguration.InternetMaxBandwidthOut)
+}
+
+func TestDefaultProvider_CreateWithIPv6(t *testing.T) {
+	env := newTestEnv(t)
+	nodeClass := newTestNodeClass()
+	nodeClass.Spec.IPv6AddressCount = lo.ToPtr[int32](1)
+	instanceTypes := []*cloudprovider.InstanceType{
+		newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1),
+	}
+
+	instance, err := env.provider.Create(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+	assert.Equal(t, "ecs.g7.large", instance.Type)
+
+	// The auto provisioning groups can't assign IPv6 addresses
+	assert.Empty(t, env.ecsAPI.CreateAutoProvisioningGroupRequests())
+	requests := env.ecsAPI.RunInstancesRequests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, int32(1), tea.Int32Value(requests[0].Ipv6AddressCount))
+	assert.Nil(t, requests[0].Tenancy)
+	assert.Nil(t, requests[0].HpcClusterId)
+}
+
+func TestDefaultProvider_CreateSpotWithDuration(t *testing.T) {
+	env := newTestEnv(t)
+	nodeClass := newTestNodeClass()
+	nodeClass.Spec.SpotOptions = &v1alpha1.SpotOptions{InterruptionBehavior: v1alpha1.SpotInterruptionBehaviorStop}
+	nodeClaim := newTestNodeClaim()
+	nodeClaim.Spec.Requirements[0].Values = []string{karpv1.CapacityTypeSpot}
+	instanceTypes := []*cloudprovider.InstanceType{newTestSpotInstanceType("ecs.g7.large", 1)}
+
+	// Without a protection period the spot instances are launched by the auto provisioning groups
+	_, err := env.provider.Create(env.ctx, nodeClass, nodeClaim, instanceTypes)
+	require.NoError(t, err)
+	assert.Len(t, env.ecsAPI.CreateAutoProvisioningGroupRequests(), 1)
+	assert.Empty(t, env.ecsAPI.RunInstancesRequests())
+
+	nodeClass.Spec.SpotOptions.Duration = lo.ToPtr[int32](0)
+	instance, err := env.provider.Create(env.ctx, nodeClass, nodeClaim, instanceTypes)
+	require.NoError(t, err)
+	assert.Equal(t, karpv1.CapacityTypeSpot, instance.CapacityType)
+	assert.Len(t, env.ecsAPI.CreateAutoProvisioningGroupRequests(), 1)
+	requests := env.ecsAPI.RunInstancesRequests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "SpotAsPriceGo", tea.StringValue(requests[0].SpotStrategy))
+	assert.Equal(t, lo.ToPtr[int32](0), requests[0].SpotDuration)
+	assert.Equal(t, "Stop", tea.StringValue(requests[0].SpotInterruptionBehavior))
+}
+
+func TestDefaultProvider_CreateMixedArchitectures(t *testing.T) {
+	env := newTestEnv(t)
+	nodeClass := newTestNodeClass()
+	nodeClass.Status.Images = append(nodeClass.Status.Images, newTestImage("arm64-image", karpv1.ArchitectureArm64))
+	instanceTypes := []*cloudprovider.InstanceType{
+		newTestInstanceType("ecs.g7.large", karpv1.ArchitectureAmd64, 1),
+		newTestInstanceType("ecs.g8y.large", karpv1.ArchitectureArm64, 2),
+	}
+	imageIDs := map[string]string{"ecs.g8y.large": "arm64-image", "ecs.g7.large": "amd64-image"}
+	// The first instance type is out of stock, the instance is launched with the image of the second one
+	env.ecsAPI.AddInsufficientCapacityPools(fake.CapacityPool{
+		InstanceType: "ecs.g7.large", ZoneID: "cn-hangzhou-i", CapacityType: karpv1.CapacityTypeOnDemand,
+	})
+
+	// The instance types of both architectures are offered with their own image
+	instance, err := env.provider.Create(env.ctx, nodeClass, newTestNodeClaim(), instanceTypes)
+	require.NoError(t, err)
+	requests := env.ecsAPI.CreateAutoProvisioningGroupRequests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, imageIDs,
+		lo.SliceToMap(requests[0].LaunchTemplateConfig, func(config *ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig) (string, string) {
+			return tea.StringValue(config.InstanceType), tea.StringValue(config.ImageId)
+		}))
+	assert.Equal(t, "ecs.g8y.large", instance.Type)
+	assert.Equal(t, "arm64-image", instance.ImageID)
+	launched, ok := env.ecsAPI.Instance(instance.ID)
+	require.True(t, ok)
+	assert.Equal(t, "arm64-image", tea.StringValue(launched.ImageId))
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/samber/lo"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils"
 )
 
@@ -65,7 +66,7 @@ func NewInstance(out *ecsclient.DescribeInstancesResponseBodyInstancesInstance)
 		Type:             *out.InstanceType,
 		Region:           *out.RegionId,
 		Zone:             *out.ZoneId,
-		CapacityType:     utils.GetCapacityTypes(*out.SpotStrategy),
+		CapacityType:     toCapacityType(out),
 		SecurityGroupIDs: toSecurityGroupIDs(out.SecurityGroupIds),
 		VSwitchID:        toVSwitchID(out.VpcAttributes),
 		Tags:             toTags(out.Tags),
@@ -84,25 +85,47 @@ func NewInstanceFromProvisioningGroup(out *ecsclient.CreateAutoProvisioningGroup
 		securityGroupIDs = []string{*req.LaunchConfiguration.SecurityGroupId}
 	}
 
+	capacityType := utils.GetCapacityTypes(*out.SpotStrategy)
+	if req.ResourcePoolOptions != nil && lo.FromPtr(req.ResourcePoolOptions.Strategy) == "PrivatePoolOnly" {
+		capacityType = v1alpha1.CapacityTypeReserved
+	}
+
 	tags := make(map[string]string, len(req.Tag))
 	for i := range req.Tag {
 		tags[*req.Tag[i].Key] = *req.Tag[i].Value
 	}
 
+	// Every launch template config carries the image of its instance type
+	imageID := req.LaunchConfiguration.ImageId
+	if config, ok := lo.Find(req.LaunchTemplateConfig, func(config *ecsclient.CreateAutoProvisioningGroupRequestLaunchTemplateConfig) bool {
+		return lo.FromPtr(config.InstanceType) == lo.FromPtr(out.InstanceType) && config.ImageId != nil
+	}); ok {
+		imageID = config.ImageId
+	}
+
 	return &Instance{
 		CreationTime:     time.Now(), // estimate the launch time since we just launched
 		Status:           InstanceStatusPending,
 		ID:               *out.InstanceIds.InstanceId[0],
-		ImageID:          *req.LaunchConfiguration.ImageId,
+		ImageID:          *imageID,
 		Type:             *out.InstanceType,
 		Region:           region,
 		Zone:             *out.ZoneId,
-		CapacityType:     utils.GetCapacityTypes(*out.SpotStrategy),
+		CapacityType:     capacityType,
 		SecurityGroupIDs: securityGroupIDs,
 		Tags:             tags,
 	}
 }
 
+// toCapacityType returns reserved for the instances consuming a capacity reservation or an elasticity assurance,
+// they are pay-as-you-go instances
+func toCapacityType(out *ecsclient.DescribeInstancesResponseBodyInstancesInstance) string {
+	if out.EcsCapacityReservationAttr != nil && lo.FromPtr(out.EcsCapacityReservationAttr.CapacityReservationId) != "" {
+		return v1alpha1.CapacityTypeReserved
+	}
+	return utils.GetCapacityTypes(*out.SpotStrategy)
+}
+
 func toSecurityGroupIDs(securityGroups *ecsclient.DescribeInstancesResponseBodyInstancesInstanceSecurityGroupIds) []string {
 	if securityGroups == nil {
 		return []string{}
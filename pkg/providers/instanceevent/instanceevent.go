@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instanceevent
+
+import (
+	"context"
+	"fmt"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/operator/options"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
+)
+
+const (
+	// Spot instances that are about to be reclaimed are locked with this reason
+	lockReasonRecycling = "recycling"
+)
+
+// Provider is the source of the ECS instance events
+type Provider interface {
+	List(context.Context) ([]Event, error)
+}
+
+type DefaultProvider struct {
+	region    string
+	ecsClient sdk.ECSAPI
+}
+
+func NewDefaultProvider(region string, ecsClient sdk.ECSAPI) *DefaultProvider {
+	return &DefaultProvider{
+		region:    region,
+		ecsClient: ecsClient,
+	}
+}
+
+// List returns the pending system events and the spot interruptions of the instances in the cluster
+func (p *DefaultProvider) List(ctx context.Context) ([]Event, error) {
+	systemEvents, err := p.listSystemEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing instance system events, %w", err)
+	}
+	spotInterruptions, err := p.listSpotInterruptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing spot interruptions, %w", err)
+	}
+	return append(systemEvents, spotInterruptions...), nil
+}
+
+func (p *DefaultProvider) listSystemEvents(ctx context.Context) ([]Event, error) {
+	var events []Event
+	request := &ecsclient.DescribeInstanceHistoryEventsRequest{
+		RegionId:                 tea.String(p.region),
+		ResourceType:             tea.String("instance"),
+		InstanceEventCycleStatus: tea.StringSlice([]string{"Scheduled", "Executing"}),
+		Tag: []*ecsclient.DescribeInstanceHistoryEventsRequestTag{
+			{
+				Key:   tea.String(fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterID)),
+				Value: tea.String("owned"),
+			},
+		},
+		MaxResults: tea.Int64(100),
+	}
+	runtime := &util.RuntimeOptions{}
+	for {
+		resp, err := p.ecsClient.DescribeInstanceHistoryEventsWithOptions(request, runtime)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil {
+			return nil, fmt.Errorf("unexpected null value was returned")
+		}
+		if resp.Body.InstanceSystemEventSet == nil {
+			return nil, alierrors.WithRequestID(tea.StringValue(resp.Body.RequestId), fmt.Errorf("unexpected null value was returned"))
+		}
+
+		for _, e := range resp.Body.InstanceSystemEventSet.InstanceSystemEventType {
+			if e.EventType == nil {
+				continue
+			}
+			events = append(events, Event{
+				ID:         tea.StringValue(e.EventId),
+				InstanceID: tea.StringValue(e.InstanceId),
+				Type:       tea.StringValue(e.EventType.Name),
+				Reason:     tea.StringValue(e.Reason),
+			})
+		}
+
+		request.NextToken = resp.Body.NextToken
+		if request.NextToken == nil || *request.NextToken == "" || len(resp.Body.InstanceSystemEventSet.InstanceSystemEventType) == 0 {
+			break
+		}
+	}
+	return events, nil
+}
+
+func (p *DefaultProvider) listSpotInterruptions(ctx context.Context) ([]Event, error) {
+	var events []Event
+	request := &ecsclient.DescribeInstancesRequest{
+		RegionId:   tea.String(p.region),
+		LockReason: tea.String(lockReasonRecycling),
+		Tag: []*ecsclient.DescribeInstancesRequestTag{
+			{
+				Key:   tea.String(fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterID)),
+				Value: tea.String("owned"),
+			},
+		},
+		MaxResults: tea.Int32(100),
+	}
+	runtime := &util.RuntimeOptions{}
+	for {
+		resp, err := p.ecsClient.DescribeInstancesWithOptions(request, runtime)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.Instances == nil || len(resp.Body.Instances.Instance) == 0 {
+			break
+		}
+
+		for _, instance := range resp.Body.Instances.Instance {
+			events = append(events, Event{
+				// There is only one spot interruption for each instance
+				ID:         fmt.Sprintf("%s/%s", SpotInterruptionEventType, tea.StringValue(instance.InstanceId)),
+				InstanceID: tea.StringValue(instance.InstanceId),
+				Type:       SpotInterruptionEventType,
+				Reason:     "spot instance is locked for recycling",
+			})
+		}
+
+		request.NextToken = resp.Body.NextToken
+		if request.NextToken == nil || *request.NextToken == "" {
+			break
+		}
+	}
+	return events, nil
+}
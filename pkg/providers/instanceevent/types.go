@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instanceevent
+
+import (
+	"strings"
+)
+
+type Kind string
+
+const (
+	SpotInterruptionKind     Kind = "SpotInterruption"
+	ScheduledMaintenanceKind Kind = "ScheduledMaintenance"
+	SystemFailureKind        Kind = "SystemFailure"
+	InstanceStoppedKind      Kind = "InstanceStopped"
+	InstanceReleasedKind     Kind = "InstanceReleased"
+	NoOpKind                 Kind = "NoOp"
+)
+
+const (
+	// SpotInterruptionEventType is not an ECS system event, it is reported when the spot instance is locked for recycling
+	SpotInterruptionEventType = "Instance:PreemptibleInstanceInterruption"
+)
+
+// Event is an ECS instance event that may interrupt the instance
+type Event struct {
+	// ID identifies the event, it is used to avoid handling the same event more than once
+	ID         string
+	InstanceID string
+	// Type is the ECS event type, e.g. SystemMaintenance.Reboot
+	Type string
+	// Reason is the reason reported by ECS for the event
+	Reason string
+}
+
+// Kind maps the ECS event type to the kind of interruption
+// Ref: https://www.alibabacloud.com/help/en/ecs/user-guide/overview-of-ecs-system-events
+func (e Event) Kind() Kind {
+	switch {
+	case e.Type == SpotInterruptionEventType:
+		return SpotInterruptionKind
+	case strings.HasSuffix(e.Type, ".Delete"):
+		return InstanceReleasedKind
+	case strings.HasSuffix(e.Type, ".Stop"):
+		return InstanceStoppedKind
+	case strings.HasPrefix(e.Type, "SystemMaintenance."):
+		return ScheduledMaintenanceKind
+	case strings.HasPrefix(e.Type, "SystemFailure."), strings.HasPrefix(e.Type, "InstanceFailure."):
+		return SystemFailureKind
+	default:
+		return NoOpKind
+	}
+}
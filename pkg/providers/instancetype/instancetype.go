@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -36,13 +37,29 @@ import (
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
 	kcache "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/cache"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/cluster"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/dedicatedhost"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/pricing"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/spotrisk"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
 )
 
+const (
+	// defaultMaxSpotRiskScore is the risk score above which the spot risk policy takes action when it doesn't set one
+	defaultMaxSpotRiskScore int32 = 50
+	// reservedPriceRatio is the ratio of the on-demand price the reserved offerings are priced at. The reserved
+	// capacity is already paid for, a price near zero makes it preferred over spot and on-demand while keeping
+	// the reserved instance types ordered by size.
+	// Karpenter core v1.2.0 doesn't know the reserved capacity type, WorstLaunchPrice only prices the spot and the
+	// on-demand offerings. A NodeClaim limited to reserved capacity is priced at math.MaxFloat64, so consolidation
+	// never launches a reserved replacement, while a NodeClaim also allowing on-demand is priced as on-demand. The
+	// reserved nodes themselves are priced by their reserved offering, they are only consolidated by being deleted.
+	reservedPriceRatio = 0.001
+)
+
 type Provider interface {
 	LivenessProbe(*http.Request) error
 	List(context.Context, *v1alpha1.KubeletConfiguration, *v1alpha1.ECSNodeClass) ([]*cloudprovider.InstanceType, error)
@@ -51,10 +68,11 @@ type Provider interface {
 }
 
 type DefaultProvider struct {
-	region          string
-	ecsClient       *ecsclient.Client
-	pricingProvider pricing.Provider
-	clusterProvider cluster.Provider
+	region           string
+	ecsClient        sdk.ECSAPI
+	pricingProvider  pricing.Provider
+	spotRiskProvider spotrisk.Provider
+	clusterProvider  cluster.Provider
 
 	// Values stored *before* considering insufficient capacity errors from the unavailableOfferings cache.
 	// Fully initialized Instance Types are also cached based on the set of all instance types, zones, unavailableOfferings cache,
@@ -78,13 +96,14 @@ type DefaultProvider struct {
 	instanceTypesOfferingsSeqNum uint64
 }
 
-func NewDefaultProvider(region string, ecsClient *ecsclient.Client,
+func NewDefaultProvider(region string, ecsClient sdk.ECSAPI,
 	instanceTypesCache *cache.Cache, unavailableOfferingsCache *kcache.UnavailableOfferings,
-	pricingProvider pricing.Provider, clusterProvider cluster.Provider) *DefaultProvider {
+	pricingProvider pricing.Provider, spotRiskProvider spotrisk.Provider, clusterProvider cluster.Provider) *DefaultProvider {
 	return &DefaultProvider{
 		ecsClient:                  ecsClient,
 		region:                     region,
 		pricingProvider:            pricingProvider,
+		spotRiskProvider:           spotRiskProvider,
 		clusterProvider:            clusterProvider,
 		instanceTypesInfo:          []*ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType{},
 		instanceTypesOfferings:     map[string]sets.Set[string]{},
@@ -136,16 +155,38 @@ func (p *DefaultProvider) List(ctx context.Context, kc *v1alpha1.KubeletConfigur
 	vSwitchsZones := sets.New(lo.Map(nodeClass.Status.VSwitches, func(s v1alpha1.VSwitch, _ int) string {
 		return s.ZoneID
 	})...)
+	// No instance can be placed into the deployment set in the zones where it is full
+	if deploymentSet := nodeClass.Status.DeploymentSet; deploymentSet != nil {
+		vSwitchsZones.Delete(lo.FilterMap(deploymentSet.Capacities, func(c v1alpha1.DeploymentSetCapacity, _ int) (string, bool) {
+			return c.ZoneID, c.AvailableInstanceCount <= 0
+		})...)
+	}
 
 	// Compute fully initialized instance types hash key
 	vSwitchZonesHash, _ := hashstructure.Hash(vSwitchsZones, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	kcHash, _ := hashstructure.Hash(kc, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
-	key := fmt.Sprintf("%d-%d-%d-%016x-%016x",
+	spotRiskPolicyHash, _ := hashstructure.Hash(nodeClass.Spec.SpotRiskPolicy, hashstructure.FormatV2, nil)
+	spotOptionsHash, _ := hashstructure.Hash(nodeClass.Spec.SpotOptions, hashstructure.FormatV2, nil)
+	capacityReservationsHash, _ := hashstructure.Hash(nodeClass.Status.CapacityReservations, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	onDedicatedHosts := nodeClass.Spec.Placement.OnDedicatedHosts()
+	// The quantities don't hash their values, so the available memory is hashed as a string
+	dedicatedHostsHash, _ := hashstructure.Hash(lo.Map(nodeClass.Status.DedicatedHosts, func(host v1alpha1.DedicatedHost, _ int) string {
+		return fmt.Sprintf("%s/%s/%s/%d/%s", host.ID, host.ZoneID, strings.Join(host.InstanceTypes, ","), host.AvailableVCPUs, host.AvailableMemory.String())
+	}), hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	key := fmt.Sprintf("%d-%d-%d-%d-%016x-%016x-%016x-%016x-%016x-%s-%t-%016x-%d",
 		p.instanceTypesSeqNum,
 		p.instanceTypesOfferingsSeqNum,
 		p.unavailableOfferings.SeqNum,
+		p.spotRiskProvider.SeqNum(),
 		vSwitchZonesHash,
 		kcHash,
+		spotRiskPolicyHash,
+		spotOptionsHash,
+		capacityReservationsHash,
+		lo.FromPtr(nodeClass.Spec.InstanceStorePolicy),
+		onDedicatedHosts,
+		dedicatedHostsHash,
+		lo.FromPtr(nodeClass.Spec.IPv6AddressCount),
 	)
 
 	if item, ok := p.instanceTypesCache.Get(key); ok {
@@ -178,15 +219,30 @@ func (p *DefaultProvider) List(ctx context.Context, kc *v1alpha1.KubeletConfigur
 		return nil, fmt.Errorf("failed to get cluster CNI: %w", err)
 	}
 
-	result := lo.Map(p.instanceTypesInfo, func(i *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType, _ int) *cloudprovider.InstanceType {
+	// The IPv6 addresses are assigned to the primary network interface, it has to support as many of them
+	instanceTypesInfo := p.instanceTypesInfo
+	if ipv6AddressCount := lo.FromPtr(nodeClass.Spec.IPv6AddressCount); ipv6AddressCount > 0 {
+		instanceTypesInfo = lo.Filter(instanceTypesInfo, func(i *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType, _ int) bool {
+			return lo.FromPtr(i.EniIpv6AddressQuantity) >= ipv6AddressCount
+		})
+	}
+	result := lo.Map(instanceTypesInfo, func(i *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType, _ int) *cloudprovider.InstanceType {
+		// The instances on dedicated hosts are pay-as-you-go instances of the instance types the hosts still have room for
+		dedicatedHostZones := dedicatedhost.Zones(nodeClass.Status.DedicatedHosts, lo.FromPtr(i.InstanceTypeId),
+			lo.FromPtr(i.CpuCoreCount), float64(lo.FromPtr(i.MemorySize)))
 		zoneData := lo.Map(allZones.UnsortedList(), func(zoneID string, _ int) ZoneData {
-			ret := ZoneData{ID: zoneID, Available: true, SpotAvailable: true}
+			ret := ZoneData{ID: zoneID, Available: true, SpotAvailable: true, ReservedAvailable: vSwitchsZones.Has(zoneID)}
 			if !p.instanceTypesOfferings[lo.FromPtr(i.InstanceTypeId)].Has(zoneID) || !vSwitchsZones.Has(zoneID) {
 				ret.Available = false
 			}
 			if !p.spotInstanceTypesOfferings[lo.FromPtr(i.InstanceTypeId)].Has(zoneID) || !vSwitchsZones.Has(zoneID) {
 				ret.SpotAvailable = false
 			}
+			if onDedicatedHosts {
+				ret.Available = ret.Available && dedicatedHostZones.Has(zoneID)
+				ret.SpotAvailable = false
+				ret.ReservedAvailable = false
+			}
 			return ret
 		})
 
@@ -194,8 +250,8 @@ func (p *DefaultProvider) List(ctx context.Context, kc *v1alpha1.KubeletConfigur
 		// Any changes to the values passed into the NewInstanceType method will require making updates to the cache key
 		// so that Karpenter is able to cache the set of InstanceTypes based on values that alter the set of instance types
 		// !!! Important !!!
-		offers := p.createOfferings(ctx, *i.InstanceTypeId, zoneData)
-		return NewInstanceType(ctx, i, kc, p.region, nodeClass.Spec.SystemDisk, offers, clusterCNI)
+		offers := p.createOfferings(ctx, *i.InstanceTypeId, zoneData, nodeClass)
+		return NewInstanceType(ctx, i, kc, p.region, nodeClass.Spec.SystemDisk, nodeClass.Spec.InstanceStorePolicy, offers, clusterCNI)
 	})
 
 	// Filter out nil values
@@ -348,7 +404,7 @@ func processAvailableResources(az *ecsclient.DescribeAvailableResourceResponseBo
 	}
 }
 
-func getAllInstanceTypes(client *ecsclient.Client) ([]*ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType, error) {
+func getAllInstanceTypes(client sdk.ECSAPI) ([]*ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType, error) {
 	var InstanceTypes []*ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType
 
 	describeInstanceTypesRequest := &ecsclient.DescribeInstanceTypesRequest{
@@ -393,7 +449,7 @@ func getAllInstanceTypes(client *ecsclient.Client) ([]*ecsclient.DescribeInstanc
 // offering, you can do the following thanks to this invariant:
 //
 //	offering.Requirements.Get(v1.TopologyLabelZone).Any()
-func (p *DefaultProvider) createOfferings(_ context.Context, instanceType string, zones []ZoneData) []cloudprovider.Offering {
+func (p *DefaultProvider) createOfferings(_ context.Context, instanceType string, zones []ZoneData, nodeClass *v1alpha1.ECSNodeClass) []cloudprovider.Offering {
 	var offerings []cloudprovider.Offering
 	for _, zone := range zones {
 		odPrice, odOK := p.pricingProvider.OnDemandPrice(instanceType)
@@ -409,14 +465,48 @@ func (p *DefaultProvider) createOfferings(_ context.Context, instanceType string
 		if spotOK {
 			isUnavailable := p.unavailableOfferings.IsUnavailable(instanceType, zone.ID, karpv1.CapacityTypeSpot)
 			offeringAvailable := !isUnavailable && zone.SpotAvailable
+			// The spot offerings above the price limit would be rejected by the launch anyway
+			if maxPrice, ok := nodeClass.Spec.SpotOptions.GetMaxPrice(odPrice); ok && spotPrice > maxPrice {
+				offeringAvailable = false
+			}
+			spotPrice, offeringAvailable = p.applySpotRiskPolicy(instanceType, zone.ID, spotPrice, offeringAvailable, nodeClass.Spec.SpotRiskPolicy)
 
 			offerings = append(offerings, p.createOffering(zone.ID, karpv1.CapacityTypeSpot, spotPrice, offeringAvailable))
 		}
+
+		// The reserved offerings only exist where the ECSNodeClass has selected a private pool of the instance type
+		reservations := lo.Filter(nodeClass.Status.CapacityReservations, func(cr v1alpha1.CapacityReservation, _ int) bool {
+			return cr.InstanceType == instanceType && cr.ZoneID == zone.ID
+		})
+		if len(reservations) != 0 {
+			isUnavailable := p.unavailableOfferings.IsUnavailable(instanceType, zone.ID, v1alpha1.CapacityTypeReserved)
+			availableInstanceCount := lo.SumBy(reservations, func(cr v1alpha1.CapacityReservation) int32 { return cr.AvailableInstanceCount })
+			offeringAvailable := !isUnavailable && zone.ReservedAvailable && availableInstanceCount > 0
+
+			offerings = append(offerings, p.createOffering(zone.ID, v1alpha1.CapacityTypeReserved, odPrice*reservedPriceRatio, offeringAvailable))
+		}
 	}
 
 	return offerings
 }
 
+// applySpotRiskPolicy excludes or deprioritizes the spot offering when its interruption risk is above the policy
+// threshold. Deprioritizing raises the price, so both the launches and the consolidation prefer the safer offerings.
+func (p *DefaultProvider) applySpotRiskPolicy(instanceType, zone string, price float64, available bool,
+	spotRiskPolicy *v1alpha1.SpotRiskPolicy) (float64, bool) {
+	if spotRiskPolicy == nil {
+		return price, available
+	}
+	score := p.spotRiskProvider.Score(instanceType, zone)
+	if score <= lo.FromPtrOr(spotRiskPolicy.MaxRiskScore, defaultMaxSpotRiskScore) {
+		return price, available
+	}
+	if spotRiskPolicy.Action == v1alpha1.SpotRiskActionExclude {
+		return price, false
+	}
+	return price * (1 + float64(score)/100), available
+}
+
 func (p *DefaultProvider) createOffering(zone, capacityType string, price float64, available bool) cloudprovider.Offering {
 	return cloudprovider.Offering{
 		Requirements: scheduling.NewRequirements(
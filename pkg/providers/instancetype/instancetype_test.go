@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/clock"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	kcache "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/cache"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/providers/spotrisk"
+)
+
+func Test_applySpotRiskPolicy(t *testing.T) {
+	spotRiskProvider := spotrisk.NewDefaultProvider(fake.DefaultRegion, fake.NewECSAPI(nil), clock.RealClock{})
+	// A single interruption scores 33
+	spotRiskProvider.RecordInterruption(context.Background(), "ecs.g7.large", "cn-beijing-a")
+	p := &DefaultProvider{spotRiskProvider: spotRiskProvider}
+
+	tests := []struct {
+		name          string
+		zone          string
+		policy        *v1alpha1.SpotRiskPolicy
+		wantPrice     float64
+		wantAvailable bool
+	}{
+		{
+			name:          "no policy",
+			zone:          "cn-beijing-a",
+			wantPrice:     1,
+			wantAvailable: true,
+		},
+		{
+			name:          "below the default max risk score",
+			zone:          "cn-beijing-a",
+			policy:        &v1alpha1.SpotRiskPolicy{Action: v1alpha1.SpotRiskActionExclude},
+			wantPrice:     1,
+			wantAvailable: true,
+		},
+		{
+			name:          "excluded",
+			zone:          "cn-beijing-a",
+			policy:        &v1alpha1.SpotRiskPolicy{Action: v1alpha1.SpotRiskActionExclude, MaxRiskScore: lo.ToPtr[int32](20)},
+			wantPrice:     1,
+			wantAvailable: false,
+		},
+		{
+			name:          "deprioritized",
+			zone:          "cn-beijing-a",
+			policy:        &v1alpha1.SpotRiskPolicy{Action: v1alpha1.SpotRiskActionDeprioritize, MaxRiskScore: lo.ToPtr[int32](20)},
+			wantPrice:     1.33,
+			wantAvailable: true,
+		},
+		{
+			name:          "safe zone",
+			zone:          "cn-beijing-b",
+			policy:        &v1alpha1.SpotRiskPolicy{Action: v1alpha1.SpotRiskActionExclude, MaxRiskScore: lo.ToPtr[int32](0)},
+			wantPrice:     1,
+			wantAvailable: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, available := p.applySpotRiskPolicy("ecs.g7.large", tt.zone, 1, true, tt.policy)
+			assert.InDelta(t, tt.wantPrice, price, 0.0001)
+			assert.Equal(t, tt.wantAvailable, available)
+		})
+	}
+}
+
+func Test_createOfferingsSpotMaxPrice(t *testing.T) {
+	pricingProvider := fake.NewPricingProvider()
+	pricingProvider.SetOnDemandPrice("ecs.g7.large", 1)
+	pricingProvider.SetSpotPrice("ecs.g7.large", "cn-beijing-a", 0.3)
+	pricingProvider.SetSpotPrice("ecs.g7.large", "cn-beijing-b", 0.6)
+	p := &DefaultProvider{
+		pricingProvider:      pricingProvider,
+		spotRiskProvider:     spotrisk.NewDefaultProvider(fake.DefaultRegion, fake.NewECSAPI(nil), clock.RealClock{}),
+		unavailableOfferings: kcache.NewUnavailableOfferings(),
+	}
+	zones := []ZoneData{
+		{ID: "cn-beijing-a", Available: true, SpotAvailable: true},
+		{ID: "cn-beijing-b", Available: true, SpotAvailable: true},
+	}
+
+	tests := []struct {
+		name          string
+		spotOptions   *v1alpha1.SpotOptions
+		wantAvailable map[string]bool
+	}{
+		{
+			name:          "no price limit",
+			wantAvailable: map[string]bool{"cn-beijing-a": true, "cn-beijing-b": true},
+		},
+		{
+			name:          "absolute price limit",
+			spotOptions:   &v1alpha1.SpotOptions{MaxPrice: lo.ToPtr(resource.MustParse("0.5"))},
+			wantAvailable: map[string]bool{"cn-beijing-a": true, "cn-beijing-b": false},
+		},
+		{
+			name:          "percentage of on-demand price limit",
+			spotOptions:   &v1alpha1.SpotOptions{MaxPricePercentageOfOnDemand: lo.ToPtr[int32](20)},
+			wantAvailable: map[string]bool{"cn-beijing-a": false, "cn-beijing-b": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeClass := &v1alpha1.ECSNodeClass{Spec: v1alpha1.ECSNodeClassSpec{SpotOptions: tt.spotOptions}}
+			offerings := p.createOfferings(context.Background(), "ecs.g7.large", zones, nodeClass)
+			for _, o := range offerings {
+				zone := o.Requirements.Get(corev1.LabelTopologyZone).Any()
+				if o.Requirements.Get(karpv1.CapacityTypeLabelKey).Any() == karpv1.CapacityTypeOnDemand {
+					assert.True(t, o.Available, zone)
+					continue
+				}
+				assert.Equal(t, tt.wantAvailable[zone], o.Available, zone)
+			}
+		})
+	}
+}
+
+func Test_createOfferingsReserved(t *testing.T) {
+	pricingProvider := fake.NewPricingProvider()
+	pricingProvider.SetOnDemandPrice("ecs.g7.large", 1)
+	unavailableOfferings := kcache.NewUnavailableOfferings()
+	p := &DefaultProvider{
+		pricingProvider:      pricingProvider,
+		spotRiskProvider:     spotrisk.NewDefaultProvider(fake.DefaultRegion, fake.NewECSAPI(nil), clock.RealClock{}),
+		unavailableOfferings: unavailableOfferings,
+	}
+	zones := []ZoneData{
+		{ID: "cn-beijing-a", Available: true, ReservedAvailable: true},
+		{ID: "cn-beijing-b", Available: true, ReservedAvailable: true},
+		{ID: "cn-beijing-c", Available: false, ReservedAvailable: true},
+		{ID: "cn-beijing-d", Available: true},
+		{ID: "cn-beijing-e", Available: true, ReservedAvailable: true},
+	}
+	unavailableOfferings.MarkUnavailable(context.Background(), "test", "ecs.g7.large", "cn-beijing-e", v1alpha1.CapacityTypeReserved)
+	nodeClass := &v1alpha1.ECSNodeClass{Status: v1alpha1.ECSNodeClassStatus{CapacityReservations: []v1alpha1.CapacityReservation{
+		{ID: "crp-a", InstanceType: "ecs.g7.large", ZoneID: "cn-beijing-a", AvailableInstanceCount: 0},
+		{ID: "eap-a", InstanceType: "ecs.g7.large", ZoneID: "cn-beijing-a", AvailableInstanceCount: 2},
+		{ID: "crp-b", InstanceType: "ecs.g7.large", ZoneID: "cn-beijing-b", AvailableInstanceCount: 0},
+		// The stock of the zone doesn't matter for the reserved capacity
+		{ID: "crp-c", InstanceType: "ecs.g7.large", ZoneID: "cn-beijing-c", AvailableInstanceCount: 1},
+		// There is no vSwitch in the zone
+		{ID: "crp-d", InstanceType: "ecs.g7.large", ZoneID: "cn-beijing-d", AvailableInstanceCount: 1},
+		{ID: "crp-e", InstanceType: "ecs.g7.large", ZoneID: "cn-beijing-e", AvailableInstanceCount: 1},
+		{ID: "crp-other", InstanceType: "ecs.g7.xlarge", ZoneID: "cn-beijing-a", AvailableInstanceCount: 1},
+	}}}
+
+	offerings := lo.Filter(p.createOfferings(context.Background(), "ecs.g7.large", zones, nodeClass), func(o cloudprovider.Offering, _ int) bool {
+		return o.Requirements.Get(karpv1.CapacityTypeLabelKey).Any() == v1alpha1.CapacityTypeReserved
+	})
+	assert.Equal(t, map[string]bool{
+		"cn-beijing-a": true,
+		"cn-beijing-b": false,
+		"cn-beijing-c": true,
+		"cn-beijing-d": false,
+		"cn-beijing-e": false,
+	}, lo.SliceToMap(offerings, func(o cloudprovider.Offering) (string, bool) {
+		return o.Requirements.Get(corev1.LabelTopologyZone).Any(), o.Available
+	}))
+	for _, o := range offerings {
+		assert.InDelta(t, 0.001, o.Price, 0.000001)
+	}
+}
@@ -49,6 +49,9 @@ const (
 	TerwayMinENIRequirements = 11
 	BaseHostNetworkPods      = 3
 	FlannelDefaultPods       = 256
+
+	MemoryAvailable = "memory.available"
+	NodeFSAvailable = "nodefs.available"
 )
 
 type ZoneData struct {
@@ -57,6 +60,8 @@ type ZoneData struct {
 	Available bool
 	// SpotAvailable represents Spot capacity
 	SpotAvailable bool
+	// ReservedAvailable represents the zone can be launched into, the reserved capacity doesn't depend on the stock
+	ReservedAvailable bool
 }
 
 func calculateResourceOverhead(pods, cpuM, memoryMi int64) corev1.ResourceList {
@@ -106,7 +111,7 @@ func calculateCPUOverhead(cpuM int64) int64 {
 
 func NewInstanceType(ctx context.Context,
 	info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType,
-	kc *v1alpha1.KubeletConfiguration, region string, systemDisk *v1alpha1.SystemDisk,
+	kc *v1alpha1.KubeletConfiguration, region string, systemDisk *v1alpha1.SystemDisk, instanceStorePolicy *string,
 	offerings cloudprovider.Offerings, clusterCNI string) *cloudprovider.InstanceType {
 	if offerings == nil {
 		return nil
@@ -116,7 +121,7 @@ func NewInstanceType(ctx context.Context,
 		Name:         *info.InstanceTypeId,
 		Requirements: computeRequirements(info, offerings, region),
 		Offerings:    offerings,
-		Capacity:     computeCapacity(ctx, info, kc.MaxPods, kc.PodsPerCore, systemDisk, clusterCNI),
+		Capacity:     computeCapacity(ctx, info, kc.MaxPods, kc.PodsPerCore, systemDisk, instanceStorePolicy, clusterCNI),
 		Overhead: &cloudprovider.InstanceTypeOverhead{
 			KubeReserved:      corev1.ResourceList{},
 			SystemReserved:    corev1.ResourceList{},
@@ -124,9 +129,12 @@ func NewInstanceType(ctx context.Context,
 		},
 	}
 
-	// Follow KubeReserved/SystemReserved/EvictionThreshold will be merged, so we can set only one overhead totally
-	it.Overhead.KubeReserved = calculateResourceOverhead(it.Capacity.Pods().Value(),
-		it.Capacity.Cpu().MilliValue(), extractMemory(info).Value()/MiBByteRatio)
+	// KubeReserved/SystemReserved/EvictionThreshold will be merged, resources that are not
+	// reserved explicitly by the user fall back to the ACK resource reservation policy
+	it.Overhead.KubeReserved = kubeReservedResources(calculateResourceOverhead(it.Capacity.Pods().Value(),
+		it.Capacity.Cpu().MilliValue(), extractMemory(info).Value()/MiBByteRatio), kc.KubeReserved)
+	it.Overhead.SystemReserved = systemReservedResources(kc.SystemReserved)
+	it.Overhead.EvictionThreshold = evictionThreshold(it.Capacity.Memory(), it.Capacity.StorageEphemeral(), kc.EvictionHard, kc.EvictionSoft)
 	if it.Requirements.Compatible(scheduling.NewRequirements(scheduling.NewRequirement(corev1.LabelOSStable, corev1.NodeSelectorOpIn, string(corev1.Windows)))) == nil {
 		it.Capacity[v1alpha1.ResourcePrivateIPv4Address] = *privateIPv4Address(info)
 	}
@@ -172,6 +180,10 @@ func computeRequirements(info *ecsclient.DescribeInstanceTypesResponseBodyInstan
 		scheduling.NewRequirement(v1alpha1.LabelInstanceGPUManufacturer, corev1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1alpha1.LabelInstanceGPUCount, corev1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1alpha1.LabelInstanceGPUMemory, corev1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceLocalStorageSize, corev1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceLocalStorageCategory, corev1.NodeSelectorOpDoesNotExist),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceERDMA, corev1.NodeSelectorOpIn, fmt.Sprint(erdma(info))),
+		scheduling.NewRequirement(v1alpha1.LabelInstanceRDMA, corev1.NodeSelectorOpIn, fmt.Sprint(rdma(info))),
 	)
 	// Only add zone-id label when available in offerings. It may not be available if a user has upgraded from a
 	// previous version of Karpenter w/o zone-id support and the nodeclass vswitch status has not yet updated.
@@ -203,6 +215,12 @@ func computeRequirements(info *ecsclient.DescribeInstanceTypesResponseBodyInstan
 		requirements.Get(v1alpha1.LabelInstanceGPUMemory).Insert(fmt.Sprint(tea.Float32Value(info.GPUMemorySize)))
 	}
 
+	// Local Storage Labels
+	if localStorageGiB(info) > 0 {
+		requirements.Get(v1alpha1.LabelInstanceLocalStorageSize).Insert(fmt.Sprint(localStorageGiB(info)))
+		requirements.Get(v1alpha1.LabelInstanceLocalStorageCategory).Insert(tea.StringValue(info.LocalStorageCategory))
+	}
+
 	// CPU Manufacturer, valid options: intel, amd
 	if info.PhysicalProcessorModel != nil {
 		requirements.Get(v1alpha1.LabelInstanceCPUModel).Insert(getCPUModel(tea.StringValue(info.PhysicalProcessorModel)))
@@ -211,14 +229,25 @@ func computeRequirements(info *ecsclient.DescribeInstanceTypesResponseBodyInstan
 	return requirements
 }
 
+// erdma returns true when the instance type has elastic RDMA interfaces
+func erdma(info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType) bool {
+	return tea.Int32Value(info.EriQuantity) > 0
+}
+
+// rdma returns true when the network of the instance type supports RDMA, the RDMA enabled interfaces of the super
+// computing cluster families report their queue pairs
+func rdma(info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType) bool {
+	return erdma(info) || tea.Int32Value(info.QueuePairNumber) > 0
+}
+
 func computeCapacity(ctx context.Context,
 	info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType,
-	maxPods *int32, podsPerCore *int32, systemDisk *v1alpha1.SystemDisk, clusterCNI string) corev1.ResourceList {
+	maxPods *int32, podsPerCore *int32, systemDisk *v1alpha1.SystemDisk, instanceStorePolicy *string, clusterCNI string) corev1.ResourceList {
 
 	resourceList := corev1.ResourceList{
 		corev1.ResourceCPU:              *cpu(info),
 		corev1.ResourceMemory:           *memory(ctx, info),
-		corev1.ResourceEphemeralStorage: *ephemeralStorage(systemDisk),
+		corev1.ResourceEphemeralStorage: *ephemeralStorage(info, systemDisk, instanceStorePolicy),
 		corev1.ResourcePods:             *pods(ctx, info, maxPods, podsPerCore, clusterCNI),
 		v1alpha1.ResourceNVIDIAGPU:      *nvidiaGPUs(info),
 		v1alpha1.ResourceAMDGPU:         *amdGPUs(info),
@@ -227,6 +256,35 @@ func computeCapacity(ctx context.Context,
 	return resourceList
 }
 
+func kubeReservedResources(defaultOverhead corev1.ResourceList, kubeReserved map[string]string) corev1.ResourceList {
+	return lo.Assign(defaultOverhead, lo.MapEntries(kubeReserved, func(k string, v string) (corev1.ResourceName, resource.Quantity) {
+		return corev1.ResourceName(k), resource.MustParse(v)
+	}))
+}
+
+func systemReservedResources(systemReserved map[string]string) corev1.ResourceList {
+	return lo.MapEntries(systemReserved, func(k string, v string) (corev1.ResourceName, resource.Quantity) {
+		return corev1.ResourceName(k), resource.MustParse(v)
+	})
+}
+
+// evictionThreshold computes the eviction overhead from the hard and soft eviction signals,
+// the larger of the two wins when both are set for the same resource
+func evictionThreshold(memory *resource.Quantity, storage *resource.Quantity, evictionHard map[string]string, evictionSoft map[string]string) corev1.ResourceList {
+	overhead := corev1.ResourceList{}
+	for _, m := range []map[string]string{evictionHard, evictionSoft} {
+		temp := corev1.ResourceList{}
+		if v, ok := m[MemoryAvailable]; ok {
+			temp[corev1.ResourceMemory] = computeEvictionSignal(*memory, v)
+		}
+		if v, ok := m[NodeFSAvailable]; ok {
+			temp[corev1.ResourceEphemeralStorage] = computeEvictionSignal(*storage, v)
+		}
+		overhead = resources.MaxResources(overhead, temp)
+	}
+	return overhead
+}
+
 // computeEvictionSignal computes the resource quantity value for an eviction signal value, computed off the
 // base capacity value if the signal value is a percentage or as a resource quantity if the signal value isn't a percentage
 func computeEvictionSignal(capacity resource.Quantity, signalValue string) resource.Quantity {
@@ -338,7 +396,12 @@ func getCPUModel(cpuName string) string {
 	return ""
 }
 
-func ephemeralStorage(systemDisk *v1alpha1.SystemDisk) *resource.Quantity {
+func ephemeralStorage(info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType,
+	systemDisk *v1alpha1.SystemDisk, instanceStorePolicy *string) *resource.Quantity {
+	// The local disks are mounted for containerd and kubelet, so they hold the ephemeral storage
+	if lo.FromPtr(instanceStorePolicy) == v1alpha1.InstanceStorePolicyRAID0 && localStorageGiB(info) > 0 {
+		return resources.Quantity(fmt.Sprintf("%dGi", localStorageGiB(info)))
+	}
 	if systemDisk == nil || systemDisk.VolumeSize == nil {
 		return imagefamily.DefaultSystemDisk.VolumeSize
 	}
@@ -346,6 +409,11 @@ func ephemeralStorage(systemDisk *v1alpha1.SystemDisk) *resource.Quantity {
 	return systemDisk.VolumeSize
 }
 
+// localStorageGiB returns the total capacity of the local disks of the instance type
+func localStorageGiB(info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType) int64 {
+	return int64(tea.Int32Value(info.LocalStorageAmount)) * tea.Int64Value(info.LocalStorageCapacity)
+}
+
 func privateIPv4Address(info *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType) *resource.Quantity {
 	return resources.Quantity(fmt.Sprint(*info.EniPrivateIpAddressQuantity * (*info.EniQuantity)))
 }
@@ -0,0 +1,202 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"testing"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+)
+
+func Test_kubeReservedResources(t *testing.T) {
+	defaultOverhead := calculateResourceOverhead(110, 4000, 8192)
+
+	tests := []struct {
+		name         string
+		kubeReserved map[string]string
+		expected     corev1.ResourceList
+	}{
+		{
+			name:     "fall back to the ACK formula when unset",
+			expected: defaultOverhead,
+		},
+		{
+			name:         "user supplied resources override the ACK formula",
+			kubeReserved: map[string]string{"cpu": "500m", "ephemeral-storage": "1Gi"},
+			expected: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("500m"),
+				corev1.ResourceMemory:           defaultOverhead[corev1.ResourceMemory],
+				corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, kubeReservedResources(defaultOverhead, tt.kubeReserved))
+		})
+	}
+}
+
+func Test_systemReservedResources(t *testing.T) {
+	assert.Empty(t, systemReservedResources(nil))
+	assert.Equal(t, corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	}, systemReservedResources(map[string]string{"cpu": "100m", "memory": "256Mi"}))
+}
+
+func Test_evictionThreshold(t *testing.T) {
+	memory := resource.MustParse("8Gi")
+	storage := resource.MustParse("40Gi")
+
+	tests := []struct {
+		name         string
+		evictionHard map[string]string
+		evictionSoft map[string]string
+		expected     map[corev1.ResourceName]int64
+	}{
+		{
+			name:     "no eviction signals",
+			expected: map[corev1.ResourceName]int64{},
+		},
+		{
+			name:         "hard eviction signals",
+			evictionHard: map[string]string{MemoryAvailable: "100Mi", NodeFSAvailable: "10%"},
+			expected: map[corev1.ResourceName]int64{
+				corev1.ResourceMemory:           100 * 1024 * 1024,
+				corev1.ResourceEphemeralStorage: 4 * 1024 * 1024 * 1024,
+			},
+		},
+		{
+			name:         "the larger of hard and soft eviction signals wins",
+			evictionHard: map[string]string{MemoryAvailable: "100Mi", NodeFSAvailable: "10%"},
+			evictionSoft: map[string]string{MemoryAvailable: "512Mi", NodeFSAvailable: "1Gi"},
+			expected: map[corev1.ResourceName]int64{
+				corev1.ResourceMemory:           512 * 1024 * 1024,
+				corev1.ResourceEphemeralStorage: 4 * 1024 * 1024 * 1024,
+			},
+		},
+		{
+			name:         "100% disables the threshold",
+			evictionHard: map[string]string{MemoryAvailable: "100%"},
+			expected: map[corev1.ResourceName]int64{
+				corev1.ResourceMemory: 0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := evictionThreshold(&memory, &storage, tt.evictionHard, tt.evictionSoft)
+			assert.Len(t, got, len(tt.expected))
+			for name, value := range tt.expected {
+				q := got[name]
+				assert.Equal(t, value, q.Value(), name)
+			}
+		})
+	}
+}
+
+func Test_ephemeralStorage(t *testing.T) {
+	localStorage := &ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType{
+		LocalStorageAmount:   tea.Int32(2),
+		LocalStorageCapacity: tea.Int64(1788),
+		LocalStorageCategory: tea.String("local_ssd_pro"),
+	}
+	systemDisk := &v1alpha1.SystemDisk{VolumeSize: lo.ToPtr(resource.MustParse("40Gi"))}
+
+	tests := []struct {
+		name                string
+		info                *ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType
+		instanceStorePolicy *string
+		expected            resource.Quantity
+	}{
+		{
+			name:     "system disk without the instance store policy",
+			info:     localStorage,
+			expected: resource.MustParse("40Gi"),
+		},
+		{
+			name:                "system disk without local disks",
+			info:                &ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType{},
+			instanceStorePolicy: tea.String(v1alpha1.InstanceStorePolicyRAID0),
+			expected:            resource.MustParse("40Gi"),
+		},
+		{
+			name:                "aggregated local disks",
+			info:                localStorage,
+			instanceStorePolicy: tea.String(v1alpha1.InstanceStorePolicyRAID0),
+			expected:            resource.MustParse("3576Gi"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.expected.Equal(*ephemeralStorage(tt.info, systemDisk, tt.instanceStorePolicy)))
+		})
+	}
+}
+
+func Test_computeRequirementsLocalStorage(t *testing.T) {
+	info := &ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType{
+		InstanceTypeId:       tea.String("ecs.i4.2xlarge"),
+		InstanceTypeFamily:   tea.String("ecs.i4"),
+		CpuArchitecture:      tea.String("X86"),
+		CpuCoreCount:         tea.Int32(8),
+		MemorySize:           tea.Float32(64),
+		LocalStorageAmount:   tea.Int32(1),
+		LocalStorageCapacity: tea.Int64(1788),
+		LocalStorageCategory: tea.String("local_ssd_pro"),
+	}
+
+	requirements := computeRequirements(info, cloudprovider.Offerings{}, "cn-beijing")
+	assert.Equal(t, "1788", requirements.Get(v1alpha1.LabelInstanceLocalStorageSize).Any())
+	assert.Equal(t, "local_ssd_pro", requirements.Get(v1alpha1.LabelInstanceLocalStorageCategory).Any())
+}
+
+func Test_computeRequirementsRDMA(t *testing.T) {
+	info := &ecsclient.DescribeInstanceTypesResponseBodyInstanceTypesInstanceType{
+		InstanceTypeId:     tea.String("ecs.g8ae.4xlarge"),
+		InstanceTypeFamily: tea.String("ecs.g8ae"),
+		CpuArchitecture:    tea.String("X86"),
+		CpuCoreCount:       tea.Int32(16),
+		MemorySize:         tea.Float32(64),
+	}
+	requirements := computeRequirements(info, cloudprovider.Offerings{}, "cn-beijing")
+	assert.Equal(t, "false", requirements.Get(v1alpha1.LabelInstanceERDMA).Any())
+	assert.Equal(t, "false", requirements.Get(v1alpha1.LabelInstanceRDMA).Any())
+
+	info.QueuePairNumber = tea.Int32(8)
+	requirements = computeRequirements(info, cloudprovider.Offerings{}, "cn-beijing")
+	assert.Equal(t, "false", requirements.Get(v1alpha1.LabelInstanceERDMA).Any())
+	assert.Equal(t, "true", requirements.Get(v1alpha1.LabelInstanceRDMA).Any())
+
+	info.QueuePairNumber = nil
+	info.EriQuantity = tea.Int32(1)
+	requirements = computeRequirements(info, cloudprovider.Offerings{}, "cn-beijing")
+	assert.Equal(t, "true", requirements.Get(v1alpha1.LabelInstanceERDMA).Any())
+	assert.Equal(t, "true", requirements.Get(v1alpha1.LabelInstanceRDMA).Any())
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ramrole
+
+import (
+	"context"
+	"fmt"
+
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
+)
+
+const (
+	// Endpoint is the RAM endpoint, RAM is a global service so it is not region specific
+	Endpoint = "ram.aliyuncs.com"
+	// The RAM API is called through the generic OpenAPI client to avoid depending on the RAM SDK
+	apiVersion = "2015-05-01"
+)
+
+type Role struct {
+	Name string
+	Arn  string
+}
+
+type Provider interface {
+	// Get returns the RAM role with the given name, or nil if the role does not exist
+	Get(context.Context, string) (*Role, error)
+}
+
+type DefaultProvider struct {
+	ramClient sdk.RAMAPI
+	cache     *cache.Cache
+}
+
+func NewDefaultProvider(ramClient sdk.RAMAPI, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		ramClient: ramClient,
+		cache:     cache,
+	}
+}
+
+func (p *DefaultProvider) Get(_ context.Context, name string) (*Role, error) {
+	if role, ok := p.cache.Get(name); ok {
+		return role.(*Role), nil
+	}
+
+	params := &openapi.Params{
+		Action:      tea.String("GetRole"),
+		Version:     tea.String(apiVersion),
+		Protocol:    tea.String("HTTPS"),
+		Pathname:    tea.String("/"),
+		Method:      tea.String("POST"),
+		AuthType:    tea.String("AK"),
+		Style:       tea.String("RPC"),
+		ReqBodyType: tea.String("formData"),
+		BodyType:    tea.String("json"),
+	}
+	request := &openapi.OpenApiRequest{
+		Query: map[string]*string{"RoleName": tea.String(name)},
+	}
+	resp, err := p.ramClient.CallApi(params, request, &util.RuntimeOptions{})
+	if err != nil {
+		if alierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting ram role %s, %w", name, err)
+	}
+
+	body, _ := resp["body"].(map[string]interface{})
+	r, ok := body["Role"].(map[string]interface{})
+	if !ok {
+		return nil, alierrors.WithRequestID(fmt.Sprint(body["RequestId"]), fmt.Errorf("unexpected null value was returned"))
+	}
+	role := &Role{
+		Name: name,
+	}
+	if roleName, ok := r["RoleName"].(string); ok {
+		role.Name = roleName
+	}
+	if arn, ok := r["Arn"].(string); ok {
+		role.Arn = arn
+	}
+	p.cache.SetDefault(name, role)
+	return role, nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ramrole
+
+import (
+	"context"
+	"testing"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+)
+
+func TestDefaultProvider_Get(t *testing.T) {
+	ramAPI := fake.NewRAMAPI()
+	ramAPI.AddRoles("KarpenterNodeRole")
+	provider := NewDefaultProvider(ramAPI, cache.New(cache.NoExpiration, cache.NoExpiration))
+
+	role, err := provider.Get(context.Background(), "KarpenterNodeRole")
+	require.NoError(t, err)
+	assert.Equal(t, &Role{Name: "KarpenterNodeRole", Arn: "acs:ram::1234567890:role/KarpenterNodeRole"}, role)
+
+	// The role is cached
+	_, err = provider.Get(context.Background(), "KarpenterNodeRole")
+	require.NoError(t, err)
+	assert.Equal(t, 1, ramAPI.GetRoleCount())
+}
+
+func TestDefaultProvider_GetNotFound(t *testing.T) {
+	ramAPI := fake.NewRAMAPI()
+	provider := NewDefaultProvider(ramAPI, cache.New(cache.NoExpiration, cache.NoExpiration))
+
+	role, err := provider.Get(context.Background(), "KarpenterNodeRole")
+	require.NoError(t, err)
+	assert.Nil(t, role)
+
+	// A missing role isn't cached, it may be created later
+	ramAPI.AddRoles("KarpenterNodeRole")
+	role, err = provider.Get(context.Background(), "KarpenterNodeRole")
+	require.NoError(t, err)
+	require.NotNil(t, role)
+	assert.Equal(t, "KarpenterNodeRole", role.Name)
+}
+
+func TestDefaultProvider_GetError(t *testing.T) {
+	ramAPI := fake.NewRAMAPI()
+	ramAPI.AddRoles("KarpenterNodeRole")
+	ramAPI.GetRoleError.Set(fake.NewThrottlingError())
+	provider := NewDefaultProvider(ramAPI, cache.New(cache.NoExpiration, cache.NoExpiration))
+
+	role, err := provider.Get(context.Background(), "KarpenterNodeRole")
+	assert.Error(t, err)
+	assert.Nil(t, role)
+}
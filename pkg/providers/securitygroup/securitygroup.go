@@ -30,6 +30,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
 )
@@ -41,14 +42,14 @@ type Provider interface {
 type DefaultProvider struct {
 	sync.Mutex
 	region string
-	ecsapi *ecs.Client
+	ecsapi sdk.ECSAPI
 	cache  *cache.Cache
 	cm     *pretty.ChangeMonitor
 	// TODO: Alibaba Cloud security groups have a limit on the number of IP addresses, may need to prevent miss
 	// And the available IPs returned by the API are not real-time. It is likely that an IP cache like VSwitchProvider will be needed later.
 }
 
-func NewDefaultProvider(region string, ecsapi *ecs.Client, cache *cache.Cache) *DefaultProvider {
+func NewDefaultProvider(region string, ecsapi sdk.ECSAPI, cache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
 		region: region,
 		ecsapi: ecsapi,
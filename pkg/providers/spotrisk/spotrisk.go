@@ -0,0 +1,239 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spotrisk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/cache"
+)
+
+const (
+	// volatilityCeiling is the coefficient of variation of the spot price at which the price risk is the highest
+	volatilityCeiling = 0.25
+	// interruptionCeiling is the amount of interruptions within the window at which the interruption risk is the highest
+	interruptionCeiling = 3
+	// maxConcurrentQueries is the amount of instance types whose spot price history is queried at the same time
+	maxConcurrentQueries = 10
+	// timeFormat is the ISO 8601 format of the spot price history
+	timeFormat = "2006-01-02T15:04:05Z"
+)
+
+type Provider interface {
+	LivenessProbe(*http.Request) error
+	// Score returns the interruption risk of the spot offering, from 0 (safe) to 100 (very likely to be reclaimed)
+	Score(instanceType, zone string) int32
+	// SeqNum changes every time the scores change
+	SeqNum() uint64
+	RecordInterruption(ctx context.Context, instanceType, zone string)
+	UpdateSpotPriceHistory(ctx context.Context, instanceTypes []string) error
+}
+
+type offering struct {
+	instanceType string
+	zone         string
+}
+
+// DefaultProvider scores the interruption risk of the spot offerings from the volatility of their spot price and
+// the interruptions observed on them within the cache.SpotRiskWindow. The interruptions are only kept in memory, so
+// the scores start from the spot price alone after a restart until new interruptions are observed.
+type DefaultProvider struct {
+	region    string
+	ecsClient sdk.ECSAPI
+	clk       clock.Clock
+
+	mu sync.RWMutex
+	// volatility is the coefficient of variation of the spot price of the offerings
+	volatility map[offering]float64
+	// interruptions keeps the time of the interruptions observed on the offerings
+	interruptions map[offering][]time.Time
+	seqNum        atomic.Uint64
+}
+
+func NewDefaultProvider(region string, ecsClient sdk.ECSAPI, clk clock.Clock) *DefaultProvider {
+	return &DefaultProvider{
+		region:        region,
+		ecsClient:     ecsClient,
+		clk:           clk,
+		volatility:    map[offering]float64{},
+		interruptions: map[offering][]time.Time{},
+	}
+}
+
+func (p *DefaultProvider) LivenessProbe(_ *http.Request) error {
+	// ensure we don't deadlock and nolint for the empty critical section
+	p.mu.Lock()
+	//nolint: staticcheck
+	p.mu.Unlock()
+	return nil
+}
+
+// Score combines the price risk and the interruption risk as independent probabilities
+func (p *DefaultProvider) Score(instanceType, zone string) int32 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	key := offering{instanceType: instanceType, zone: zone}
+	priceRisk := math.Min(1, p.volatility[key]/volatilityCeiling)
+	since := p.clk.Now().Add(-cache.SpotRiskWindow)
+	interruptions := lo.CountBy(p.interruptions[key], func(t time.Time) bool { return t.After(since) })
+	interruptionRisk := math.Min(1, float64(interruptions)/interruptionCeiling)
+	return int32(math.Round(100 * (1 - (1-priceRisk)*(1-interruptionRisk))))
+}
+
+func (p *DefaultProvider) SeqNum() uint64 {
+	return p.seqNum.Load()
+}
+
+// RecordInterruption is called when a spot instance of the offering is reclaimed
+func (p *DefaultProvider) RecordInterruption(ctx context.Context, instanceType, zone string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := offering{instanceType: instanceType, zone: zone}
+	p.interruptions[key] = append(p.interruptions[key], p.clk.Now())
+	p.seqNum.Add(1)
+	log.FromContext(ctx).WithValues("instance-type", instanceType, "zone", zone, "interruptions", len(p.interruptions[key])).
+		V(1).Info("recorded spot interruption")
+}
+
+// UpdateSpotPriceHistory refreshes the volatility of the spot prices of the instance types, forgets the volatility
+// of the other instance types and the interruptions which left the window
+func (p *DefaultProvider) UpdateSpotPriceHistory(ctx context.Context, instanceTypes []string) error {
+	now := p.clk.Now()
+
+	volatilities := make([]map[string]float64, len(instanceTypes))
+	errs := make([]error, len(instanceTypes))
+	workqueue.ParallelizeUntil(ctx, maxConcurrentQueries, len(instanceTypes), func(i int) {
+		prices, err := p.describeSpotPriceHistory(instanceTypes[i], now)
+		if err != nil {
+			errs[i] = fmt.Errorf("describing spot price history of %s, %w", instanceTypes[i], err)
+			return
+		}
+		volatilities[i] = lo.MapValues(prices, func(zonePrices []float64, _ string) float64 { return coefficientOfVariation(zonePrices) })
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	changed := false
+	queried := sets.New(instanceTypes...)
+	for key := range p.volatility {
+		if !queried.Has(key.instanceType) {
+			delete(p.volatility, key)
+			changed = true
+		}
+	}
+	for i, instanceType := range instanceTypes {
+		// Keep the previous volatility when the query failed
+		if errs[i] != nil {
+			continue
+		}
+		for zone, volatility := range volatilities[i] {
+			key := offering{instanceType: instanceType, zone: zone}
+			if p.volatility[key] != volatility {
+				p.volatility[key] = volatility
+				changed = true
+			}
+		}
+	}
+	since := now.Add(-cache.SpotRiskWindow)
+	for key, times := range p.interruptions {
+		recent := lo.Filter(times, func(t time.Time, _ int) bool { return t.After(since) })
+		if len(recent) == len(times) {
+			continue
+		}
+		changed = true
+		if len(recent) == 0 {
+			delete(p.interruptions, key)
+			continue
+		}
+		p.interruptions[key] = recent
+	}
+	if changed {
+		p.seqNum.Add(1)
+	}
+
+	if err := multierr.Combine(errs...); err != nil {
+		return err
+	}
+	log.FromContext(ctx).WithValues("instance-type-count", len(instanceTypes), "offering-count", len(p.volatility)).
+		V(1).Info("updated spot price history")
+	return nil
+}
+
+// describeSpotPriceHistory returns the spot prices of the instance type within the window, grouped by zone
+func (p *DefaultProvider) describeSpotPriceHistory(instanceType string, now time.Time) (map[string][]float64, error) {
+	request := &ecsclient.DescribeSpotPriceHistoryRequest{
+		RegionId:     tea.String(p.region),
+		InstanceType: tea.String(instanceType),
+		NetworkType:  tea.String("vpc"),
+		StartTime:    tea.String(now.Add(-cache.SpotRiskWindow).UTC().Format(timeFormat)),
+		EndTime:      tea.String(now.UTC().Format(timeFormat)),
+	}
+
+	prices := map[string][]float64{}
+	for {
+		resp, err := p.ecsClient.DescribeSpotPriceHistoryWithOptions(request, &util.RuntimeOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Body == nil || resp.Body.SpotPrices == nil {
+			return nil, fmt.Errorf("unexpected null value was returned")
+		}
+		for _, price := range resp.Body.SpotPrices.SpotPriceType {
+			zone := tea.StringValue(price.ZoneId)
+			prices[zone] = append(prices[zone], float64(tea.Float32Value(price.SpotPrice)))
+		}
+
+		nextOffset := tea.Int32Value(resp.Body.NextOffset)
+		if nextOffset == 0 || nextOffset == tea.Int32Value(request.Offset) || len(resp.Body.SpotPrices.SpotPriceType) == 0 {
+			return prices, nil
+		}
+		request.Offset = tea.Int32(nextOffset)
+	}
+}
+
+// coefficientOfVariation is the standard deviation of the prices relative to their mean
+func coefficientOfVariation(prices []float64) float64 {
+	if len(prices) < 2 {
+		return 0
+	}
+	mean := lo.Sum(prices) / float64(len(prices))
+	if mean == 0 {
+		return 0
+	}
+	variance := lo.SumBy(prices, func(price float64) float64 { return (price - mean) * (price - mean) }) / float64(len(prices))
+	return math.Sqrt(variance) / mean
+}
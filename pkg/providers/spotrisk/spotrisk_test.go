@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spotrisk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ecsclient "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+)
+
+func newTestProvider(t *testing.T) (*DefaultProvider, *fake.ECSAPI, *clocktesting.FakeClock) {
+	t.Helper()
+
+	ecsAPI := fake.NewECSAPI(nil)
+	clk := clocktesting.NewFakeClock(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	return NewDefaultProvider(fake.DefaultRegion, ecsAPI, clk), ecsAPI, clk
+}
+
+func spotPrice(zone, timestamp string, price float32) *ecsclient.DescribeSpotPriceHistoryResponseBodySpotPricesSpotPriceType {
+	return &ecsclient.DescribeSpotPriceHistoryResponseBodySpotPricesSpotPriceType{
+		InstanceType: tea.String("ecs.g7.large"),
+		NetworkType:  tea.String("vpc"),
+		ZoneId:       tea.String(zone),
+		Timestamp:    tea.String(timestamp),
+		SpotPrice:    tea.Float32(price),
+	}
+}
+
+func TestDefaultProvider_ScorePriceVolatility(t *testing.T) {
+	provider, ecsAPI, _ := newTestProvider(t)
+	ecsAPI.AddSpotPriceHistory(
+		spotPrice("cn-beijing-a", "2026-10-16T01:00:00Z", 0.2),
+		spotPrice("cn-beijing-a", "2026-10-16T06:00:00Z", 0.2),
+		spotPrice("cn-beijing-b", "2026-10-16T01:00:00Z", 0.2),
+		spotPrice("cn-beijing-b", "2026-10-16T06:00:00Z", 0.24),
+		spotPrice("cn-beijing-c", "2026-10-16T01:00:00Z", 0.1),
+		spotPrice("cn-beijing-c", "2026-10-16T06:00:00Z", 0.3),
+		// Out of the window
+		spotPrice("cn-beijing-a", "2026-10-14T06:00:00Z", 0.9),
+	)
+
+	require.NoError(t, provider.UpdateSpotPriceHistory(context.Background(), []string{"ecs.g7.large"}))
+	assert.Equal(t, int32(0), provider.Score("ecs.g7.large", "cn-beijing-a"))
+	assert.Equal(t, int32(36), provider.Score("ecs.g7.large", "cn-beijing-b"))
+	assert.Equal(t, int32(100), provider.Score("ecs.g7.large", "cn-beijing-c"))
+	assert.Equal(t, int32(0), provider.Score("ecs.g7.large", "cn-beijing-unknown"))
+}
+
+func TestDefaultProvider_ScoreInterruptions(t *testing.T) {
+	provider, _, clk := newTestProvider(t)
+	ctx := context.Background()
+
+	seqNum := provider.SeqNum()
+	provider.RecordInterruption(ctx, "ecs.g7.large", "cn-beijing-a")
+	assert.Equal(t, int32(33), provider.Score("ecs.g7.large", "cn-beijing-a"))
+	assert.NotEqual(t, seqNum, provider.SeqNum())
+
+	provider.RecordInterruption(ctx, "ecs.g7.large", "cn-beijing-a")
+	provider.RecordInterruption(ctx, "ecs.g7.large", "cn-beijing-a")
+	provider.RecordInterruption(ctx, "ecs.g7.large", "cn-beijing-a")
+	assert.Equal(t, int32(100), provider.Score("ecs.g7.large", "cn-beijing-a"))
+
+	// The interruptions are forgotten once they leave the window
+	clk.Step(25 * time.Hour)
+	seqNum = provider.SeqNum()
+	require.NoError(t, provider.UpdateSpotPriceHistory(ctx, nil))
+	assert.Equal(t, int32(0), provider.Score("ecs.g7.large", "cn-beijing-a"))
+	assert.NotEqual(t, seqNum, provider.SeqNum())
+}
+
+func TestDefaultProvider_UpdateSpotPriceHistoryError(t *testing.T) {
+	provider, ecsAPI, _ := newTestProvider(t)
+	ecsAPI.AddSpotPriceHistory(
+		spotPrice("cn-beijing-c", "2026-10-16T01:00:00Z", 0.1),
+		spotPrice("cn-beijing-c", "2026-10-16T06:00:00Z", 0.3),
+	)
+	require.NoError(t, provider.UpdateSpotPriceHistory(context.Background(), []string{"ecs.g7.large"}))
+
+	// The previous scores are kept when the history can't be described
+	ecsAPI.DescribeSpotPriceHistoryError.Set(errors.New("throttled"))
+	assert.Error(t, provider.UpdateSpotPriceHistory(context.Background(), []string{"ecs.g7.large"}))
+	assert.Equal(t, int32(100), provider.Score("ecs.g7.large", "cn-beijing-c"))
+}
+
+func TestDefaultProvider_UpdateSpotPriceHistoryUnusedInstanceTypes(t *testing.T) {
+	provider, ecsAPI, _ := newTestProvider(t)
+	ecsAPI.AddSpotPriceHistory(
+		spotPrice("cn-beijing-c", "2026-10-16T01:00:00Z", 0.1),
+		spotPrice("cn-beijing-c", "2026-10-16T06:00:00Z", 0.3),
+	)
+	require.NoError(t, provider.UpdateSpotPriceHistory(context.Background(), []string{"ecs.g7.large"}))
+	assert.Equal(t, int32(100), provider.Score("ecs.g7.large", "cn-beijing-c"))
+
+	// The spot price history isn't described once no node class offers the instance type
+	seqNum := provider.SeqNum()
+	ecsAPI.DescribeSpotPriceHistoryError.Set(errors.New("throttled"))
+	require.NoError(t, provider.UpdateSpotPriceHistory(context.Background(), nil))
+	assert.Equal(t, int32(0), provider.Score("ecs.g7.large", "cn-beijing-c"))
+	assert.NotEqual(t, seqNum, provider.SeqNum())
+}
@@ -36,22 +36,27 @@ import (
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
+	sdk "github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/alibabacloud"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
 	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/utils/alierrors"
 )
 
+// shareTypeShared is the share type of the vSwitches shared with the account by another account
+const shareTypeShared = "Shared"
+
 type Provider interface {
 	LivenessProbe(*http.Request) error
 	List(context.Context, *v1alpha1.ECSNodeClass) ([]*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch, error)
 	ZonalVSwitchesForLaunch(context.Context, *v1alpha1.ECSNodeClass, []*cloudprovider.InstanceType, string) (map[string]*VSwitch, error)
-	UpdateInflightIPs(*ecs.CreateAutoProvisioningGroupRequest, *ecs.DescribeInstancesResponseBodyInstances, []*cloudprovider.InstanceType, []*VSwitch, string)
+	UpdateInflightIPs(*ecs.CreateAutoProvisioningGroupResponse, []*cloudprovider.InstanceType, []*VSwitch, string)
+	MarkIPsExhausted(string)
 }
 
 type DefaultProvider struct {
 	region string
 
 	sync.Mutex
-	vpcapi                  *vpc.Client
+	vpcapi                  sdk.VPCAPI
 	cache                   *cache.Cache
 	availableIPAddressCache *cache.Cache
 	cm                      *pretty.ChangeMonitor
@@ -64,7 +69,7 @@ type VSwitch struct {
 	AvailableIPAddressCount int64
 }
 
-func NewDefaultProvider(region string, vpcapi *vpc.Client, cache *cache.Cache, availableIPAddressCache *cache.Cache) *DefaultProvider {
+func NewDefaultProvider(region string, vpcapi sdk.VPCAPI, cache *cache.Cache, availableIPAddressCache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
 		region: region,
 		vpcapi: vpcapi,
@@ -92,7 +97,7 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1alpha1.ECSNodeC
 	if switches, ok := p.cache.Get(fmt.Sprint(hash)); ok {
 		// Ensure what's returned from this function is a shallow-copy of the slice (not a deep-copy of the data itself)
 		// so that modifications to the ordering of the data don't affect the original
-		return append([]*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch{}, switches.([]*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch)...), nil
+		return publicIPVSwitches(nodeClass, ipv6VSwitches(nodeClass, append([]*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch{}, switches.([]*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch)...))), nil
 	}
 
 	// Ensure that all the vSwitches that are returned here are unique
@@ -138,7 +143,28 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1alpha1.ECSNodeC
 				}
 			})).V(1).Info("discovered vSwitches")
 	}
-	return lo.Values(vSwitches), nil
+	return publicIPVSwitches(nodeClass, ipv6VSwitches(nodeClass, lo.Values(vSwitches))), nil
+}
+
+// ipv6VSwitches keeps the vSwitches with IPv6 enabled when the ECSNodeClass assigns IPv6 addresses to the instances
+func ipv6VSwitches(nodeClass *v1alpha1.ECSNodeClass, vSwitches []*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch) []*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch {
+	if nodeClass.Spec.IPv6AddressCount == nil {
+		return vSwitches
+	}
+	return lo.Filter(vSwitches, func(vSwitch *vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch, _ int) bool {
+		return lo.FromPtr(vSwitch.EnabledIpv6) && lo.FromPtr(vSwitch.Ipv6CidrBlock) != ""
+	})
+}
+
+// publicIPVSwitches drops the vSwitches shared from another account when the ECSNodeClass assigns public IP addresses
+// to the instances, the instances launched into a shared vSwitch can't be assigned a public IP address
+func publicIPVSwitches(nodeClass *v1alpha1.ECSNodeClass, vSwitches []*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch) []*vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch {
+	if !lo.FromPtr(nodeClass.Spec.AssociatePublicIPAddress) {
+		return vSwitches
+	}
+	return lo.Reject(vSwitches, func(vSwitch *vpc.DescribeVSwitchesResponseBodyVSwitchesVSwitch, _ int) bool {
+		return lo.FromPtr(vSwitch.ShareType) == shareTypeShared
+	})
 }
 
 // ZonalVSwitchesForLaunch returns a mapping of zone to the vSwitch with the most available IP addresses and deducts the passed ips from the available count
@@ -152,6 +178,9 @@ func (p *DefaultProvider) ZonalVSwitchesForLaunch(ctx context.Context, nodeClass
 
 	availableIPAddressCount := map[string]int64{}
 	for _, vSwitch := range nodeClass.Status.VSwitches {
+		// A missing or expired entry doesn't mean the vSwitch is exhausted, the count resolved into the status
+		// is used until the vSwitches are listed again
+		availableIPAddressCount[vSwitch.ID] = vSwitch.AvailableIPAddressCount
 		if availableIP, ok := p.availableIPAddressCache.Get(vSwitch.ID); ok {
 			availableIPAddressCount[vSwitch.ID] = availableIP.(int64)
 		}
@@ -176,7 +205,7 @@ func (p *DefaultProvider) ZonalVSwitchesForLaunch(ctx context.Context, nodeClass
 		zonalVSwitches[vSwitch.ZoneID] = &VSwitch{ID: vSwitch.ID, ZoneID: vSwitch.ZoneID, AvailableIPAddressCount: availableIPAddressCount[vSwitch.ID]}
 	}
 
-	for _, vSwitch := range zonalVSwitches {
+	for zoneID, vSwitch := range zonalVSwitches {
 		predictedIPsUsed := p.minPods(instanceTypes, scheduling.NewRequirements(
 			scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, capacityType),
 			scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, vSwitch.ZoneID),
@@ -185,38 +214,49 @@ func (p *DefaultProvider) ZonalVSwitchesForLaunch(ctx context.Context, nodeClass
 		if trackedIPs, ok := p.inflightIPs[vSwitch.ID]; ok {
 			prevIPs = trackedIPs
 		}
+		// The vSwitch can't fit the pods of any of the instance types, launching into it would fail
+		if prevIPs < predictedIPsUsed {
+			log.FromContext(ctx).WithValues("vSwitch", vSwitch.ID, "zone", vSwitch.ZoneID, "availableIPs", prevIPs, "predictedIPs", predictedIPsUsed).
+				V(1).Info("excluding vSwitch without enough available IPs from launch")
+			delete(zonalVSwitches, zoneID)
+			continue
+		}
 		p.inflightIPs[vSwitch.ID] = prevIPs - predictedIPsUsed
 	}
+	if len(zonalVSwitches) == 0 {
+		return nil, fmt.Errorf("no vSwitches have enough available IPs for the instance types")
+	}
 	return zonalVSwitches, nil
 }
 
-// UpdateInflightIPs is used to refresh the in-memory IP usage by adding back unused IPs after a CreateAutoProvisioningGroup response is returned
-func (p *DefaultProvider) UpdateInflightIPs(createAutoProvisioningGroupRequest *ecs.CreateAutoProvisioningGroupRequest, createAutoProvisioningGroupResponse *ecs.DescribeInstancesResponseBodyInstances, instanceTypes []*cloudprovider.InstanceType,
+// UpdateInflightIPs is used to refresh the in-memory IP usage by adding back unused IPs after a CreateAutoProvisioningGroup response is returned,
+// the response is nil when the launch failed
+func (p *DefaultProvider) UpdateInflightIPs(createAutoProvisioningGroupResponse *ecs.CreateAutoProvisioningGroupResponse, instanceTypes []*cloudprovider.InstanceType,
 	vSwitches []*VSwitch, capacityType string) {
 	p.Lock()
 	defer p.Unlock()
 
-	// Process the CreateAutoProvisioningGroupRequest to pull out all the requested VSwitchIDs
-	reqeustVSwitches := lo.Compact(lo.Uniq(lo.Map(createAutoProvisioningGroupRequest.LaunchTemplateConfig, func(req *ecs.CreateAutoProvisioningGroupRequestLaunchTemplateConfig, _ int) string {
-		if req == nil {
-			return ""
-		}
-		return lo.FromPtr(req.VSwitchId)
-	})))
+	// The IPs were deducted from all the vSwitches passed for launch
+	requestVSwitches := lo.Map(vSwitches, func(vSwitch *VSwitch, _ int) string { return vSwitch.ID })
 
-	// Process the CreateAutoProvisioningGroupResponse to pull out all the fulfilled VSwitchIDs
+	// Process the CreateAutoProvisioningGroupResponse to pull out all the fulfilled VSwitchIDs, the launch results only
+	// contain the zone, there is a single vSwitch per zone for launch
 	var responseVSwitches []string
-	if createAutoProvisioningGroupResponse != nil {
-		responseVSwitches = lo.Compact(lo.Uniq(lo.Map(createAutoProvisioningGroupResponse.Instance, func(instance *ecs.DescribeInstancesResponseBodyInstancesInstance, _ int) string {
-			if instance == nil || instance.VpcAttributes == nil || instance.VpcAttributes.VSwitchId == nil {
-				return ""
+	if createAutoProvisioningGroupResponse != nil && createAutoProvisioningGroupResponse.Body != nil && createAutoProvisioningGroupResponse.Body.LaunchResults != nil {
+		for _, launchResult := range createAutoProvisioningGroupResponse.Body.LaunchResults.LaunchResult {
+			if launchResult == nil || launchResult.InstanceIds == nil || len(launchResult.InstanceIds.InstanceId) == 0 {
+				continue
+			}
+			if vSwitch, ok := lo.Find(vSwitches, func(vSwitch *VSwitch) bool {
+				return vSwitch.ZoneID == lo.FromPtr(launchResult.ZoneId)
+			}); ok {
+				responseVSwitches = append(responseVSwitches, vSwitch.ID)
 			}
-			return lo.FromPtr(instance.VpcAttributes.VSwitchId)
-		})))
+		}
 	}
 
-	// Find the VSwitches that were included in the input but not chosen by Fleet, so we need to add the inflight IPs back to them
-	vSwitchIDsToAddBackIPs, _ := lo.Difference(reqeustVSwitches, responseVSwitches)
+	// Find the VSwitches that were included in the input but not chosen by the auto provisioning group, so we need to add the inflight IPs back to them
+	vSwitchIDsToAddBackIPs, _ := lo.Difference(requestVSwitches, lo.Uniq(responseVSwitches))
 
 	// Aggregate all the cached vSwitches ip address count
 	cachedAvailableIPAddressMap := lo.MapEntries(p.availableIPAddressCache.Items(), func(k string, v cache.Item) (string, int64) {
@@ -238,7 +278,7 @@ func (p *DefaultProvider) UpdateInflightIPs(createAutoProvisioningGroupRequest *
 		// If the cached vSwitch IP address count hasn't changed from the original vSwitch used to
 		// launch the instance, then we need to update the tracked IPs
 		if originalVSwitch.AvailableIPAddressCount == cachedIPAddressCount {
-			// other IPs deducted were opportunistic and need to be readded since Fleet didn't pick those vSwitches to launch into
+			// other IPs deducted were opportunistic and need to be readded since the auto provisioning group didn't pick those vSwitches to launch into
 			if ips, ok := p.inflightIPs[originalVSwitch.ID]; ok {
 				minPods := p.minPods(instanceTypes, scheduling.NewRequirements(
 					scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, capacityType),
@@ -250,6 +290,14 @@ func (p *DefaultProvider) UpdateInflightIPs(createAutoProvisioningGroupRequest *
 	}
 }
 
+// MarkIPsExhausted stops launching into the vSwitch after a launch failed because it ran out of IPs, the vSwitch
+// is used again once its available IP address count is refreshed
+func (p *DefaultProvider) MarkIPsExhausted(vSwitchID string) {
+	p.Lock()
+	defer p.Unlock()
+	p.inflightIPs[vSwitchID] = 0
+}
+
 func (p *DefaultProvider) LivenessProbe(_ *http.Request) error {
 	p.Lock()
 	//nolint: staticcheck
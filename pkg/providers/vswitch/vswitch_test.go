@@ -0,0 +1,195 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vswitch
+
+import (
+	"context"
+	"testing"
+
+	ecs "github.com/alibabacloud-go/ecs-20140526/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/apis/v1alpha1"
+	"github.com/cloudpilot-ai/karpenter-provider-alibabacloud/pkg/fake"
+)
+
+func newTestInstanceType(name string, pods int64, zones ...string) *cloudprovider.InstanceType {
+	return &cloudprovider.InstanceType{
+		Name:     name,
+		Capacity: corev1.ResourceList{corev1.ResourcePods: *resource.NewQuantity(pods, resource.DecimalSI)},
+		Offerings: lo.Map(zones, func(zone string, _ int) cloudprovider.Offering {
+			return cloudprovider.Offering{
+				Requirements: scheduling.NewRequirements(
+					scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, karpv1.CapacityTypeOnDemand),
+					scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, zone),
+				),
+				Price:     1,
+				Available: true,
+			}
+		}),
+	}
+}
+
+func newTestProvider(t *testing.T, vSwitches ...fake.VSwitch) (*DefaultProvider, *v1alpha1.ECSNodeClass) {
+	t.Helper()
+
+	vpcAPI := fake.NewVPCAPI()
+	vpcAPI.AddVSwitches(vSwitches...)
+	provider := NewDefaultProvider(fake.DefaultRegion, vpcAPI, cache.New(cache.NoExpiration, cache.NoExpiration),
+		cache.New(cache.NoExpiration, cache.NoExpiration))
+	nodeClass := &v1alpha1.ECSNodeClass{
+		Spec: v1alpha1.ECSNodeClassSpec{
+			VSwitchSelectorTerms: lo.Map(vSwitches, func(vSwitch fake.VSwitch, _ int) v1alpha1.VSwitchSelectorTerm {
+				return v1alpha1.VSwitchSelectorTerm{ID: vSwitch.ID}
+			}),
+		},
+		Status: v1alpha1.ECSNodeClassStatus{
+			VSwitches: lo.Map(vSwitches, func(vSwitch fake.VSwitch, _ int) v1alpha1.VSwitch {
+				return v1alpha1.VSwitch{ID: vSwitch.ID, ZoneID: vSwitch.ZoneID, AvailableIPAddressCount: vSwitch.AvailableIPAddressCount}
+			}),
+		},
+	}
+	_, err := provider.List(context.Background(), nodeClass)
+	require.NoError(t, err)
+	return provider, nodeClass
+}
+
+func newTestLaunchResponse(zoneID string) *ecs.CreateAutoProvisioningGroupResponse {
+	return &ecs.CreateAutoProvisioningGroupResponse{
+		Body: &ecs.CreateAutoProvisioningGroupResponseBody{
+			LaunchResults: &ecs.CreateAutoProvisioningGroupResponseBodyLaunchResults{
+				LaunchResult: []*ecs.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResult{{
+					InstanceIds: &ecs.CreateAutoProvisioningGroupResponseBodyLaunchResultsLaunchResultInstanceIds{InstanceId: []*string{tea.String("i-test")}},
+					ZoneId:      tea.String(zoneID),
+				}},
+			},
+		},
+	}
+}
+
+func TestDefaultProvider_ZonalVSwitchesForLaunch(t *testing.T) {
+	provider, nodeClass := newTestProvider(t,
+		fake.VSwitch{ID: "vsw-a-1", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 100},
+		fake.VSwitch{ID: "vsw-a-2", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 200},
+		fake.VSwitch{ID: "vsw-b", ZoneID: "cn-beijing-b", AvailableIPAddressCount: 40},
+	)
+	instanceTypes := []*cloudprovider.InstanceType{
+		newTestInstanceType("ecs.g7.large", 30, "cn-beijing-a", "cn-beijing-b"),
+		newTestInstanceType("ecs.g7.xlarge", 60, "cn-beijing-a", "cn-beijing-b"),
+	}
+
+	zonalVSwitches, err := provider.ZonalVSwitchesForLaunch(context.Background(), nodeClass, instanceTypes, karpv1.CapacityTypeOnDemand)
+	require.NoError(t, err)
+	assert.Equal(t, "vsw-a-2", zonalVSwitches["cn-beijing-a"].ID)
+	assert.Equal(t, "vsw-b", zonalVSwitches["cn-beijing-b"].ID)
+	assert.Equal(t, map[string]int64{"vsw-a-2": 170, "vsw-b": 10}, provider.inflightIPs)
+
+	// The zone b doesn't have enough IPs left for the smallest instance type
+	zonalVSwitches, err = provider.ZonalVSwitchesForLaunch(context.Background(), nodeClass, instanceTypes, karpv1.CapacityTypeOnDemand)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cn-beijing-a"}, lo.Keys(zonalVSwitches))
+	assert.Equal(t, map[string]int64{"vsw-a-2": 140, "vsw-b": 10}, provider.inflightIPs)
+
+	_, err = provider.ZonalVSwitchesForLaunch(context.Background(), nodeClass,
+		[]*cloudprovider.InstanceType{newTestInstanceType("ecs.g7.16xlarge", 250, "cn-beijing-a", "cn-beijing-b")}, karpv1.CapacityTypeOnDemand)
+	assert.Error(t, err)
+}
+
+func TestDefaultProvider_ZonalVSwitchesForLaunchExpiredCache(t *testing.T) {
+	provider, nodeClass := newTestProvider(t,
+		fake.VSwitch{ID: "vsw-a", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 100},
+		fake.VSwitch{ID: "vsw-b", ZoneID: "cn-beijing-b", AvailableIPAddressCount: 100},
+	)
+	instanceTypes := []*cloudprovider.InstanceType{newTestInstanceType("ecs.g7.large", 30, "cn-beijing-a", "cn-beijing-b")}
+
+	// The available IP address counts expired, the counts from the status are used instead
+	provider.availableIPAddressCache.Flush()
+	nodeClass.Status.VSwitches[1].AvailableIPAddressCount = 10
+	zonalVSwitches, err := provider.ZonalVSwitchesForLaunch(context.Background(), nodeClass, instanceTypes, karpv1.CapacityTypeOnDemand)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cn-beijing-a"}, lo.Keys(zonalVSwitches))
+	assert.Equal(t, int64(100), zonalVSwitches["cn-beijing-a"].AvailableIPAddressCount)
+	assert.Equal(t, map[string]int64{"vsw-a": 70}, provider.inflightIPs)
+}
+
+func TestDefaultProvider_UpdateInflightIPs(t *testing.T) {
+	provider, nodeClass := newTestProvider(t,
+		fake.VSwitch{ID: "vsw-a", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 100},
+		fake.VSwitch{ID: "vsw-b", ZoneID: "cn-beijing-b", AvailableIPAddressCount: 100},
+	)
+	instanceTypes := []*cloudprovider.InstanceType{newTestInstanceType("ecs.g7.large", 30, "cn-beijing-a", "cn-beijing-b")}
+
+	zonalVSwitches, err := provider.ZonalVSwitchesForLaunch(context.Background(), nodeClass, instanceTypes, karpv1.CapacityTypeOnDemand)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"vsw-a": 70, "vsw-b": 70}, provider.inflightIPs)
+
+	// Only the vSwitch which received the instance keeps the deduction
+	provider.UpdateInflightIPs(newTestLaunchResponse("cn-beijing-b"), instanceTypes, lo.Values(zonalVSwitches), karpv1.CapacityTypeOnDemand)
+	assert.Equal(t, map[string]int64{"vsw-a": 100, "vsw-b": 70}, provider.inflightIPs)
+
+	// All the deductions are given back when the launch failed
+	zonalVSwitches, err = provider.ZonalVSwitchesForLaunch(context.Background(), nodeClass, instanceTypes, karpv1.CapacityTypeOnDemand)
+	require.NoError(t, err)
+	provider.UpdateInflightIPs(nil, instanceTypes, lo.Values(zonalVSwitches), karpv1.CapacityTypeOnDemand)
+	assert.Equal(t, map[string]int64{"vsw-a": 100, "vsw-b": 70}, provider.inflightIPs)
+}
+
+func TestDefaultProvider_ListIPv6(t *testing.T) {
+	provider, nodeClass := newTestProvider(t,
+		fake.VSwitch{ID: "vsw-a", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 100, IPv6CIDRBlock: "2408:4005:3c2:a300::/64"},
+		fake.VSwitch{ID: "vsw-b", ZoneID: "cn-beijing-b", AvailableIPAddressCount: 100},
+	)
+
+	vSwitches, err := provider.List(context.Background(), nodeClass)
+	require.NoError(t, err)
+	assert.Len(t, vSwitches, 2)
+
+	// Only the vSwitches with IPv6 enabled are listed, the cached vSwitches are filtered too
+	nodeClass.Spec.IPv6AddressCount = lo.ToPtr[int32](1)
+	vSwitches, err = provider.List(context.Background(), nodeClass)
+	require.NoError(t, err)
+	require.Len(t, vSwitches, 1)
+	assert.Equal(t, "vsw-a", tea.StringValue(vSwitches[0].VSwitchId))
+	assert.Equal(t, "2408:4005:3c2:a300::/64", tea.StringValue(vSwitches[0].Ipv6CidrBlock))
+}
+
+func TestDefaultProvider_ListPublicIP(t *testing.T) {
+	provider, nodeClass := newTestProvider(t,
+		fake.VSwitch{ID: "vsw-a", ZoneID: "cn-beijing-a", AvailableIPAddressCount: 100},
+		fake.VSwitch{ID: "vsw-b", ZoneID: "cn-beijing-b", AvailableIPAddressCount: 100, ShareType: "Shared"},
+	)
+
+	vSwitches, err := provider.List(context.Background(), nodeClass)
+	require.NoError(t, err)
+	assert.Len(t, vSwitches, 2)
+
+	// The shared vSwitches can't assign public IP addresses
+	nodeClass.Spec.AssociatePublicIPAddress = lo.ToPtr(true)
+	vSwitches, err = provider.List(context.Background(), nodeClass)
+	require.NoError(t, err)
+	require.Len(t, vSwitches, 1)
+	assert.Equal(t, "vsw-a", tea.StringValue(vSwitches[0].VSwitchId))
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alierrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+// Category is the kind of failure an Alibaba Cloud API error code stands for, it decides how Karpenter reacts to it
+type Category string
+
+const (
+	// CategoryUnknown is an error which isn't in the catalogue
+	CategoryUnknown Category = "Unknown"
+	// CategoryInsufficientCapacity means the offering (instance type, zone and capacity type) can't be launched for now
+	CategoryInsufficientCapacity Category = "InsufficientCapacity"
+	// CategoryIPExhausted means the vSwitch of the zone has no IP address left
+	CategoryIPExhausted Category = "IPExhausted"
+	// CategoryNodeClassMisconfigured means a resource referenced by the ECSNodeClass is invalid, launching will keep
+	// failing until the ECSNodeClass is fixed
+	CategoryNodeClassMisconfigured Category = "NodeClassMisconfigured"
+	// CategoryThrottling means the request was throttled and can be retried
+	CategoryThrottling Category = "Throttling"
+	// CategoryFatal means the account can't launch any instance, e.g. the account quota is exceeded or the balance is
+	// insufficient
+	CategoryFatal Category = "Fatal"
+)
+
+const (
+	ErrCodeNoInstanceStock                = "NoInstanceStock"
+	ErrCodeOperationDeniedNoStock         = "OperationDenied.NoStock"
+	ErrCodeZoneNotOnSale                  = "Zone.NotOnSale"
+	ErrCodeInvalidResourceTypeNotSupport  = "InvalidResourceType.NotSupported"
+	ErrCodeInvalidSystemDiskCategory      = "InvalidSystemDiskCategory.ValueNotSupported"
+	ErrCodeInvalidDataDiskCategory        = "InvalidDataDiskCategory.ValueNotSupported"
+	ErrCodeInvalidDiskCategoryNotSupport  = "InvalidDiskCategory.NotSupported"
+	ErrCodeSpotPriceLowerThanPublicPrice  = "InvalidSpotPriceLimit.LowerThanPublicPrice"
+	ErrCodeDeploymentSetNoStock           = "DeploymentSet.NoInstanceStock"
+	ErrCodePrivatePoolNoStock             = "PrivatePool.InsufficientCapacity"
+	ErrCodeElasticityAssuranceNoStock     = "ElasticityAssurance.InsufficientCapacity"
+	ErrCodeElasticQuotaExceeded           = "QuotaExceed.ElasticQuota"
+	ErrCodeIPNotEnough                    = "InvalidVSwitchId.IpNotEnough"
+	ErrCodeVSwitchNotFound                = "InvalidVSwitchId.NotFound"
+	ErrCodeKeyPairNotFound                = "InvalidKeyPairName.NotFound"
+	ErrCodeRAMRoleNotFound                = "InvalidRamRole.NotExist"
+	ErrCodeResourceGroupNotFound          = "InvalidResourceGroup.NotFound"
+	ErrCodeDeploymentSetNotFound          = "InvalidDeploymentSetId.NotFound"
+	ErrCodeDedicatedHostNotFound          = "InvalidDedicatedHostId.NotFound"
+	ErrCodeDedicatedHostClusterNotFound   = "InvalidDedicatedHostClusterId.NotFound"
+	ErrCodeHpcClusterNotFound             = "InvalidHpcClusterId.NotFound"
+	ErrCodeInvalidUserData                = "InvalidUserData.NotSupported"
+	ErrCodeServiceUnavailable             = "ServiceUnavailable"
+	ErrCodeNotEnoughBalance               = "InvalidAccountStatus.NotEnoughBalance"
+	ErrCodeAccountArrearage               = "Account.Arrearage"
+	ErrCodeInvalidPayMethod               = "InvalidPayMethod"
+	ErrCodeRealNameAuthenticationRequired = "RealNameAuthenticationError"
+)
+
+// codeCategories classifies the exact error codes, it takes precedence over codePrefixCategories
+var codeCategories = map[string]Category{
+	ErrCodeNoInstanceStock:               CategoryInsufficientCapacity,
+	ErrCodeOperationDeniedNoStock:        CategoryInsufficientCapacity,
+	ErrCodeZoneNotOnSale:                 CategoryInsufficientCapacity,
+	ErrCodeInvalidResourceTypeNotSupport: CategoryInsufficientCapacity,
+	// The disk categories are supported per instance type and zone, another offering may support them
+	ErrCodeInvalidSystemDiskCategory:     CategoryInsufficientCapacity,
+	ErrCodeInvalidDataDiskCategory:       CategoryInsufficientCapacity,
+	ErrCodeInvalidDiskCategoryNotSupport: CategoryInsufficientCapacity,
+	ErrCodeSpotPriceLowerThanPublicPrice: CategoryInsufficientCapacity,
+	// The deployment set is full in the zone, the other zones may still have capacity
+	ErrCodeDeploymentSetNoStock: CategoryInsufficientCapacity,
+	// The private pool of the capacity reservation or the elasticity assurance is used up for the instance type
+	// in the zone, the reserved offering is unavailable until the instances are released
+	ErrCodePrivatePoolNoStock:         CategoryInsufficientCapacity,
+	ErrCodeElasticityAssuranceNoStock: CategoryInsufficientCapacity,
+	// The vCPU quota of the instance type is used up in the zone, the other instance types and zones may still be
+	// launched
+	ErrCodeElasticQuotaExceeded: CategoryInsufficientCapacity,
+
+	ErrCodeIPNotEnough: CategoryIPExhausted,
+
+	ErrCodeVSwitchNotFound:              CategoryNodeClassMisconfigured,
+	ErrCodeKeyPairNotFound:              CategoryNodeClassMisconfigured,
+	ErrCodeRAMRoleNotFound:              CategoryNodeClassMisconfigured,
+	ErrCodeResourceGroupNotFound:        CategoryNodeClassMisconfigured,
+	ErrCodeDeploymentSetNotFound:        CategoryNodeClassMisconfigured,
+	ErrCodeDedicatedHostNotFound:        CategoryNodeClassMisconfigured,
+	ErrCodeDedicatedHostClusterNotFound: CategoryNodeClassMisconfigured,
+	ErrCodeHpcClusterNotFound:           CategoryNodeClassMisconfigured,
+	ErrCodeInvalidUserData:              CategoryNodeClassMisconfigured,
+
+	ErrCodeServiceUnavailable: CategoryThrottling,
+
+	ErrCodeNotEnoughBalance:               CategoryFatal,
+	ErrCodeAccountArrearage:               CategoryFatal,
+	ErrCodeInvalidPayMethod:               CategoryFatal,
+	ErrCodeRealNameAuthenticationRequired: CategoryFatal,
+}
+
+// codePrefixCategories classifies the families of error codes, e.g. InvalidSecurityGroupId.NotFound
+var codePrefixCategories = []struct {
+	prefix   string
+	category Category
+}{
+	{prefix: "InvalidSecurityGroup", category: CategoryNodeClassMisconfigured},
+	{prefix: "InvalidImageId.", category: CategoryNodeClassMisconfigured},
+	{prefix: "ImageNotSubscribed", category: CategoryNodeClassMisconfigured},
+	{prefix: "Throttling", category: CategoryThrottling},
+	// The quotas which aren't bound to an offering are exceeded for the whole account
+	{prefix: "QuotaExceed", category: CategoryFatal},
+}
+
+// Classify returns the category of an ECS, VPC or ACK error code
+func Classify(code string) Category {
+	if category, ok := codeCategories[code]; ok {
+		return category
+	}
+	for _, c := range codePrefixCategories {
+		if strings.HasPrefix(code, c.prefix) {
+			return c.category
+		}
+	}
+	return CategoryUnknown
+}
+
+// Error is a classified error returned by Alibaba Cloud, either by an API call or in a launch result
+type Error struct {
+	Category Category
+	Code     string
+	Message  string
+}
+
+// New returns the classified error of the code
+func New(code, message string) *Error {
+	return &Error{
+		Category: Classify(code),
+		Code:     code,
+		Message:  message,
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("errorCode=%s, errorMessage=%s", e.Code, e.Message)
+}
+
+// AsError returns the classified error in the chain of err, the error returned by the SDK is classified as well
+func AsError(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	var sdkError *tea.SDKError
+	if errors.As(err, &sdkError) {
+		e = New(tea.StringValue(sdkError.Code), tea.StringValue(sdkError.Message))
+		if e.Category == CategoryUnknown && tea.IntValue(sdkError.StatusCode) == http.StatusTooManyRequests {
+			e.Category = CategoryThrottling
+		}
+		return e, true
+	}
+	return nil, false
+}
+
+// CategoryOf returns the category of err, CategoryUnknown is returned when err doesn't come from Alibaba Cloud
+func CategoryOf(err error) Category {
+	if e, ok := AsError(err); ok {
+		return e.Category
+	}
+	return CategoryUnknown
+}
+
+// IsThrottling returns true when the request was throttled and can be retried later
+func IsThrottling(err error) bool {
+	return CategoryOf(err) == CategoryThrottling
+}
+
+type attachedError struct {
+	error
+	aliErr *Error
+}
+
+func (e *attachedError) Unwrap() []error {
+	return []error{e.error, e.aliErr}
+}
+
+// Attach keeps aliErr reachable by errors.As from err, the Karpenter cloud provider errors don't unwrap the errors
+// they wrap
+func Attach(err error, aliErr *Error) error {
+	if err == nil || aliErr == nil {
+		return err
+	}
+	return &attachedError{error: err, aliErr: aliErr}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The CloudPilot AI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alierrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		code string
+		want Category
+	}{
+		{code: ErrCodeNoInstanceStock, want: CategoryInsufficientCapacity},
+		{code: ErrCodeOperationDeniedNoStock, want: CategoryInsufficientCapacity},
+		{code: ErrCodeInvalidDataDiskCategory, want: CategoryInsufficientCapacity},
+		{code: ErrCodeDeploymentSetNoStock, want: CategoryInsufficientCapacity},
+		{code: ErrCodePrivatePoolNoStock, want: CategoryInsufficientCapacity},
+		{code: ErrCodeElasticityAssuranceNoStock, want: CategoryInsufficientCapacity},
+		{code: ErrCodeIPNotEnough, want: CategoryIPExhausted},
+		{code: ErrCodeVSwitchNotFound, want: CategoryNodeClassMisconfigured},
+		{code: ErrCodeDeploymentSetNotFound, want: CategoryNodeClassMisconfigured},
+		{code: ErrCodeDedicatedHostNotFound, want: CategoryNodeClassMisconfigured},
+		{code: ErrCodeHpcClusterNotFound, want: CategoryNodeClassMisconfigured},
+		{code: "InvalidSecurityGroupId.NotFound", want: CategoryNodeClassMisconfigured},
+		{code: "InvalidImageId.NotFound", want: CategoryNodeClassMisconfigured},
+		{code: "Throttling.User", want: CategoryThrottling},
+		{code: ErrCodeElasticQuotaExceeded, want: CategoryInsufficientCapacity},
+		{code: "QuotaExceed.PostPaidInstance", want: CategoryFatal},
+		{code: ErrCodeNotEnoughBalance, want: CategoryFatal},
+		{code: "InvalidParameter", want: CategoryUnknown},
+		{code: "", want: CategoryUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			assert.Equal(t, tt.want, Classify(tt.code))
+		})
+	}
+}
+
+func TestAsError(t *testing.T) {
+	sdkError := &tea.SDKError{Code: tea.String("Throttling.Api"), Message: tea.String("throttled"), StatusCode: tea.Int(http.StatusBadRequest)}
+	aliErr, ok := AsError(fmt.Errorf("creating auto provisioning group, %w", sdkError))
+	require.True(t, ok)
+	assert.Equal(t, &Error{Category: CategoryThrottling, Code: "Throttling.Api", Message: "throttled"}, aliErr)
+
+	tooManyRequests := &tea.SDKError{Code: tea.String("Unknown"), StatusCode: tea.Int(http.StatusTooManyRequests)}
+	assert.True(t, IsThrottling(tooManyRequests))
+
+	_, ok = AsError(errors.New("unrelated"))
+	assert.False(t, ok)
+	assert.Equal(t, CategoryUnknown, CategoryOf(errors.New("unrelated")))
+}
+
+type opaqueError struct {
+	error
+}
+
+func TestAttach(t *testing.T) {
+	aliErr := New(ErrCodeNoInstanceStock, "sold out")
+	err := Attach(opaqueError{fmt.Errorf("launching, %w", aliErr)}, aliErr)
+
+	var opaque opaqueError
+	assert.True(t, errors.As(err, &opaque))
+	got, ok := AsError(err)
+	require.True(t, ok)
+	assert.Equal(t, aliErr, got)
+	assert.Equal(t, "launching, errorCode=NoInstanceStock, errorMessage=sold out", err.Error())
+	assert.NoError(t, Attach(nil, aliErr))
+}
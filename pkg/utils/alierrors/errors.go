@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/alibabacloud-go/tea/tea"
 )
@@ -35,6 +36,17 @@ func IsNotFound(err error) bool {
 	return false
 }
 
+// IsIncorrectInstanceStatus returns true when the status of the instance doesn't support the operation, e.g.
+// deleting an instance which is still being created
+func IsIncorrectInstanceStatus(err error) bool {
+	var sdkError *tea.SDKError
+	if errors.As(err, &sdkError) {
+		return strings.HasPrefix(tea.StringValue(sdkError.Code), "IncorrectInstanceStatus")
+	}
+
+	return false
+}
+
 func WithRequestID(requestID string, err error) error {
 	if err == nil {
 		return nil